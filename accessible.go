@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// accessible is set by the accessible setting. The UI then draws no box
+// characters, spells out in words what colors and glyphs show, and shows
+// one panel at a time, for terminal screen readers.
+var accessible bool
+
+// plainBorder draws panels with spaces, keeping their size.
+var plainBorder = lipgloss.Border{
+	Top: " ", Bottom: " ", Left: " ", Right: " ",
+	TopLeft: " ", TopRight: " ", BottomLeft: " ", BottomRight: " ",
+}
+
+// cursorPrefix marks the selected row of a list in words, as the cursor
+// is otherwise only a background color.
+func cursorPrefix(selected bool) string {
+	if accessible && selected {
+		return tr("cursor") + ": "
+	}
+	return ""
+}
+
+// moreGlyph returns the arrow for a "more" indicator with a space, or
+// nothing in the accessible setting where the word says enough.
+func moreGlyph(arrow string) string {
+	if accessible {
+		return ""
+	}
+	return arrow + " "
+}
+
+// describeNode says in words what a tree row shows: its kind and state,
+// then its name and details, e.g. "modified, staged: src/main.go".
+func describeNode(node TreeNode, reviewed bool, note string, task *taskRun) string {
+	var state []string
+	var name string
+	var details []string
+	folded := func() {
+		if node.Collapsed {
+			state = append(state, tr("collapsed"))
+		}
+	}
+	switch node.Kind {
+	case NodeGroup:
+		state = append(state, tr("group"))
+		folded()
+		name = node.Group
+		details = append(details, trf("%d repo(s)", node.groupRepos), trf("%d change(s)", node.groupFiles))
+	case NodeRepo:
+		r := node.Repo
+		state = append(state, tr("repo"))
+		folded()
+		name = r.RelPath
+		switch {
+		case r.Unavailable:
+			details = append(details, tr("unavailable"))
+		case r.Detached != "":
+			details = append(details, strings.Trim(r.Detached, "()"))
+		default:
+			details = append(details, trf("branch %s", r.Branch))
+		}
+		details = append(details, trf("%d change(s)", len(r.Files)))
+		if r.Ahead > 0 {
+			details = append(details, trf("%d ahead", r.Ahead))
+		}
+		if r.Behind > 0 {
+			details = append(details, trf("%d behind", r.Behind))
+		}
+		for _, w := range r.Warnings {
+			details = append(details, tr("warning")+" "+w)
+		}
+		if task != nil {
+			details = append(details, task.name+" "+taskStatus(task))
+		}
+	case NodeDir:
+		state = append(state, tr("directory"))
+		folded()
+		name = node.dirFull
+	case NodeFile:
+		state = append(state, tr(statusName(node.File.Status)))
+		if node.File.IsStaged {
+			state = append(state, tr("staged"))
+		}
+		if reviewed {
+			state = append(state, tr("reviewed"))
+		}
+		name = node.File.Path
+	}
+	if note != "" {
+		details = append(details, trf("note %s", note))
+	}
+	s := strings.Join(state, ", ") + ": " + name
+	if len(details) > 0 {
+		s += ", " + strings.Join(details, ", ")
+	}
+	return s
+}
+
+// renderLinear renders the tree for the accessible setting: one line of
+// text per row, without colors, glyphs or connectors. Rows under a repo
+// are indented by a space so they stay grouped when read.
+func (tm *TreeModel) renderLinear(width, height int) string {
+	startIdx := 0
+	if tm.cursor >= height {
+		startIdx = tm.cursor - height + 1
+	}
+	var lines []string
+	for i := startIdx; i < len(tm.visible) && len(lines) < height; i++ {
+		node := tm.nodes[tm.visible[i]]
+		indent := ""
+		if node.Kind == NodeDir || node.Kind == NodeFile {
+			indent = " "
+		}
+		line := truncateStr(indent+cursorPrefix(i == tm.cursor)+describeNode(node, tm.isReviewed(node), tm.note(node), tm.task(node)), width)
+		lines = append(lines, line+strings.Repeat(" ", max(0, width-lipgloss.Width(line))))
+	}
+	for len(lines) < height {
+		lines = append(lines, strings.Repeat(" ", width))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// announce says where the cursor is, for the status bar: the selected row
+// and its position, e.g. "3 of 12, modified: src/main.go (api)".
+func (m model) announce() string {
+	node := m.tree.SelectedNode()
+	if node == nil {
+		return ""
+	}
+	s := trf("%d of %d", m.tree.cursor+1, m.tree.Len()) + ", " + describeNode(*node, m.tree.isReviewed(*node), m.tree.note(*node), m.tree.task(*node))
+	if node.Kind == NodeDir || node.Kind == NodeFile {
+		s += fmt.Sprintf(" (%s)", node.Repo.RelPath)
+	}
+	return s
+}
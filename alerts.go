@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/hermanschutte/sidegit/pkg/sidegit"
+)
+
+// Alerts draw attention to sidegit while it runs in another pane or
+// window: a terminal bell (which tmux and most terminals flag on the tab),
+// or a desktop notification. Each kind of event has its own setting.
+
+// Alerts configures them, each event "off", "bell" or "desktop".
+type Alerts struct {
+	Dirty      string   `yaml:"dirty"`       // a clean repo gets changes
+	Behind     string   `yaml:"behind"`      // a fetch leaves a branch further behind its upstream
+	Branches   []string `yaml:"branches"`    // globs of the branches behind watches, empty for all
+	JobDone    string   `yaml:"job_done"`    // a job that ran for job_seconds or longer ends
+	JobSeconds int      `yaml:"job_seconds"` // how long a job runs before its end is worth an alert
+}
+
+// alertModes are the values every Alerts event accepts.
+var alertModes = []string{"off", "bell", "desktop"}
+
+// alertCmd rings the bell or shows a desktop notification, as mode says.
+// Without a way to show a desktop notification it rings the bell instead.
+func alertCmd(mode, title, body string) tea.Cmd {
+	if mode != "bell" && mode != "desktop" {
+		return nil
+	}
+	return func() tea.Msg {
+		if mode != "desktop" || desktopNotify(title, body) != nil {
+			return bellMsg{}
+		}
+		return nil
+	}
+}
+
+// bellMsg rings the bell. The frame View draws next carries it, so it
+// reaches the terminal with the renderer's own output rather than
+// interleaved with it; bellDoneMsg takes it out again.
+type bellMsg struct{}
+
+type bellDoneMsg struct{}
+
+// bellTime is how long the bell stays in the frame, long enough for the
+// renderer to draw it once.
+const bellTime = 100 * time.Millisecond
+
+// desktopNotify shows a notification with the platform's tool.
+func desktopNotify(title, body string) error {
+	var c *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		c = exec.Command("osascript", "-e", "on run argv", "-e", "display notification (item 2 of argv) with title (item 1 of argv)", "-e", "end run", title, body)
+	case "windows":
+		return fmt.Errorf("no desktop notifications on windows")
+	default:
+		c = exec.Command("notify-send", "--app-name=sidegit", title, body)
+	}
+	return c.Run()
+}
+
+// repoAlerts compares a new snapshot with the one before it for repos that
+// got changes, and branches a fetch left further behind.
+func (m model) repoAlerts(prev, repos []sidegit.Repo) tea.Cmd {
+	alerts := m.config.Alerts
+	if alerts.Dirty == "off" && alerts.Behind == "off" {
+		return nil
+	}
+	before := map[string]sidegit.Repo{}
+	for _, r := range prev {
+		before[r.Path] = r
+	}
+	var dirty, behind []string
+	for _, r := range repos {
+		p, ok := before[r.Path]
+		if !ok || r.Unavailable || p.Unavailable {
+			continue
+		}
+		if len(p.Files) == 0 && len(r.Files) > 0 {
+			dirty = append(dirty, r.RelPath)
+		}
+		branchWatched := len(alerts.Branches) == 0 || sidegit.MatchGlob(alerts.Branches, r.Branch)
+		if r.Fetched.After(p.Fetched) && r.Branch == p.Branch && r.Behind > p.Behind && branchWatched {
+			behind = append(behind, fmt.Sprintf("%s: %s is %d behind", r.RelPath, r.Branch, r.Behind))
+		}
+	}
+	var cmds []tea.Cmd
+	if len(dirty) > 0 {
+		cmds = append(cmds, alertCmd(alerts.Dirty, trf("%d repo(s) got changes", len(dirty)), joinShort(dirty)))
+	}
+	if len(behind) > 0 {
+		cmds = append(cmds, alertCmd(alerts.Behind, trf("%d branch(es) fell behind", len(behind)), joinShort(behind)))
+	}
+	return tea.Batch(cmds...)
+}
+
+// jobAlert alerts that job j ended, when it ran long enough for that to
+// matter. A job canceled from the jobs panel ends quietly.
+func (m model) jobAlert(j *job, failed bool) tea.Cmd {
+	if j.canceled() || j.duration() < time.Duration(m.config.Alerts.JobSeconds)*time.Second {
+		return nil
+	}
+	title := trf("%s finished", j.title)
+	if failed {
+		title = trf("%s failed", j.title)
+	}
+	return alertCmd(m.config.Alerts.JobDone, title, trf("after %s", j.duration().Round(time.Second)))
+}
+
+// joinShort lists names one per line, the first few of them.
+func joinShort(names []string) string {
+	const most = 5
+	s := ""
+	for i, n := range names {
+		if i == most {
+			return s + "\n" + trf("and %d more", len(names)-most)
+		}
+		if i > 0 {
+			s += "\n"
+		}
+		s += n
+	}
+	return s
+}
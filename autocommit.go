@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/hermanschutte/sidegit/pkg/sidegit"
+)
+
+// autoCommitState tracks one auto_commit repo between scans.
+type autoCommitState struct {
+	sig string // what its changes looked like at the last scan
+	gen int    // bumped on every change, so only the latest timer commits
+}
+
+// autoCommitMsg fires once repo has gone quiet for auto_commit_quiet.
+type autoCommitMsg struct {
+	repo string
+	gen  int
+}
+
+func isAutoCommit(cfg Config, r sidegit.Repo) bool {
+	return len(cfg.AutoCommit) > 0 && sidegit.MatchGlob(cfg.AutoCommit, r.RelPath)
+}
+
+// changeSignature sums up a repo's changes: the files, their status and
+// the newest modification time among them. It changes whenever the repo
+// is edited again, even when the list of changed files doesn't.
+func changeSignature(r sidegit.Repo) string {
+	var b strings.Builder
+	var newest time.Time
+	for _, f := range r.Files {
+		fmt.Fprintf(&b, "%s %v %v\n", f.Path, f.Status, f.IsStaged)
+		if info, err := os.Stat(filepath.Join(r.Path, f.Path)); err == nil && info.ModTime().After(newest) {
+			newest = info.ModTime()
+		}
+	}
+	return b.String() + newest.String()
+}
+
+// checkAutoCommits (re)starts the quiet period timer of every auto_commit
+// repo whose changes differ from the last scan.
+func (m *model) checkAutoCommits(repos []sidegit.Repo) tea.Cmd {
+	if len(m.config.AutoCommit) == 0 || m.config.SafeMode {
+		return nil
+	}
+	if m.autoCommits == nil {
+		m.autoCommits = map[string]autoCommitState{}
+	}
+	quiet := time.Duration(m.config.AutoCommitQuiet) * time.Second
+	var cmds []tea.Cmd
+	for _, r := range repos {
+		if !isAutoCommit(m.config, r) || len(r.Files) == 0 || r.Unavailable {
+			delete(m.autoCommits, r.Path)
+			continue
+		}
+		sig := changeSignature(r)
+		st := m.autoCommits[r.Path]
+		if st.sig == sig {
+			continue
+		}
+		st.sig = sig
+		st.gen++
+		m.autoCommits[r.Path] = st
+		msg := autoCommitMsg{repo: r.Path, gen: st.gen}
+		cmds = append(cmds, tea.Tick(quiet, func(time.Time) tea.Msg { return msg }))
+	}
+	return tea.Batch(cmds...)
+}
+
+// autoCommitCmd stages and commits everything in repoPath with a
+// timestamped message, then pushes when auto_commit_push is on. Repos in
+// the middle of a merge are left alone.
+func autoCommitCmd(repo sidegit.Repo, push bool) tea.Cmd {
+	for _, f := range repo.Files {
+		if f.Status == sidegit.StatusConflict {
+			return func() tea.Msg {
+				return gitErrorMsg{repo: repo.Path, err: fmt.Errorf("auto-commit skipped %s: it has conflicts", repo.RelPath)}
+			}
+		}
+	}
+	return jobCmd("auto-commit "+repo.RelPath, repo.Path, "auto-committed "+repo.RelPath, func(ctx context.Context) error {
+		msg := "Auto-commit " + time.Now().Format("2006-01-02 15:04:05")
+		err := sidegit.StagePaths(repo.Path, ".")
+		if err == nil {
+			err = sidegit.CommitStaged(repo.Path, msg, false)
+		}
+		if err == nil && push {
+			err = sidegit.GitPushSetUpstream(ctx, repo.Path)
+		}
+		if err != nil {
+			return fmt.Errorf("auto-commit %s: %w", repo.RelPath, err)
+		}
+		return nil
+	})
+}
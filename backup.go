@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/hermanschutte/sidegit/pkg/sidegit"
+)
+
+// backupDir is where discarded and deleted files are copied before they
+// go, so a slip of the finger can be undone with U.
+func backupDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "sidegit", "backups"), nil
+}
+
+// backup is a copy of a file (or untracked directory) taken before a
+// destructive action.
+type backup struct {
+	repo string
+	file string // path relative to repo, as shown in the tree
+	dir  string // the copy, mirroring file's path
+}
+
+// backupStamp names each backup's directory under backupDir.
+const backupStamp = "20060102-150405.000"
+
+// backupFile copies repo/file into a fresh timestamped backup directory,
+// then drops backups older than keepDays (0 keeps them all). A file that's
+// already gone from the worktree has nothing to save and yields a nil
+// backup.
+func backupFile(repoPath, filePath string, keepDays int) (*backup, error) {
+	src := filepath.Join(repoPath, filePath)
+	if _, err := os.Lstat(src); os.IsNotExist(err) {
+		return nil, nil
+	}
+	root, err := backupDir()
+	if err != nil {
+		return nil, err
+	}
+	if keepDays > 0 {
+		pruneBackups(root, time.Now().AddDate(0, 0, -keepDays))
+	}
+	dir := filepath.Join(root, time.Now().Format(backupStamp), filepath.Base(repoPath))
+	if err := copyTree(src, filepath.Join(dir, filePath)); err != nil {
+		return nil, err
+	}
+	return &backup{repo: repoPath, file: filePath, dir: dir}, nil
+}
+
+// pruneBackups removes the backups in root taken before cutoff. Anything
+// not named like a backup is left alone.
+func pruneBackups(root string, cutoff time.Time) {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	for _, e := range entries {
+		taken, err := time.ParseInLocation(backupStamp, e.Name(), time.Local)
+		if err != nil || !e.IsDir() {
+			continue
+		}
+		if !taken.Before(cutoff) {
+			break // the rest are newer
+		}
+		_ = os.RemoveAll(filepath.Join(root, e.Name()))
+	}
+}
+
+// restore copies the backup back over the worktree and unstages the path,
+// which brings back the index entry a git rm dropped.
+func (b *backup) restore() error {
+	if err := copyTree(filepath.Join(b.dir, b.file), filepath.Join(b.repo, b.file)); err != nil {
+		return err
+	}
+	_ = exec.Command("git", sidegit.RepoArgs(b.repo, "reset", "-q", "--", b.file)...).Run()
+	return nil
+}
+
+// copyTree copies a file or directory, overwriting files at dst.
+func copyTree(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		switch {
+		case d.IsDir():
+			return os.MkdirAll(target, 0755)
+		case d.Type()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			_ = os.Remove(target)
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case d.Type().IsRegular():
+			return copyFile(path, target)
+		}
+		return fmt.Errorf("%s: not a regular file", path)
+	})
+}
+
+func copyFile(src, dst string) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/hermanschutte/sidegit/pkg/sidegit"
+)
+
+// bulkRow is one repo in the progress overlay of a bulk operation.
+type bulkRow struct {
+	repo sidegit.Repo
+	done bool
+	err  error
+}
+
+// bulkResultMsg reports one repo of bulk operation id.
+type bulkResultMsg struct {
+	id, row int
+	err     error
+}
+
+// bulkMenuOptions builds the menu of operations across every repo.
+func (m model) bulkMenuOptions() []menuOption {
+	var all, withUpstream, ahead, dirty []sidegit.Repo
+	for _, r := range m.repos {
+		if r.Unavailable {
+			continue
+		}
+		all = append(all, r)
+		if r.Upstream != "" {
+			withUpstream = append(withUpstream, r)
+		}
+		if r.Ahead > 0 {
+			ahead = append(ahead, r)
+		}
+		if len(r.Files) > 0 {
+			dirty = append(dirty, r)
+		}
+	}
+	bulk := func(key, label string, repos []sidegit.Repo, op func(context.Context, string) error) menuOption {
+		return menuOption{key: key, label: fmt.Sprintf("%s (%d repos)", label, len(repos)), action: func() tea.Cmd {
+			return func() tea.Msg { return bulkOpenMsg{title: label, repos: repos, op: op} }
+		}}
+	}
+	return []menuOption{
+		bulk("f", "Fetch all", all, sidegit.GitFetch),
+		bulk("l", "Pull all, fast-forward only", withUpstream, sidegit.GitPullFFOnly),
+		bulk("p", "Push all repos ahead of their upstream", ahead, sidegit.GitPush),
+		bulk("s", "Stash all repos with changes", dirty, func(_ context.Context, path string) error { return sidegit.GitStash(path, "") }),
+		{label: "Cancel"},
+	}
+}
+
+// bulkOpenMsg starts a bulk operation from the menu.
+type bulkOpenMsg struct {
+	title string
+	repos []sidegit.Repo
+	op    func(ctx context.Context, repoPath string) error
+}
+
+// startBulk opens the progress overlay and runs op on every repo, at most
+// fetch_workers at a time, as one job. Canceling the job (x) skips the
+// repos that haven't started.
+func (m *model) startBulk(msg bulkOpenMsg) tea.Cmd {
+	m.bulkID++
+	m.bulkTitle = msg.title
+	m.bulkRows = nil
+	m.bulkOpen = true
+	if len(msg.repos) == 0 {
+		return nil
+	}
+	id := m.bulkID
+	ctx, cancel := context.WithCancel(context.Background())
+	m.bulkJob = m.addJob(fmt.Sprintf("%s (%d repos)", msg.title, len(msg.repos)), "", cancel)
+	sem := make(chan struct{}, m.config.FetchWorkers)
+	var cmds []tea.Cmd
+	for i, r := range msg.repos {
+		m.bulkRows = append(m.bulkRows, bulkRow{repo: r})
+		path := r.Path
+		cmds = append(cmds, func() tea.Msg {
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			if err := ctx.Err(); err != nil {
+				return bulkResultMsg{id: id, row: i, err: err}
+			}
+			return bulkResultMsg{id: id, row: i, err: msg.op(ctx, path)}
+		})
+	}
+	return tea.Batch(cmds...)
+}
+
+// finishBulkJob ends the job of a bulk operation once every repo is done,
+// with the repos that failed as its output, and returns its alert.
+func (m *model) finishBulkJob() tea.Cmd {
+	var failed []string
+	var err error
+	for _, r := range m.bulkRows {
+		switch {
+		case errors.Is(r.err, context.Canceled):
+			err = r.err
+		case r.err != nil:
+			failed = append(failed, r.repo.RelPath+": "+lastErrLine(r.err))
+		}
+	}
+	if err == nil && len(failed) > 0 {
+		err = fmt.Errorf("%d of %d repos failed", len(failed), len(m.bulkRows))
+	}
+	m.bulkJob.output = strings.Join(failed, "\n")
+	m.bulkJob.finish(err)
+	return m.jobAlert(m.bulkJob, err != nil)
+}
+
+// bulkRunning reports whether some repo of the current bulk operation is
+// still going.
+func (m model) bulkRunning() bool {
+	for _, r := range m.bulkRows {
+		if !r.done {
+			return true
+		}
+	}
+	return false
+}
+
+func (m model) handleBulkKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc", "enter", "q", "A":
+		// Closing doesn't stop anything; A shows the overlay again
+		m.bulkOpen = false
+	case "x":
+		if m.bulkRunning() {
+			m.bulkJob.cancel()
+		}
+	}
+	return m, nil
+}
+
+func (m model) renderBulk() string {
+	boxWidth := m.width - 2
+	innerWidth := boxWidth - 2
+	ok := lipgloss.NewStyle().Foreground(themeColor(m.config.Theme.StatusAdded))
+	failed := lipgloss.NewStyle().Foreground(themeColor(m.config.Theme.StatusConflict))
+	pending := lipgloss.NewStyle().Foreground(themeColor(m.config.Theme.StatusBar))
+
+	nameWidth := 0
+	for _, r := range m.bulkRows {
+		nameWidth = max(nameWidth, lipgloss.Width(r.repo.RelPath))
+	}
+	nameWidth = min(nameWidth+2, innerWidth/2)
+
+	var lines []string
+	if len(m.bulkRows) == 0 {
+		lines = append(lines, "no repos to do this for")
+	}
+	done, errs := 0, 0
+	for _, r := range m.bulkRows {
+		mark, detail := pending.Render("…"), pending.Render("running")
+		switch {
+		case r.done && r.err != nil:
+			mark, detail = failed.Render("✗"), failed.Render(truncateStr(lastErrLine(r.err), innerWidth-nameWidth-2))
+			done++
+			errs++
+		case r.done:
+			mark, detail = ok.Render("✓"), ok.Render("done")
+			done++
+		}
+		name := lipgloss.NewStyle().Width(nameWidth).Render(truncateStr(r.repo.RelPath, nameWidth-1))
+		line := mark + " " + name + detail
+		if vis := lipgloss.Width(line); vis < innerWidth {
+			line += strings.Repeat(" ", innerWidth-vis)
+		}
+		lines = append(lines, line)
+	}
+	if visible := m.height - 4; len(lines) > visible {
+		more := len(lines) - visible + 1
+		lines = append(lines[:visible-1], pending.Render(fmt.Sprintf("… and %d more", more)))
+	}
+
+	title := fmt.Sprintf("%s: %d/%d", m.bulkTitle, done, len(m.bulkRows))
+	if errs > 0 {
+		title += fmt.Sprintf(", %d failed", errs)
+	}
+	if m.bulkRunning() {
+		title += " (x to cancel)"
+	} else {
+		title += " (esc to close)"
+	}
+	box := renderBorderedPanel(title, strings.Join(lines, "\n"), boxWidth, len(lines)+2, m.config.Theme.BorderFocused, m.config.Theme.Title)
+	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, box,
+		lipgloss.WithWhitespaceChars(" "),
+		lipgloss.WithWhitespaceForeground(lipgloss.Color("0")))
+}
+
+// lastErrLine is the last line of a multi-line git error.
+func lastErrLine(err error) string {
+	lines := strings.Split(strings.TrimSpace(err.Error()), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
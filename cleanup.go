@@ -0,0 +1,158 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/hermanschutte/sidegit/pkg/sidegit"
+)
+
+// keepBranches are left unticked in the merged-branches list: a feature
+// branch that merged main in lists main as merged too.
+var keepBranches = map[string]bool{"main": true, "master": true, "develop": true, "trunk": true}
+
+// cleanupMenuOptions builds the repo menu's clean-up submenu.
+func cleanupMenuOptions(repo sidegit.Repo) []menuOption {
+	return []menuOption{
+		{key: "p", label: "Prune stale origin branches…", action: func() tea.Cmd { return pruneMenuCmd(repo) }},
+		{key: "b", label: "Delete merged local branches…", action: func() tea.Cmd { return mergedBranchesCmd(repo) }},
+		{key: "g", label: "Garbage collect (git gc)…", action: func() tea.Cmd { return gcMenuCmd(repo) }},
+		{label: "Cancel"},
+	}
+}
+
+// pruneMenuCmd asks the remote which branches are gone and confirms
+// pruning them.
+func pruneMenuCmd(repo sidegit.Repo) tea.Cmd {
+	return func() tea.Msg {
+		stale, err := sidegit.StaleRemoteBranches(repo.Path, "origin")
+		if err != nil {
+			return gitErrorMsg{repo: repo.Path, err: err}
+		}
+		if len(stale) == 0 {
+			return fileChangedMsg{repo: repo.Path, note: "nothing to prune in " + repo.RelPath}
+		}
+		prune := func() tea.Cmd {
+			return func() tea.Msg {
+				pruned, err := sidegit.PruneRemote(repo.Path, "origin")
+				if err != nil {
+					return gitErrorMsg{repo: repo.Path, err: err}
+				}
+				return fileChangedMsg{repo: repo.Path, note: fmt.Sprintf("pruned %s in %s", plural(len(pruned), "remote branch"), repo.RelPath)}
+			}
+		}
+		return openMenuMsg{
+			title: fmt.Sprintf("Prune %s from %s", plural(len(stale), "stale remote branch"), repo.RelPath),
+			options: []menuOption{
+				{key: "p", label: "Prune", action: prune},
+				{label: "Cancel"},
+			},
+			preview: strings.Join(stale, "\n"),
+		}
+	}
+}
+
+// mergedBranchesCmd lists the branches merged into HEAD for picking which
+// to delete.
+func mergedBranchesCmd(repo sidegit.Repo) tea.Cmd {
+	return func() tea.Msg {
+		branches, err := sidegit.MergedBranches(repo.Path)
+		if err != nil {
+			return gitErrorMsg{repo: repo.Path, err: err}
+		}
+		if len(branches) == 0 {
+			return fileChangedMsg{repo: repo.Path, note: "no merged branches in " + repo.RelPath}
+		}
+		picked := make([]bool, len(branches))
+		for i, b := range branches {
+			picked[i] = !keepBranches[b]
+		}
+		return branchPickerMsg(repo, branches, picked, 0)
+	}
+}
+
+// branchPickerMsg is the merged-branches list. Enter ticks a branch and
+// reopens the list on it; d deletes the ticked ones.
+func branchPickerMsg(repo sidegit.Repo, branches []string, picked []bool, cursor int) openMenuMsg {
+	var chosen []string
+	for i, b := range branches {
+		if picked[i] {
+			chosen = append(chosen, b)
+		}
+	}
+	var opts []menuOption
+	for i, b := range branches {
+		mark := "[ ] "
+		if picked[i] {
+			mark = "[x] "
+		}
+		opts = append(opts, menuOption{label: mark + b, action: func() tea.Cmd {
+			next := append([]bool(nil), picked...)
+			next[i] = !next[i]
+			return func() tea.Msg { return branchPickerMsg(repo, branches, next, i) }
+		}})
+	}
+	all := len(chosen) < len(branches)
+	toggleLabel := "Tick all"
+	if !all {
+		toggleLabel = "Untick all"
+	}
+	opts = append(opts, menuOption{key: "a", label: toggleLabel, action: func() tea.Cmd {
+		next := make([]bool, len(branches))
+		for i := range next {
+			next[i] = all
+		}
+		return func() tea.Msg { return branchPickerMsg(repo, branches, next, len(branches)) }
+	}})
+	if len(chosen) > 0 {
+		opts = append(opts, menuOption{key: "d", label: fmt.Sprintf("Delete %s…", plural(len(chosen), "branch")), action: func() tea.Cmd {
+			return openMenuCmd(fmt.Sprintf("Delete %s in %s: %s?", plural(len(chosen), "branch"), repo.RelPath, strings.Join(chosen, ", ")), []menuOption{
+				{key: "d", label: "Delete", action: func() tea.Cmd { return deleteBranchesCmd(repo, chosen) }},
+				{label: "Cancel"},
+			})
+		}})
+	}
+	opts = append(opts, menuOption{label: "Cancel"})
+	return openMenuMsg{
+		title:   fmt.Sprintf("Branches merged into %s (%d of %d ticked)", repo.Branch, len(chosen), len(branches)),
+		options: opts,
+		cursor:  cursor,
+	}
+}
+
+func deleteBranchesCmd(repo sidegit.Repo, branches []string) tea.Cmd {
+	return func() tea.Msg {
+		deleted, err := sidegit.DeleteBranches(repo.Path, branches)
+		if err != nil {
+			return gitErrorMsg{repo: repo.Path, err: fmt.Errorf("deleted %d of %d branches: %w", len(deleted), len(branches), err), changed: true}
+		}
+		return fileChangedMsg{repo: repo.Path, note: fmt.Sprintf("deleted %s in %s", plural(len(deleted), "branch"), repo.RelPath)}
+	}
+}
+
+// gcMenuCmd confirms a git gc, showing the current size of the objects.
+func gcMenuCmd(repo sidegit.Repo) tea.Cmd {
+	return func() tea.Msg {
+		size, err := sidegit.ObjectsSize(repo.Path)
+		if err != nil {
+			return gitErrorMsg{repo: repo.Path, err: err}
+		}
+		gc := func() tea.Cmd {
+			return func() tea.Msg {
+				before, after, err := sidegit.GarbageCollect(repo.Path)
+				if err != nil {
+					return gitErrorMsg{repo: repo.Path, err: err}
+				}
+				return fileChangedMsg{repo: repo.Path, note: fmt.Sprintf("gc %s: %s → %s", repo.RelPath, formatSize(before), formatSize(after))}
+			}
+		}
+		return openMenuMsg{
+			title: fmt.Sprintf("Run git gc in %s (objects take %s)", repo.RelPath, formatSize(size)),
+			options: []menuOption{
+				{key: "g", label: "Garbage collect", action: gc},
+				{label: "Cancel"},
+			},
+		}
+	}
+}
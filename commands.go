@@ -0,0 +1,319 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// subcommands are the words sidegit takes before its flags. Without one
+// it opens the TUI. status and daemon are the --once and --daemon flags
+// under a name; the rest have their own arguments.
+var subcommands = []struct{ name, args, summary string }{
+	{"status", "[flags] [path]", "print a colored summary of every repo (JSON with --json, markdown with --markdown) and exit"},
+	{"daemon", "[flags] [path]", "keep repos scanned and watched, serving them to other sidegit runs"},
+	{"config", "[show|path|edit|check]", "print the effective config or where it lives, open it in $EDITOR, or list its problems"},
+	{"completion", "bash|zsh|fish", "print a shell completion script"},
+	{"export-settings", "[archive]", "pack the config, themes and UI state into a tar.gz"},
+	{"import-settings", "<archive>", "unpack settings written by export-settings"},
+}
+
+// listReposArg is the hidden subcommand the completion scripts call to
+// complete repo names. It takes the same flags and path as status.
+const listReposArg = "__repos"
+
+func printUsage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintf(out, "usage: sidegit [command] [flags] [path...]\n\ncommands:\n")
+	for _, c := range subcommands {
+		fmt.Fprintf(out, "  %-16s %-18s %s\n", c.name, c.args, c.summary)
+	}
+	fmt.Fprintf(out, "\nflags:\n")
+	flag.PrintDefaults()
+}
+
+func runConfigCommand(args []string) {
+	op := "show"
+	if len(args) > 0 {
+		op = args[0]
+	}
+	root, err := os.Getwd()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	switch op {
+	case "show":
+		// What a sidegit run here would use: the global config with the
+		// workspace's .sidegit.yaml on top
+		cfg, _, err := LoadConfig()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+		if cfg, err = LoadProjectConfig(cfg, root); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+		data, err := yaml.Marshal(cfg)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		os.Stdout.Write(data)
+	case "path":
+		fmt.Println(configPath())
+		if _, err := os.Stat(projectConfigPath(root)); err == nil {
+			fmt.Println(projectConfigPath(root))
+		}
+	case "edit":
+		LoadConfig() // writes the default file if there is none yet
+		editor := os.Getenv("EDITOR")
+		if editor == "" {
+			editor = "vi"
+		}
+		parts := strings.Fields(editor)
+		c := exec.Command(parts[0], append(parts[1:], configPath())...)
+		c.Stdin, c.Stdout, c.Stderr = os.Stdin, os.Stdout, os.Stderr
+		if err := c.Run(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	case "check":
+		// Each file on its own, exiting 1 if any has problems
+		files := []string{configPath()}
+		if _, err := os.Stat(projectConfigPath(root)); err == nil {
+			files = append(files, projectConfigPath(root))
+		}
+		failed := false
+		for _, path := range files {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%v\n", err)
+				failed = true
+				continue
+			}
+			var p *configProblems
+			switch _, err := parseConfig(DefaultConfig(), data, path); {
+			case errors.As(err, &p):
+				for _, problem := range p.problems {
+					fmt.Printf("%s: %s\n", path, problem)
+				}
+				failed = true
+			case err != nil:
+				fmt.Println(err)
+				failed = true
+			default:
+				fmt.Printf("%s: ok\n", path)
+			}
+		}
+		if failed {
+			os.Exit(1)
+		}
+	default:
+		fmt.Fprintln(os.Stderr, "usage: sidegit config [show|path|edit|check]")
+		os.Exit(2)
+	}
+}
+
+func runCompletionCommand(args []string) {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "usage: sidegit completion bash|zsh|fish")
+		os.Exit(2)
+	}
+	var err error
+	switch args[0] {
+	case "bash":
+		err = writeBashCompletion(os.Stdout)
+	case "zsh":
+		err = writeZshCompletion(os.Stdout)
+	case "fish":
+		err = writeFishCompletion(os.Stdout)
+	default:
+		fmt.Fprintf(os.Stderr, "Error: no completion for %q (bash, zsh or fish)\n", args[0])
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// completionFlag is a flag as the completion scripts see it.
+type completionFlag struct {
+	name    string
+	usage   string
+	valued  bool   // takes a value
+	values  string // fixed words to complete the value from, if any
+	command string // shell command printing the values, if any
+	file    bool   // the value is a file
+}
+
+// completionFlags lists the flags defined on the command line, with what
+// their values complete to.
+func completionFlags() []completionFlag {
+	var layouts []string
+	for _, l := range builtinLayouts {
+		layouts = append(layouts, l.Name)
+	}
+	var flags []completionFlag
+	flag.VisitAll(func(f *flag.Flag) {
+		valueName, usage := flag.UnquoteUsage(f)
+		cf := completionFlag{name: f.Name, usage: usage, valued: valueName != ""}
+		switch f.Name {
+		case "repo":
+			cf.command = "sidegit " + listReposArg
+		case "theme":
+			cf.values = strings.Join(sortedThemeNames(), " ")
+		case "layout":
+			cf.values = strings.Join(layouts, " ")
+		default:
+			cf.file = valueName == "file"
+		}
+		flags = append(flags, cf)
+	})
+	return flags
+}
+
+func writeBashCompletion(w io.Writer) error {
+	var b strings.Builder
+	var names, words []string
+	for _, c := range subcommands {
+		names = append(names, c.name)
+	}
+	for _, f := range completionFlags() {
+		words = append(words, "--"+f.name)
+	}
+	b.WriteString("# bash completion for sidegit: source <(sidegit completion bash)\n")
+	b.WriteString("_sidegit() {\n")
+	b.WriteString("\tlocal cur=\"${COMP_WORDS[COMP_CWORD]}\" prev=\"${COMP_WORDS[COMP_CWORD-1]}\"\n")
+	b.WriteString("\tcase \"$prev\" in\n")
+	for _, f := range completionFlags() {
+		switch {
+		case f.command != "":
+			fmt.Fprintf(&b, "\t--%s) COMPREPLY=($(compgen -W \"$(%s 2>/dev/null)\" -- \"$cur\")); return ;;\n", f.name, f.command)
+		case f.values != "":
+			fmt.Fprintf(&b, "\t--%s) COMPREPLY=($(compgen -W %q -- \"$cur\")); return ;;\n", f.name, f.values)
+		case f.file:
+			fmt.Fprintf(&b, "\t--%s) COMPREPLY=($(compgen -f -- \"$cur\")); return ;;\n", f.name)
+		case f.valued:
+			fmt.Fprintf(&b, "\t--%s) return ;;\n", f.name)
+		}
+	}
+	b.WriteString("\tesac\n")
+	b.WriteString("\tif [[ $COMP_CWORD -eq 2 ]]; then\n")
+	b.WriteString("\t\tcase \"${COMP_WORDS[1]}\" in\n")
+	b.WriteString("\t\tconfig) COMPREPLY=($(compgen -W \"show path edit check\" -- \"$cur\")); return ;;\n")
+	b.WriteString("\t\tcompletion) COMPREPLY=($(compgen -W \"bash zsh fish\" -- \"$cur\")); return ;;\n")
+	b.WriteString("\t\tesac\n")
+	b.WriteString("\tfi\n")
+	b.WriteString("\tcase \"${COMP_WORDS[1]}\" in\n")
+	b.WriteString("\tconfig|completion) return ;;\n")
+	b.WriteString("\texport-settings|import-settings) COMPREPLY=($(compgen -f -- \"$cur\")); return ;;\n")
+	b.WriteString("\tesac\n")
+	b.WriteString("\tif [[ $cur == -* ]]; then\n")
+	fmt.Fprintf(&b, "\t\tCOMPREPLY=($(compgen -W %q -- \"$cur\"))\n", strings.Join(words, " "))
+	b.WriteString("\telif [[ $COMP_CWORD -eq 1 ]]; then\n")
+	fmt.Fprintf(&b, "\t\tCOMPREPLY=($(compgen -W %q -- \"$cur\") $(compgen -d -- \"$cur\"))\n", strings.Join(names, " "))
+	b.WriteString("\telse\n")
+	b.WriteString("\t\tCOMPREPLY=($(compgen -d -- \"$cur\"))\n")
+	b.WriteString("\tfi\n")
+	b.WriteString("}\n")
+	b.WriteString("complete -o filenames -F _sidegit sidegit\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeZshCompletion(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("#compdef sidegit\n")
+	b.WriteString("# zsh completion for sidegit: source <(sidegit completion zsh)\n")
+	b.WriteString("_sidegit() {\n")
+	b.WriteString("\tlocal -a commands flags\n")
+	b.WriteString("\tcommands=(\n")
+	for _, c := range subcommands {
+		fmt.Fprintf(&b, "\t\t%s\n", zshQuote(c.name+":"+c.summary))
+	}
+	b.WriteString("\t)\n")
+	b.WriteString("\tflags=(\n")
+	for _, f := range completionFlags() {
+		fmt.Fprintf(&b, "\t\t%s\n", zshQuote("--"+f.name+":"+f.usage))
+	}
+	b.WriteString("\t)\n")
+	b.WriteString("\tcase $words[CURRENT-1] in\n")
+	for _, f := range completionFlags() {
+		switch {
+		case f.command != "":
+			fmt.Fprintf(&b, "\t--%s) compadd -- ${(f)\"$(%s 2>/dev/null)\"}; return ;;\n", f.name, f.command)
+		case f.values != "":
+			fmt.Fprintf(&b, "\t--%s) compadd -- %s; return ;;\n", f.name, f.values)
+		case f.file:
+			fmt.Fprintf(&b, "\t--%s) _files; return ;;\n", f.name)
+		case f.valued:
+			fmt.Fprintf(&b, "\t--%s) return ;;\n", f.name)
+		}
+	}
+	b.WriteString("\tesac\n")
+	b.WriteString("\tif (( CURRENT == 3 )); then\n")
+	b.WriteString("\t\tcase $words[2] in\n")
+	b.WriteString("\t\tconfig) compadd show path edit check; return ;;\n")
+	b.WriteString("\t\tcompletion) compadd bash zsh fish; return ;;\n")
+	b.WriteString("\t\tesac\n")
+	b.WriteString("\tfi\n")
+	b.WriteString("\tcase $words[2] in\n")
+	b.WriteString("\tconfig|completion) return ;;\n")
+	b.WriteString("\texport-settings|import-settings) _files; return ;;\n")
+	b.WriteString("\tesac\n")
+	b.WriteString("\tif [[ $words[CURRENT] == -* ]]; then\n")
+	b.WriteString("\t\t_describe flag flags\n")
+	b.WriteString("\telse\n")
+	b.WriteString("\t\t(( CURRENT == 2 )) && _describe command commands\n")
+	b.WriteString("\t\t_path_files -/\n")
+	b.WriteString("\tfi\n")
+	b.WriteString("}\n")
+	b.WriteString("compdef _sidegit sidegit\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeFishCompletion(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("# fish completion for sidegit: sidegit completion fish | source\n")
+	b.WriteString("complete -c sidegit -f\n")
+	for _, c := range subcommands {
+		fmt.Fprintf(&b, "complete -c sidegit -n __fish_use_subcommand -a %s -d %s\n", c.name, fishQuote(c.summary))
+	}
+	for _, f := range completionFlags() {
+		fmt.Fprintf(&b, "complete -c sidegit -l %s -d %s", f.name, fishQuote(f.usage))
+		switch {
+		case f.command != "":
+			fmt.Fprintf(&b, " -x -a '(%s 2>/dev/null)'", f.command)
+		case f.values != "":
+			fmt.Fprintf(&b, " -x -a %s", fishQuote(f.values))
+		case f.file:
+			b.WriteString(" -r -F")
+		case f.valued:
+			b.WriteString(" -x")
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("complete -c sidegit -n '__fish_seen_subcommand_from config' -x -a 'show path edit check'\n")
+	b.WriteString("complete -c sidegit -n '__fish_seen_subcommand_from completion' -x -a 'bash zsh fish'\n")
+	b.WriteString("complete -c sidegit -n '__fish_seen_subcommand_from export-settings import-settings' -F\n")
+	b.WriteString("complete -c sidegit -n 'not __fish_seen_subcommand_from config completion export-settings import-settings' -a '(__fish_complete_directories)'\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// zshQuote and fishQuote single-quote s for their shell.
+func zshQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(s) + "'"
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/hermanschutte/sidegit/pkg/sidegit"
+)
+
+// conventionalTypes are the types offered by the conventional commit
+// assistant, in menu order.
+var conventionalTypes = []struct{ key, name, desc string }{
+	{"f", "feat", "a new feature"},
+	{"x", "fix", "a bug fix"},
+	{"d", "docs", "documentation only"},
+	{"r", "refactor", "neither fixes a bug nor adds a feature"},
+	{"p", "perf", "a performance improvement"},
+	{"t", "test", "adding or fixing tests"},
+	{"b", "build", "build system or dependencies"},
+	{"i", "ci", "CI configuration"},
+	{"s", "style", "formatting, no code change"},
+	{"c", "chore", "anything else"},
+}
+
+var conventionalRe = regexp.MustCompile(`^[a-z]+(\([^()]+\))?!?: \S`)
+
+// commitMenuTitle is the commit menu's title for repo.
+func commitMenuTitle(repo sidegit.Repo) string {
+	staged := 0
+	for _, f := range repo.Files {
+		if f.IsStaged {
+			staged++
+		}
+	}
+	return fmt.Sprintf("Commit %d staged file(s): %s", staged, repo.RelPath)
+}
+
+// commitMenuOptions builds the commit menu for repo. With
+// conventional_commits on, the assistant comes first. With push, the
+// commit is pushed once it's made.
+func (m model) commitMenuOptions(repo sidegit.Repo, push bool) []menuOption {
+	repoPath := repo.Path
+	cfg := m.config
+	write := menuOption{key: "m", label: "Write message…", action: func() tea.Cmd {
+		return func() tea.Msg {
+			// Start from the subject line of commit.template, if any
+			tmpl, err := sidegit.CommitTemplate(repoPath)
+			if err != nil {
+				return gitErrorMsg{repo: repoPath, err: err}
+			}
+			subject, _, _ := strings.Cut(tmpl, "\n")
+			return openPromptMsg{title: "Commit message", value: subject, history: "commit", onSubmit: func(msg string) tea.Cmd {
+				return commitCmd(cfg, repoPath, msg, push)
+			}}
+		}
+	}}
+	conventional := menuOption{key: "c", label: "Conventional commit…", action: func() tea.Cmd {
+		return openMenuCmd("Commit type", conventionalTypeOptions(cfg, repoPath, push))
+	}}
+	editor := menuOption{key: "e", label: "Write in $EDITOR (uses commit.template)", action: func() tea.Cmd {
+		c := exec.Command("git", sidegit.RepoArgs(repoPath, "commit")...)
+		return tea.ExecProcess(c, func(err error) tea.Msg {
+			if err == nil {
+				return committedMsg{repo: repoPath, push: push}
+			}
+			return editorFinishedMsg{repo: repoPath, err: err}
+		})
+	}}
+	toggle := "Push after committing: off"
+	if push {
+		toggle = "Push after committing: on (sets the upstream if there's none)"
+	}
+	togglePush := menuOption{key: "P", label: toggle, action: func() tea.Cmd {
+		return openMenuCmd(commitMenuTitle(repo), m.commitMenuOptions(repo, !push))
+	}}
+	opts := []menuOption{write, conventional, editor}
+	if cfg.ConventionalCommits {
+		opts = []menuOption{conventional, write, editor}
+	}
+	return append(opts, togglePush, menuOption{label: "Cancel"})
+}
+
+func conventionalTypeOptions(cfg Config, repoPath string, push bool) []menuOption {
+	var opts []menuOption
+	for _, t := range conventionalTypes {
+		name := t.name
+		opts = append(opts, menuOption{key: t.key, label: fmt.Sprintf("%-8s %s", name, t.desc), action: func() tea.Cmd {
+			return openOptionalPromptCmd(name+": scope (optional)", "", func(scope string) tea.Cmd {
+				prefix := name
+				if scope != "" {
+					prefix += "(" + scope + ")"
+				}
+				return openPromptCmd(prefix+": subject", "", func(subject string) tea.Cmd {
+					return commitCmd(cfg, repoPath, prefix+": "+subject, push)
+				})
+			})
+		}})
+	}
+	return append(opts, menuOption{label: "Cancel"})
+}
+
+// lintCommitMessage returns what's wrong with a commit message. None of
+// it blocks the commit; it's shown so it can be fixed first.
+func lintCommitMessage(cfg Config, msg string) []string {
+	subject, body, _ := strings.Cut(msg, "\n")
+	var problems []string
+	if n := len([]rune(subject)); cfg.CommitSubjectMax > 0 && n > cfg.CommitSubjectMax {
+		problems = append(problems, fmt.Sprintf("subject is %d characters, over %d", n, cfg.CommitSubjectMax))
+	}
+	if strings.HasSuffix(subject, ".") {
+		problems = append(problems, "subject ends with a period")
+	}
+	if cfg.ConventionalCommits && !conventionalRe.MatchString(subject) {
+		problems = append(problems, "subject isn't type(scope): description")
+	}
+	if body != "" && !strings.HasPrefix(body, "\n") {
+		problems = append(problems, "no blank line after the subject")
+	}
+	return problems
+}
+
+// committedMsg reports a new commit in repo, to be pushed with push.
+type committedMsg struct {
+	repo string
+	push bool
+}
+
+// commitCmd commits msg, asking first when the message has lint problems.
+func commitCmd(cfg Config, repoPath, msg string, push bool) tea.Cmd {
+	commit := func() tea.Cmd {
+		return func() tea.Msg { return runCommit(repoPath, msg, push, false) }
+	}
+	problems := lintCommitMessage(cfg, msg)
+	if len(problems) == 0 {
+		return commit()
+	}
+	return openMenuCmd("Commit message: "+strings.Join(problems, "; "), []menuOption{
+		{key: "c", label: "Commit anyway", action: commit},
+		{key: "e", label: "Edit message…", action: func() tea.Cmd {
+			return openPromptCmd("Commit message", msg, func(v string) tea.Cmd {
+				return commitCmd(cfg, repoPath, v, push)
+			})
+		}},
+		{label: "Cancel"},
+	})
+}
+
+// runCommit commits msg, skipping the commit hooks with noVerify. A hook
+// that stops the commit gets its output shown, with a retry without hooks.
+func runCommit(repoPath, msg string, push, noVerify bool) tea.Msg {
+	if sidegit.SigningEnabled(repoPath) {
+		return signedCommitCmd(repoPath, msg, push, noVerify)()
+	}
+	err := sidegit.CommitStaged(repoPath, msg, noVerify)
+	var hookErr *sidegit.HookError
+	switch {
+	case errors.As(err, &hookErr):
+		return hookFailedMsg(repoPath, msg, push, hookErr)
+	case err != nil:
+		// A hook may have rewritten files before failing
+		return gitErrorMsg{repo: repoPath, err: err, changed: true}
+	}
+	return committedMsg{repo: repoPath, push: push}
+}
+
+// hookFailedMsg opens what a commit hook printed when it stopped a commit,
+// scrollable in full, offering to commit again with --no-verify.
+func hookFailedMsg(repoPath, msg string, push bool, hookErr *sidegit.HookError) openMenuMsg {
+	return openMenuMsg{
+		title: "Commit stopped by the " + hookErr.Hook + " hook",
+		options: []menuOption{
+			{key: "n", label: "Commit without hooks (--no-verify)", action: func() tea.Cmd {
+				return func() tea.Msg { return runCommit(repoPath, msg, push, true) }
+			}},
+			{label: "Cancel"},
+		},
+		preview:      hookErr.Output,
+		previewTitle: hookErr.Hook + " output",
+	}
+}
+
+// signedCommitCmd runs git commit on the terminal, so pinentry or an ssh
+// passphrase prompt can ask for the key. git's stderr is kept to explain
+// a failure once the TUI is back.
+func signedCommitCmd(repoPath, msg string, push, noVerify bool) tea.Cmd {
+	f, err := os.CreateTemp("", "sidegit-commit-*")
+	if err == nil {
+		_, err = f.WriteString(msg)
+		f.Close()
+	}
+	if err != nil {
+		return func() tea.Msg { return gitErrorMsg{repo: repoPath, err: err} }
+	}
+	var stderr bytes.Buffer
+	args := []string{"commit", "-F", f.Name()}
+	if noVerify {
+		args = append(args, "--no-verify")
+	}
+	c := exec.Command("git", sidegit.RepoArgs(repoPath, args...)...)
+	c.Stderr = io.MultiWriter(os.Stderr, &stderr)
+	if os.Getenv("GPG_TTY") == "" {
+		// pinentry-curses needs to know which terminal to draw on
+		if tty, err := os.Readlink("/proc/self/fd/0"); err == nil && strings.HasPrefix(tty, "/dev/") {
+			c.Env = append(os.Environ(), "GPG_TTY="+tty)
+		}
+	}
+	trace := sidegit.TraceHooks(c)
+	return tea.ExecProcess(c, func(err error) tea.Msg {
+		os.Remove(f.Name())
+		hooks := trace()
+		if err != nil {
+			if hookErr := sidegit.CommitHookError(stderr.String(), hooks); hookErr != nil && !noVerify {
+				return hookFailedMsg(repoPath, msg, push, hookErr)
+			}
+			return gitErrorMsg{repo: repoPath, err: signingError(stderr.String(), err)}
+		}
+		return committedMsg{repo: repoPath, push: push}
+	})
+}
+
+// signingError turns git's stderr into a one-line error, calling out a
+// signing failure rather than leaving it buried in gpg's output.
+func signingError(stderr string, err error) error {
+	lines := strings.Split(strings.TrimSpace(stderr), "\n")
+	last := strings.TrimSpace(lines[len(lines)-1])
+	if last == "" {
+		last = err.Error()
+	}
+	for _, l := range lines {
+		if strings.Contains(l, "failed to sign") || strings.Contains(l, "signing failed") {
+			return fmt.Errorf("commit not created, signing failed: %s", strings.TrimPrefix(strings.TrimSpace(l), "error: "))
+		}
+	}
+	return fmt.Errorf("git commit: %s", last)
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/hermanschutte/sidegit/pkg/sidegit"
+)
+
+// commitView is the commit detail overlay opened from the log (L). It
+// pages through the summary and then one file's diff at a time.
+type commitView struct {
+	repo   sidegit.Repo
+	detail sidegit.CommitDetail
+	file   int // index into detail.Files, -1 for the summary
+	vp     viewport.Model
+}
+
+// commitLoadedMsg opens the commit view; commitFileMsg fills it with a
+// file's diff.
+type commitLoadedMsg struct {
+	repo   sidegit.Repo
+	detail sidegit.CommitDetail
+}
+
+type commitFileMsg struct {
+	hash  string
+	file  int
+	text  string
+	files []sidegit.FileDiff // text parsed, nil with a diff_pager or an error
+	note  string             // why the diff is cut short
+}
+
+func loadCommitCmd(repo sidegit.Repo, hash string) tea.Cmd {
+	return func() tea.Msg {
+		c, err := sidegit.GetCommit(repo.Path, hash)
+		if err != nil {
+			return gitErrorMsg{repo: repo.Path, err: err}
+		}
+		return commitLoadedMsg{repo: repo, detail: c}
+	}
+}
+
+func commitFileCmd(repoPath string, c sidegit.CommitDetail, file int, opts sidegit.DiffOptions, pager diffPager) tea.Cmd {
+	return func() tea.Msg {
+		d, err := sidegit.CommitFileDiff(repoPath, c, c.Files[file], opts)
+		if err != nil {
+			return commitFileMsg{hash: c.Hash, file: file, text: fmt.Sprintf("Error loading diff: %v", err)}
+		}
+		msg := commitFileMsg{hash: c.Hash, file: file, text: pager.page(d.Text), files: d.Files}
+		if d.Truncated {
+			msg.note = fmt.Sprintf("\n… only the first %d lines are shown", opts.MaxLines)
+		}
+		return msg
+	}
+}
+
+func (m *model) openCommit(msg commitLoadedMsg) {
+	m.commit = commitView{repo: msg.repo, detail: msg.detail, file: -1}
+	m.commit.vp = viewport.New(m.width-4, m.commitViewHeight())
+	m.commit.vp.SetContent(commitSummary(msg.detail))
+	m.commitOpen = true
+}
+
+func (m model) commitViewHeight() int {
+	// Outer margin, the border and the key hints
+	return max(3, m.height-2-2-1)
+}
+
+// commitSummary is the first page of the commit view, like git show
+// without the patch.
+func commitSummary(c sidegit.CommitDetail) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "commit %s\nAuthor: %s\nDate:   %s\n\n", c.Hash, c.Author, c.Date)
+	for _, line := range strings.Split(c.Message, "\n") {
+		b.WriteString("    " + line + "\n")
+	}
+	if c.Stat != "" {
+		b.WriteString("\n" + c.Stat + "\n")
+	}
+	return b.String()
+}
+
+// showCommitFile moves the view to file i, the summary for -1.
+func (m *model) showCommitFile(i int) tea.Cmd {
+	m.commit.file = i
+	if i < 0 {
+		m.commit.vp.SetContent(commitSummary(m.commit.detail))
+		m.commit.vp.GotoTop()
+		return nil
+	}
+	m.commit.vp.SetContent("loading " + m.commit.detail.Files[i] + "…")
+	m.commit.vp.GotoTop()
+	opts := m.diffOptions()
+	opts.MaxLines = m.config.DiffMaxLines
+	pager := m.diffPager()
+	pager.width = m.commit.vp.Width
+	return commitFileCmd(m.commit.repo.Path, m.commit.detail, i, opts, pager)
+}
+
+func (m model) handleCommitKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	c := m.commit.detail
+	switch msg.String() {
+	case "esc", "q":
+		m.commitOpen = false
+	case "n", "tab", "right", "l":
+		if len(c.Files) > 0 {
+			return m, m.showCommitFile((m.commit.file+2)%(len(c.Files)+1) - 1)
+		}
+	case "N", "shift+tab", "left", "h":
+		if len(c.Files) > 0 {
+			return m, m.showCommitFile((m.commit.file+len(c.Files)+1)%(len(c.Files)+1) - 1)
+		}
+	case "o":
+		if m.commit.file >= 0 {
+			repoPath, hash, file := m.commit.repo.Path, c.Short, c.Files[m.commit.file]
+			return m, func() tea.Msg {
+				dest, err := sidegit.CheckoutFileAt(repoPath, hash, file)
+				if err != nil {
+					return gitErrorMsg{repo: repoPath, err: err}
+				}
+				return fileChangedMsg{repo: repoPath, note: "wrote " + file + "@" + hash + " to " + dest}
+			}
+		}
+	case "v":
+		m.commitOpen = false
+		repo, edit := m.commit.repo, m.config.RevertEdit
+		m.openMenu("Revert "+c.Short+" "+strings.SplitN(c.Message, "\n", 2)[0]+"?", []menuOption{
+			{key: "v", label: "Revert on " + repo.Branch, action: func() tea.Cmd { return revertCmd(repo, c, edit) }},
+			{label: "Cancel"},
+		})
+	case "c":
+		m.commitOpen = false
+		commit := sidegit.Commit{Hash: c.Hash, Short: c.Short}
+		m.openMenu("Cherry-pick "+c.Short+" onto", m.cherryPickTargets(m.commit.repo, commit))
+	case "g", "home":
+		m.commit.vp.GotoTop()
+	case "G", "end":
+		m.commit.vp.GotoBottom()
+	case "ctrl+d":
+		m.commit.vp.HalfPageDown()
+	case "ctrl+u":
+		m.commit.vp.HalfPageUp()
+	default:
+		var cmd tea.Cmd
+		m.commit.vp, cmd = m.commit.vp.Update(msg)
+		return m, cmd
+	}
+	return m, nil
+}
+
+func (m model) renderCommit() string {
+	c := m.commit.detail
+	boxWidth := m.width - 2
+	title := fmt.Sprintf("%s: %s %s", m.commit.repo.RelPath, c.Short, strings.SplitN(c.Message, "\n", 2)[0])
+	if m.commit.file >= 0 {
+		title = fmt.Sprintf("%s: %s %s (%d/%d)", m.commit.repo.RelPath, c.Short, c.Files[m.commit.file], m.commit.file+1, len(c.Files))
+	}
+	hints := "n/N next/previous file · o write the file at this commit to a temp dir · c cherry-pick · v revert · esc close"
+	if m.commit.file < 0 {
+		hints = fmt.Sprintf("n to step through the %s · c cherry-pick · v revert · esc close", plural(len(c.Files), "changed file"))
+	}
+	hint := lipgloss.NewStyle().Foreground(themeColor(m.config.Theme.StatusBar)).Render(truncateStr(hints, boxWidth-2))
+	content := m.commit.vp.View() + "\n" + hint
+	box := renderBorderedPanel(title, content, boxWidth, m.commitViewHeight()+3, m.config.Theme.BorderFocused, m.config.Theme.Title)
+	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, box,
+		lipgloss.WithWhitespaceChars(" "),
+		lipgloss.WithWhitespaceForeground(lipgloss.Color("0")))
+}
+
+// revertCmd reverts commit c. With edit, git runs in the terminal so
+// $EDITOR can change the message. Conflicts show up in the tree.
+func revertCmd(repo sidegit.Repo, c sidegit.CommitDetail, edit bool) tea.Cmd {
+	if !edit {
+		return gitNoteCmd(repo.Path, "reverted "+c.Short+" in "+repo.RelPath, func() error { return sidegit.Revert(repo.Path, c.Hash) })
+	}
+	args := sidegit.RepoArgs(repo.Path, "revert", "--edit")
+	if sidegit.IsMerge(repo.Path, c.Hash) {
+		args = append(args, "-m", "1")
+	}
+	cmd := exec.Command("git", append(args, c.Hash)...)
+	return tea.ExecProcess(cmd, func(err error) tea.Msg {
+		if err != nil {
+			return gitErrorMsg{repo: repo.Path, err: fmt.Errorf("git revert %s: %v", c.Short, err), changed: true}
+		}
+		return fileChangedMsg{repo: repo.Path, note: "reverted " + c.Short + " in " + repo.RelPath}
+	})
+}
@@ -1,9 +1,17 @@
 package main
 
 import (
+	"errors"
+	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
 
+	"github.com/hermanschutte/sidegit/pkg/sidegit"
 	"gopkg.in/yaml.v3"
 )
 
@@ -16,6 +24,7 @@ type Theme struct {
 	NoRepos         string `yaml:"no_repos"`
 	RepoName        string `yaml:"repo_name"`
 	BranchName      string `yaml:"branch_name"`
+	Detached        string `yaml:"detached"`
 	FileCount       string `yaml:"file_count"`
 	FolderIcon      string `yaml:"folder_icon"`
 	DirName         string `yaml:"dir_name"`
@@ -24,13 +33,50 @@ type Theme struct {
 	StatusDeleted   string `yaml:"status_deleted"`
 	StatusModified  string `yaml:"status_modified"`
 	StatusUntracked string `yaml:"status_untracked"`
+	StatusConflict  string `yaml:"status_conflict"`
 	DefaultIcon     string `yaml:"default_icon"`
 	AheadColor      string `yaml:"ahead_color"`
 	BehindColor     string `yaml:"behind_color"`
 	TreeLines       string `yaml:"tree_lines"`
+	Warning         string `yaml:"warning"`
+	LineNumber      string `yaml:"line_number"` // the diff's line number columns
 }
 
+// DefaultTheme adapts to the terminal background: every color is a
+// "light|dark" pair taken from the light and dark themes.
 func DefaultTheme() Theme {
+	t, light := darkTheme(), lightTheme()
+	dst, src := t.colors(), light.colors()
+	for i := range dst {
+		*dst[i] = *src[i] + "|" + *dst[i]
+	}
+	return t
+}
+
+// colors returns pointers to every color in the theme.
+func (t *Theme) colors() []*string {
+	return []*string{
+		&t.CursorBg, &t.BorderFocused, &t.BorderNormal, &t.Title, &t.StatusBar,
+		&t.NoRepos, &t.RepoName, &t.BranchName, &t.Detached, &t.FileCount,
+		&t.FolderIcon, &t.DirName, &t.StatusStaged, &t.StatusAdded,
+		&t.StatusDeleted, &t.StatusModified, &t.StatusUntracked,
+		&t.StatusConflict, &t.DefaultIcon, &t.AheadColor, &t.BehindColor,
+		&t.TreeLines, &t.Warning, &t.LineNumber,
+	}
+}
+
+// colorNames returns the config key of each color, in the order of colors.
+func colorNames() []string {
+	t := reflect.TypeOf(Theme{})
+	names := make([]string, t.NumField())
+	for i := range names {
+		names[i], _, _ = strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+	}
+	return names
+}
+
+// darkTheme is the original palette, tuned for dark terminals.
+func darkTheme() Theme {
 	return Theme{
 		CursorBg:        "237",
 		BorderFocused:   "12",
@@ -40,6 +86,7 @@ func DefaultTheme() Theme {
 		NoRepos:         "8",
 		RepoName:        "12",
 		BranchName:      "13",
+		Detached:        "208",
 		FileCount:       "7",
 		FolderIcon:      "7",
 		DirName:         "7",
@@ -48,26 +95,206 @@ func DefaultTheme() Theme {
 		StatusDeleted:   "9",
 		StatusModified:  "11",
 		StatusUntracked: "8",
+		StatusConflict:  "9",
 		DefaultIcon:     "7",
 		AheadColor:      "10",
 		BehindColor:     "9",
 		TreeLines:       "8",
+		Warning:         "11",
+		LineNumber:      "8",
 	}
 }
 
 type Config struct {
-	DiffPosition string `yaml:"diff_position"`
-	ScanDepth    int    `yaml:"scan_depth"`
-	PollInterval int    `yaml:"poll_interval"`
-	Theme        Theme  `yaml:"theme"`
+	DiffPosition  string             `yaml:"diff_position"` // a layout name
+	Layouts       []Layout           `yaml:"layouts"`
+	ScanDepth     int                `yaml:"scan_depth"`
+	IgnoreDirs    []string           `yaml:"ignore_dirs"`
+	HiddenRepos   []string           `yaml:"hidden_repos"`
+	ExcludeRepos  []string           `yaml:"exclude_repos"`
+	BareRepos     []BareRepoConfig   `yaml:"bare_repos"`
+	NestedRepos   bool               `yaml:"nested_repos"`
+	Filters       Filters            `yaml:"filters"`
+	HideFiles     []string           `yaml:"hide_files"`
+	Groups        []RepoGroup        `yaml:"groups"`
+	PollInterval  int                `yaml:"poll_interval"`
+	RescanMS      int                `yaml:"rescan_interval_ms"`
+	WatchIgnore   []string           `yaml:"watch_ignore"`
+	FetchInterval int                `yaml:"fetch_interval"`
+	FetchWorkers  int                `yaml:"fetch_workers"`
+	PRStatus      bool               `yaml:"pr_status"`
+	PRInterval    int                `yaml:"pr_interval"`
+	GitTimeout    int                `yaml:"git_timeout"`
+	RepoSort      string             `yaml:"repo_sort"`
+	FileAges      bool               `yaml:"file_ages"`
+	StaleDays     int                `yaml:"stale_days"` // dim repos without a commit or file change in this many days, 0 = never
+	StaleLast     bool               `yaml:"stale_last"` // and list them after the others
+	BackupDays    int                `yaml:"backup_days"`
+	DiffContext   int                `yaml:"diff_context"`
+	DiffIgnoreWS  bool               `yaml:"diff_ignore_whitespace"`
+	DiffLineNums  bool               `yaml:"diff_line_numbers"`
+	DiffFold      bool               `yaml:"diff_fold"`
+	DiffMaxLines  int                `yaml:"diff_max_lines"`
+	DiffWarnKB    int                `yaml:"diff_warn_kb"`
+	DiffPager     string             `yaml:"diff_pager"`
+	RootName      string             `yaml:"root_name"`
+	RepoAccents   map[string]string  `yaml:"repo_accents"`
+	WebURLs       map[string]WebURLs `yaml:"web_urls"`
+	AutoAccent    bool               `yaml:"auto_accent"`
+	BranchColors  map[string]string  `yaml:"branch_colors"`
+	Background    string             `yaml:"background"`
+	Theme         Theme              `yaml:"theme"`
+
+	ConventionalCommits bool `yaml:"conventional_commits"`
+	CommitSubjectMax    int  `yaml:"commit_subject_max"` // 0 turns the length check off
+	CommitPush          bool `yaml:"commit_push"`        // start the commit menu with push after committing on
+	RevertEdit          bool `yaml:"revert_edit"`        // open $EDITOR on the message of a revert
+
+	Editor     string `yaml:"editor"`      // what o opens files in: auto or one of editors
+	NvimServer string `yaml:"nvim_server"` // Neovim --server address, defaults to $NVIM
+
+	UntrackedFiles string `yaml:"untracked_files"` // all, normal, no, or repo for each repo's status.showUntrackedFiles
+
+	Language   string `yaml:"language"`   // auto (from $LANG) or one of languages
+	Accessible bool   `yaml:"accessible"` // plain text for screen readers, see accessible
+
+	Inline        bool   `yaml:"inline"`         // draw below the prompt instead of on the alternate screen
+	Height        string `yaml:"height"`         // rows used inline: a count or a percentage, see parseHeight
+	ReducedMotion bool   `yaml:"reduced_motion"` // no blinking text cursors
+
+	AutoCommit      []string `yaml:"auto_commit"`       // repos committed automatically, matched like groups
+	AutoCommitQuiet int      `yaml:"auto_commit_quiet"` // seconds without changes before an auto-commit
+	AutoCommitPush  bool     `yaml:"auto_commit_push"`
+
+	Snapshots        bool `yaml:"snapshots"`         // record git stash create snapshots of dirty repos
+	SnapshotInterval int  `yaml:"snapshot_interval"` // seconds between snapshots
+	SnapshotKeep     int  `yaml:"snapshot_keep"`     // snapshots kept per repo
+
+	ConfirmQuitWhenDirty bool `yaml:"confirm_quit_when_dirty"` // list unsaved work and ask again before quitting
+
+	Commands map[string]string `yaml:"commands"` // shell commands run in a repo with !, by name
+
+	Alerts Alerts `yaml:"alerts"` // a bell or desktop notification on events, see Alerts
+
+	// SafeMode is set by --safe-mode: no watcher and no background fetches.
+	SafeMode bool `yaml:"-"`
+	// Overrides holds the command-line flags, kept so a live reload can
+	// re-apply them.
+	Overrides Overrides `yaml:"-"`
+}
+
+// Overrides holds settings given as command-line flags. They win over both
+// the global and the project config.
+type Overrides struct {
+	ConfigFile string // used instead of ~/.config/sidegit/config.yaml
+	Depth      int    // scan depth, -1 when not given
+	Layout     string // layout name
+	Theme      string // theme name, see LookupTheme
+	NoWatch    bool
+	Accessible bool
+	Inline     bool   // --no-altscreen
+	Height     string // inline height, implies Inline
+	NoMotion   bool   // --reduced-motion
+}
+
+// configFile returns the config file in use: --config or config.yaml.
+func (o Overrides) configFile() string {
+	if o.ConfigFile != "" {
+		return o.ConfigFile
+	}
+	return configPath()
+}
+
+// Apply writes the overrides into cfg.
+func (o Overrides) Apply(cfg *Config) error {
+	cfg.Overrides = o
+	if o.Depth >= 0 {
+		cfg.ScanDepth = o.Depth
+	}
+	if o.Accessible {
+		cfg.Accessible = true
+	}
+	if o.Inline {
+		cfg.Inline = true
+	}
+	if o.Height != "" {
+		if _, _, err := parseHeight(o.Height); err != nil {
+			return err
+		}
+		cfg.Inline, cfg.Height = true, o.Height
+	}
+	if o.NoMotion {
+		cfg.ReducedMotion = true
+	}
+	if o.Layout != "" {
+		if _, ok := findLayout(cfg.allLayouts(), o.Layout); !ok {
+			var names []string
+			for _, l := range cfg.allLayouts() {
+				names = append(names, l.Name)
+			}
+			return fmt.Errorf("invalid layout %q (%s)", o.Layout, strings.Join(names, ", "))
+		}
+		cfg.DiffPosition = o.Layout
+	}
+	if o.Theme != "" {
+		t, err := LookupTheme(o.Theme)
+		if err != nil {
+			return err
+		}
+		cfg.Theme = t
+	}
+	return nil
+}
+
+// BareRepoConfig is a bare_repos entry: a git directory used with a work
+// tree somewhere else, like a dotfiles repo. Paths may start with ~.
+type BareRepoConfig struct {
+	GitDir   string `yaml:"git_dir"`
+	WorkTree string `yaml:"work_tree"`
+	Name     string `yaml:"name,omitempty"`
+}
+
+// RepoGroup puts the repos matching any of its patterns under a
+// collapsible header in the tree. Patterns match repo paths the way
+// hide_files patterns match file paths.
+type RepoGroup struct {
+	Name  string   `yaml:"name"`
+	Repos []string `yaml:"repos"`
 }
 
 func DefaultConfig() Config {
 	return Config{
-		DiffPosition: "right",
-		ScanDepth:    1,
-		PollInterval: 10,
-		Theme:        DefaultTheme(),
+		DiffPosition:     "right",
+		ScanDepth:        1,
+		PollInterval:     10,
+		RescanMS:         300,
+		FetchInterval:    0,
+		FetchWorkers:     4,
+		PRInterval:       300,
+		CommitSubjectMax: 72,
+		AutoCommitQuiet:  60,
+		SnapshotInterval: 600,
+		SnapshotKeep:     20,
+		GitTimeout:       10,
+		BackupDays:       14,
+		RepoSort:         "name",
+		DiffContext:      3,
+		DiffFold:         true,
+		DiffMaxLines:     2000,
+		DiffWarnKB:       1024,
+		Alerts:           Alerts{Dirty: "off", Behind: "off", JobDone: "off", JobSeconds: 30},
+		Background:       "auto",
+		Editor:           "auto",
+		Language:         "auto",
+		Height:           "40%",
+		UntrackedFiles:   "all",
+		Theme:            DefaultTheme(),
+		BranchColors: map[string]string{
+			"feature/": "2|10",
+			"fix/":     "3|11",
+			"hotfix/":  "1|9",
+			"release/": "5|13",
+		},
 	}
 }
 
@@ -97,6 +324,9 @@ func applyThemeDefaults(t *Theme) {
 	if t.BranchName == "" {
 		t.BranchName = d.BranchName
 	}
+	if t.Detached == "" {
+		t.Detached = d.Detached
+	}
 	if t.FileCount == "" {
 		t.FileCount = d.FileCount
 	}
@@ -121,6 +351,9 @@ func applyThemeDefaults(t *Theme) {
 	if t.StatusUntracked == "" {
 		t.StatusUntracked = d.StatusUntracked
 	}
+	if t.StatusConflict == "" {
+		t.StatusConflict = d.StatusConflict
+	}
 	if t.DefaultIcon == "" {
 		t.DefaultIcon = d.DefaultIcon
 	}
@@ -133,41 +366,370 @@ func applyThemeDefaults(t *Theme) {
 	if t.TreeLines == "" {
 		t.TreeLines = d.TreeLines
 	}
+	if t.Warning == "" {
+		t.Warning = d.Warning
+	}
+	if t.LineNumber == "" {
+		t.LineNumber = d.LineNumber
+	}
 }
 
-func LoadConfig() Config {
-	cfg := DefaultConfig()
-
+// configDir returns ~/.config/sidegit, or "" if there is no home directory.
+func configDir() string {
 	home, err := os.UserHomeDir()
 	if err != nil {
-		return cfg
+		return ""
 	}
+	return filepath.Join(home, ".config", "sidegit")
+}
+
+// configPath returns the path of config.yaml, or "" if there is no home
+// directory.
+func configPath() string {
+	dir := configDir()
+	if dir == "" {
+		return ""
+	}
+	return filepath.Join(dir, "config.yaml")
+}
+
+// serviceOptions maps the config onto the repo service for root.
+func (c Config) serviceOptions(root string) sidegit.Options {
+	var bare []sidegit.BareRepo
+	for _, b := range c.BareRepos {
+		bare = append(bare, sidegit.BareRepo{GitDir: expandHome(b.GitDir), WorkTree: expandHome(b.WorkTree), Name: b.Name})
+	}
+	// "dist/" reads like a .gitignore line; the watcher matches it as "dist"
+	var watchIgnore []string
+	for _, p := range c.WatchIgnore {
+		if p = strings.TrimRight(p, "/"); p != "" {
+			watchIgnore = append(watchIgnore, p)
+		}
+	}
+	return sidegit.Options{
+		Scan:            sidegit.ScanOptions{Depth: c.ScanDepth, Ignore: c.IgnoreDirs, Hidden: c.HiddenRepos, Exclude: c.ExcludeRepos, Bare: bare, Nested: c.NestedRepos},
+		PollInterval:    time.Duration(c.PollInterval) * time.Second,
+		MinScanInterval: time.Duration(c.RescanMS) * time.Millisecond,
+		FetchInterval:   time.Duration(c.FetchInterval) * time.Second,
+		FetchWorkers:    c.FetchWorkers,
+		RootName:        c.RootName,
+		NoWatch:         c.SafeMode || c.Overrides.NoWatch,
+		WatchIgnore:     watchIgnore,
+		Offline:         c.SafeMode,
+		ConfigFiles:     []string{c.Overrides.configFile(), projectConfigPath(root)},
+	}
+}
+
+// LoadConfigFile reads a config file given with --config. Unlike the
+// default location, a missing file is an error. Like every loader it
+// returns a usable config along with a *configProblems error.
+func LoadConfigFile(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return DefaultConfig(), err
+	}
+	return parseConfig(DefaultConfig(), data, path)
+}
+
+// LoadConfig reads the user config. The second return value reports whether
+// this is the first run, i.e. the default config file was just written. A
+// config.yaml that doesn't parse leaves the defaults, and the error says
+// why.
+func LoadConfig() (Config, bool, error) {
+	cfg := DefaultConfig()
 
-	configDir := filepath.Join(home, ".config", "sidegit")
-	configFile := filepath.Join(configDir, "config.yaml")
+	configDir := configDir()
+	if configDir == "" {
+		return cfg, false, nil
+	}
+	configFile := configPath()
 
 	data, err := os.ReadFile(configFile)
 	if err != nil {
 		// Create default config file
-		_ = os.MkdirAll(configDir, 0755)
+		if err := os.MkdirAll(configDir, 0755); err != nil {
+			return cfg, false, nil
+		}
 		defaultData, _ := yaml.Marshal(cfg)
-		_ = os.WriteFile(configFile, defaultData, 0644)
-		return cfg
+		if err := os.WriteFile(configFile, defaultData, 0644); err != nil {
+			return cfg, false, nil
+		}
+		return cfg, true, nil
+	}
+
+	cfg, err = parseConfig(cfg, data, configFile)
+	return cfg, false, err
+}
+
+// projectConfigPath returns the path of the workspace config in root.
+func projectConfigPath(root string) string {
+	return filepath.Join(root, ".sidegit.yaml")
+}
+
+// LoadProjectConfig layers root/.sidegit.yaml over cfg, so each workspace
+// can override the global config. A missing file leaves cfg unchanged.
+func LoadProjectConfig(cfg Config, root string) (Config, error) {
+	path := projectConfigPath(root)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return cfg, nil
+		}
+		return cfg, err
+	}
+	return parseConfig(cfg, data, path)
+}
+
+// ReloadConfig re-reads config.yaml and the workspace config in root after
+// one of them changed on disk, then re-applies the command-line overrides.
+// A file that doesn't parse is an error, so a half-typed edit doesn't wipe
+// the running config; problems in files that do parse come back joined
+// with a usable config, see isConfigProblems.
+func ReloadConfig(root string, o Overrides) (Config, error) {
+	cfg, err := LoadConfigFile(o.configFile())
+	if err != nil && !isConfigProblems(err) {
+		return Config{}, err
+	}
+	cfg, projectErr := LoadProjectConfig(cfg, root)
+	if projectErr != nil && !isConfigProblems(projectErr) {
+		return Config{}, projectErr
+	}
+	if err := o.Apply(&cfg); err != nil {
+		return Config{}, err
+	}
+	return cfg, errors.Join(err, projectErr)
+}
+
+// editConfigFile applies edit to the top-level mapping of the config file
+// at path and writes it back. It edits the YAML tree rather than
+// re-encoding a Config, so comments and the keys left at their defaults
+// survive.
+func editConfigFile(path string, edit func(root *yaml.Node) error) error {
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	if doc.Kind == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return fmt.Errorf("%s: not a YAML mapping", path)
+	}
+	if err := edit(root); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	var b strings.Builder
+	enc := yaml.NewEncoder(&b)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return err
+	}
+	if err := enc.Close(); err != nil {
+		return err
 	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(b.String()), 0644)
+}
+
+// mappingValue returns the value under key in the mapping node m, adding
+// an empty node of kind if there is none.
+func mappingValue(m *yaml.Node, key string, kind yaml.Kind) *yaml.Node {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return m.Content[i+1]
+		}
+	}
+	v := &yaml.Node{Kind: kind}
+	m.Content = append(m.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, v)
+	return v
+}
+
+// appendConfigList adds value to the list under key in the config file at
+// path, unless it's there already.
+func appendConfigList(path, key, value string) error {
+	return editConfigFile(path, func(root *yaml.Node) error {
+		list := mappingValue(root, key, yaml.SequenceNode)
+		switch {
+		case list.Kind == yaml.ScalarNode && list.Tag == "!!null":
+			*list = yaml.Node{Kind: yaml.SequenceNode}
+		case list.Kind != yaml.SequenceNode:
+			return fmt.Errorf("%s is not a list", key)
+		}
+		for _, n := range list.Content {
+			if n.Value == value {
+				return nil
+			}
+		}
+		// An empty flow list ("[]") would put the new entry on one line
+		list.Style &^= yaml.FlowStyle
+		list.Content = append(list.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: value})
+		return nil
+	})
+}
 
-	_ = yaml.Unmarshal(data, &cfg)
+// setThemeColors writes colors, by config key, into the theme in the
+// config file at path. A theme given as a preset name becomes a map that
+// starts from that preset.
+func setThemeColors(path string, colors map[string]string) error {
+	return editConfigFile(path, func(root *yaml.Node) error {
+		theme := mappingValue(root, "theme", yaml.MappingNode)
+		switch {
+		case theme.Kind == yaml.ScalarNode && theme.Tag == "!!null":
+			*theme = yaml.Node{Kind: yaml.MappingNode}
+		case theme.Kind == yaml.ScalarNode:
+			preset := *theme
+			*theme = yaml.Node{Kind: yaml.MappingNode}
+			*mappingValue(theme, "preset", yaml.ScalarNode) = preset
+		case theme.Kind != yaml.MappingNode:
+			return fmt.Errorf("theme is not a preset name or a map")
+		}
+		theme.Style &^= yaml.FlowStyle
+		for _, key := range slices.Sorted(maps.Keys(colors)) {
+			v := mappingValue(theme, key, yaml.ScalarNode)
+			// Tagged as a string, so the encoder quotes "12" and "#fff"
+			*v = yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: colors[key], LineComment: v.LineComment}
+		}
+		return nil
+	})
+}
+
+// parseConfig decodes config data over base and validates the result.
+// Keys missing from data keep their value from base.
+func parseConfig(base Config, data []byte, path string) (Config, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		// Not YAML, so there's nothing to take from it
+		return base, fmt.Errorf("%s: %w", path, err)
+	}
+	cfg := base
+	var problems []string
+	if len(doc.Content) > 0 {
+		problems = unknownKeys(doc.Content[0], reflect.TypeOf(cfg), "")
+		// A value of the wrong type leaves its setting alone and decoding
+		// carries on with the rest
+		var typeErr *yaml.TypeError
+		if err := doc.Decode(&cfg); errors.As(err, &typeErr) {
+			problems = append(problems, typeErr.Errors...)
+		} else if err != nil {
+			return base, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	problems = append(problems, checkColors(&cfg)...)
 	applyThemeDefaults(&cfg.Theme)
 
-	// Validate
-	if cfg.DiffPosition != "right" && cfg.DiffPosition != "bottom" {
+	// Validate. Out of range numbers are clamped quietly; a value that
+	// isn't one of the choices is reported
+	invalid := func(key, value, fallback string) {
+		if value != "" {
+			problems = append(problems, fmt.Sprintf("%s: invalid value %q, using %s", key, value, fallback))
+		}
+	}
+	cfg.Layouts = normalizeLayouts(cfg.Layouts)
+	if _, ok := findLayout(cfg.allLayouts(), cfg.DiffPosition); !ok {
+		invalid("diff_position", cfg.DiffPosition, "right")
 		cfg.DiffPosition = "right"
 	}
-	if cfg.ScanDepth < 1 {
-		cfg.ScanDepth = 1
+	if cfg.ScanDepth < 0 {
+		cfg.ScanDepth = 0
 	}
 	if cfg.PollInterval < 0 {
 		cfg.PollInterval = 0
 	}
+	if cfg.FetchInterval < 0 {
+		cfg.FetchInterval = 0
+	}
+	if cfg.RescanMS < 0 {
+		cfg.RescanMS = 0
+	}
+	if cfg.GitTimeout < 0 {
+		cfg.GitTimeout = 0
+	}
+	if cfg.FetchWorkers < 1 {
+		cfg.FetchWorkers = 4
+	}
+	if cfg.PRInterval < 30 {
+		cfg.PRInterval = 30
+	}
+	if cfg.AutoCommitQuiet < 5 {
+		cfg.AutoCommitQuiet = 5
+	}
+	if cfg.SnapshotInterval < 60 {
+		cfg.SnapshotInterval = 60
+	}
+	if cfg.SnapshotKeep < 1 {
+		cfg.SnapshotKeep = 1
+	}
+	if cfg.DiffContext < 0 {
+		cfg.DiffContext = 3
+	}
+	if cfg.DiffMaxLines < 0 {
+		cfg.DiffMaxLines = 0
+	}
+	if cfg.DiffWarnKB < 0 {
+		cfg.DiffWarnKB = 0
+	}
+	if cfg.StaleDays < 0 {
+		cfg.StaleDays = 0
+	}
+	if cfg.BackupDays < 0 {
+		cfg.BackupDays = 0
+	}
+	if cfg.RepoSort != "name" && cfg.RepoSort != "frecency" && cfg.RepoSort != "recent" {
+		invalid("repo_sort", cfg.RepoSort, "name")
+		cfg.RepoSort = "name"
+	}
+	if _, ok := statusByName(cfg.Filters.Only); !ok {
+		invalid("filters.only", cfg.Filters.Only, "all")
+		cfg.Filters.Only = ""
+	}
+	if cfg.Background != "light" && cfg.Background != "dark" {
+		if cfg.Background != "auto" {
+			invalid("background", cfg.Background, "auto")
+		}
+		cfg.Background = "auto"
+	}
+	if !slices.Contains([]string{"all", "normal", "no", "repo"}, cfg.UntrackedFiles) {
+		invalid("untracked_files", cfg.UntrackedFiles, "all")
+		cfg.UntrackedFiles = "all"
+	}
+	if cfg.Editor != "auto" && !slices.Contains(editors, cfg.Editor) {
+		invalid("editor", cfg.Editor, "auto")
+		cfg.Editor = "auto"
+	}
+	if cfg.Language != "auto" && !slices.Contains(languages(), cfg.Language) {
+		invalid("language", cfg.Language, "auto")
+		cfg.Language = "auto"
+	}
+	if _, _, err := parseHeight(cfg.Height); err != nil {
+		invalid("height", cfg.Height, "40%")
+		cfg.Height = "40%"
+	}
+	for key, mode := range map[string]*string{"alerts.dirty": &cfg.Alerts.Dirty, "alerts.behind": &cfg.Alerts.Behind, "alerts.job_done": &cfg.Alerts.JobDone} {
+		if !slices.Contains(alertModes, *mode) {
+			invalid(key, *mode, "off")
+			*mode = "off"
+		}
+	}
+	if cfg.Alerts.JobSeconds < 0 {
+		cfg.Alerts.JobSeconds = 0
+	}
+	for name, command := range cfg.Commands {
+		if strings.TrimSpace(command) == "" {
+			problems = append(problems, fmt.Sprintf("commands.%s: no command given, leaving it out", name))
+			delete(cfg.Commands, name)
+		}
+	}
 
-	return cfg
+	if len(problems) > 0 {
+		return cfg, &configProblems{file: path, problems: problems}
+	}
+	return cfg, nil
 }
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// configProblems lists what's wrong in a config file that still loaded:
+// unknown keys, values of the wrong type and invalid values. Each of those
+// settings keeps its previous value or default; the rest of the file
+// applies.
+type configProblems struct {
+	file     string
+	problems []string
+}
+
+func (p *configProblems) Error() string {
+	return p.file + ": " + strings.Join(p.problems, "; ")
+}
+
+// summary is the first problem and a count of the rest, short enough for
+// the status bar.
+func (p *configProblems) summary() string {
+	s := p.file + ": " + p.problems[0]
+	if n := len(p.problems) - 1; n > 0 {
+		s += fmt.Sprintf(" (+%d more, see sidegit config check)", n)
+	}
+	return s
+}
+
+// isConfigProblems reports whether err from loading a config only lists
+// problems, so the config that came with it is usable.
+func isConfigProblems(err error) bool {
+	var p *configProblems
+	return errors.As(err, &p)
+}
+
+// configNotice turns an error from loading config files into a line for
+// the status bar.
+func configNotice(err error) string {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		var parts []string
+		for _, e := range joined.Unwrap() {
+			parts = append(parts, configNotice(e))
+		}
+		return strings.Join(parts, "; ")
+	}
+	var p *configProblems
+	if errors.As(err, &p) {
+		return p.summary()
+	}
+	return err.Error()
+}
+
+// unknownKeys returns a problem for every key in n that t has no field
+// for, looking into nested settings, lists and maps.
+func unknownKeys(n *yaml.Node, t reflect.Type, path string) []string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	var problems []string
+	switch {
+	case t.Kind() == reflect.Struct && n.Kind == yaml.MappingNode:
+		fields := map[string]reflect.Type{}
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+			if name == "-" || !f.IsExported() {
+				continue
+			}
+			if name == "" {
+				name = strings.ToLower(f.Name)
+			}
+			fields[name] = f.Type
+		}
+		if t == reflect.TypeOf(Theme{}) {
+			fields["preset"] = reflect.TypeOf("") // see Theme.UnmarshalYAML
+		}
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key, val := n.Content[i], n.Content[i+1]
+			if key.Value == "<<" {
+				continue // a merge key
+			}
+			ft, ok := fields[key.Value]
+			if !ok {
+				problems = append(problems, fmt.Sprintf("line %d: unknown key %q", key.Line, path+key.Value))
+				continue
+			}
+			problems = append(problems, unknownKeys(val, ft, path+key.Value+".")...)
+		}
+	case t.Kind() == reflect.Slice && n.Kind == yaml.SequenceNode:
+		for _, item := range n.Content {
+			problems = append(problems, unknownKeys(item, t.Elem(), path)...)
+		}
+	case t.Kind() == reflect.Map && n.Kind == yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			problems = append(problems, unknownKeys(n.Content[i+1], t.Elem(), path+n.Content[i].Value+".")...)
+		}
+	}
+	return problems
+}
+
+// checkColors clears the theme colors lipgloss can't use, so they fall
+// back to the default theme's, and drops bad repo_accents and
+// branch_colors entries. It returns a problem for each.
+func checkColors(cfg *Config) []string {
+	var problems []string
+	names := colorNames()
+	for i, c := range cfg.Theme.colors() {
+		if !validColor(*c) {
+			problems = append(problems, fmt.Sprintf("theme.%s: invalid color %q, using the default", names[i], *c))
+			*c = ""
+		}
+	}
+	for _, m := range []struct {
+		key    string
+		colors map[string]string
+	}{{"repo_accents", cfg.RepoAccents}, {"branch_colors", cfg.BranchColors}} {
+		for _, k := range slices.Sorted(maps.Keys(m.colors)) {
+			if c := m.colors[k]; !validColor(c) {
+				problems = append(problems, fmt.Sprintf("%s.%s: invalid color %q, ignored", m.key, k, c))
+				delete(m.colors, k)
+			}
+		}
+	}
+	return problems
+}
+
+// validColor reports whether s is a color lipgloss understands: an ANSI
+// number, a #rgb or #rrggbb hex code, or a "light|dark" pair of them. An
+// empty string means no color.
+func validColor(s string) bool {
+	if light, dark, ok := strings.Cut(s, "|"); ok {
+		return light != "" && dark != "" && !strings.Contains(dark, "|") && validColor(light) && validColor(dark)
+	}
+	if s == "" {
+		return true
+	}
+	if hex, ok := strings.CutPrefix(s, "#"); ok {
+		if len(hex) != 3 && len(hex) != 6 {
+			return false
+		}
+		_, err := strconv.ParseUint(hex, 16, 32)
+		return err == nil
+	}
+	n, err := strconv.Atoi(s)
+	return err == nil && n >= 0 && n <= 255
+}
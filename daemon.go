@@ -0,0 +1,335 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"syscall"
+
+	"github.com/hermanschutte/sidegit/pkg/sidegit"
+)
+
+// Engine is what the TUI needs from a repo service. *Service runs one in
+// process; *Client talks to one running in a daemon.
+type Engine interface {
+	Refresh()
+	RefreshRepo(repoPath string)
+	Updates() <-chan []sidegit.Repo
+	Snapshot() []sidegit.Repo
+	Fetching() bool
+	CancelFetch()
+	ConfigChanges() <-chan struct{}
+	Stop()
+}
+
+// The daemon protocol is newline-delimited JSON over a unix socket. The
+// daemon sends daemonEvents: a "repos" snapshot right after connecting and
+// after every scan, and "config" when the config file changes. Clients
+// send daemonRequests to trigger rescans or stop the background fetch.
+type daemonEvent struct {
+	Type     string         `json:"type"` // "repos" or "config"
+	Repos    []sidegit.Repo `json:"repos,omitempty"`
+	Fetching bool           `json:"fetching,omitempty"`
+}
+
+type daemonRequest struct {
+	Op   string `json:"op"`             // "refresh" or "cancel-fetch"
+	Repo string `json:"repo,omitempty"` // empty for everything
+}
+
+// socketPath returns the daemon socket for a scan root. Each root gets its
+// own daemon, so the path is keyed by a hash of the root.
+func socketPath(root string) string {
+	dir := os.Getenv("XDG_RUNTIME_DIR")
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	h := fnv.New64a()
+	h.Write([]byte(root))
+	return filepath.Join(dir, fmt.Sprintf("sidegit-%d-%x.sock", os.Getuid(), h.Sum64()))
+}
+
+// RunDaemon serves s on the socket for root until interrupted.
+func RunDaemon(s *sidegit.Service, root string) error {
+	path := socketPath(root)
+	if c, err := net.Dial("unix", path); err == nil {
+		c.Close()
+		return fmt.Errorf("a daemon is already serving %s", root)
+	}
+	_ = os.Remove(path) // stale socket from a daemon that died
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(path)
+
+	// Close the listener on SIGINT/SIGTERM so the socket gets removed
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	var stopping atomic.Bool
+	go func() {
+		<-stop
+		stopping.Store(true)
+		ln.Close()
+	}()
+
+	d := &daemon{service: s, clients: map[*daemonConn]bool{}}
+	go d.forward()
+	s.Refresh()
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if stopping.Load() {
+				return nil
+			}
+			return err
+		}
+		go d.serve(conn)
+	}
+}
+
+type daemon struct {
+	service *sidegit.Service
+
+	mu      sync.Mutex
+	clients map[*daemonConn]bool
+	last    *daemonEvent // latest snapshot, sent to new clients
+}
+
+// daemonConn queues events for one client, so a client that reads slowly
+// holds up nobody else. A newer snapshot replaces one still queued.
+type daemonConn struct {
+	enc  *json.Encoder
+	wake chan struct{}
+
+	mu     sync.Mutex
+	repos  *daemonEvent // latest snapshot not sent yet
+	config bool         // a config change not sent yet
+}
+
+func (c *daemonConn) send(ev daemonEvent) {
+	c.mu.Lock()
+	if ev.Type == "repos" {
+		c.repos = &ev
+	} else {
+		c.config = true
+	}
+	c.mu.Unlock()
+	select {
+	case c.wake <- struct{}{}:
+	default:
+	}
+}
+
+// write sends queued events until the connection fails or done closes.
+func (c *daemonConn) write(done <-chan struct{}) error {
+	for {
+		select {
+		case <-c.wake:
+		case <-done:
+			return nil
+		}
+		c.mu.Lock()
+		repos, config := c.repos, c.config
+		c.repos, c.config = nil, false
+		c.mu.Unlock()
+		if repos != nil {
+			if err := c.enc.Encode(*repos); err != nil {
+				return err
+			}
+		}
+		if config {
+			if err := c.enc.Encode(daemonEvent{Type: "config"}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// forward fans service snapshots and config changes out to every client.
+func (d *daemon) forward() {
+	for {
+		var ev daemonEvent
+		select {
+		case repos, ok := <-d.service.Updates():
+			if !ok {
+				return
+			}
+			ev = daemonEvent{Type: "repos", Repos: repos, Fetching: d.service.Fetching()}
+		case _, ok := <-d.service.ConfigChanges():
+			if !ok {
+				return
+			}
+			ev = daemonEvent{Type: "config"}
+		}
+		d.mu.Lock()
+		if ev.Type == "repos" {
+			d.last = &ev
+		}
+		clients := make([]*daemonConn, 0, len(d.clients))
+		for c := range d.clients {
+			clients = append(clients, c)
+		}
+		d.mu.Unlock()
+		for _, c := range clients {
+			c.send(ev)
+		}
+	}
+}
+
+func (d *daemon) serve(conn net.Conn) {
+	defer conn.Close()
+	c := &daemonConn{enc: json.NewEncoder(conn), wake: make(chan struct{}, 1)}
+
+	d.mu.Lock()
+	d.clients[c] = true
+	last := d.last
+	d.mu.Unlock()
+	defer func() {
+		d.mu.Lock()
+		delete(d.clients, c)
+		d.mu.Unlock()
+	}()
+
+	if last != nil {
+		c.send(*last)
+	}
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		if c.write(done) != nil {
+			conn.Close() // ends the read loop below
+		}
+	}()
+
+	sc := bufio.NewScanner(conn)
+	for sc.Scan() {
+		var req daemonRequest
+		if err := json.Unmarshal(sc.Bytes(), &req); err != nil {
+			continue
+		}
+		switch req.Op {
+		case "refresh":
+			d.service.RefreshRepo(req.Repo)
+		case "cancel-fetch":
+			d.service.CancelFetch()
+		}
+	}
+}
+
+// Client is an Engine backed by a daemon.
+type Client struct {
+	conn    net.Conn
+	encMu   sync.Mutex
+	enc     *json.Encoder
+	updates chan []sidegit.Repo
+	configs chan struct{}
+
+	mu       sync.RWMutex
+	repos    []sidegit.Repo
+	err      error // why the connection ended
+	fetching atomic.Bool
+}
+
+// DialDaemon connects to the daemon serving root and waits for its first
+// snapshot. It fails fast when no daemon is running.
+func DialDaemon(root string) (*Client, error) {
+	conn, err := net.Dial("unix", socketPath(root))
+	if err != nil {
+		return nil, err
+	}
+	c := &Client{
+		conn:    conn,
+		enc:     json.NewEncoder(conn),
+		updates: make(chan []sidegit.Repo, 1),
+		configs: make(chan struct{}, 1),
+	}
+	dec := json.NewDecoder(bufio.NewReader(conn))
+	var ev daemonEvent
+	for ev.Type != "repos" {
+		if err := dec.Decode(&ev); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	c.handle(ev)
+	go c.read(dec)
+	return c, nil
+}
+
+// read handles the daemon's events until the connection ends, then closes
+// Updates and ConfigChanges, with the reason in Err.
+func (c *Client) read(dec *json.Decoder) {
+	for {
+		var ev daemonEvent
+		if err := dec.Decode(&ev); err != nil {
+			if errors.Is(err, io.EOF) {
+				err = errors.New("the daemon stopped")
+			}
+			c.mu.Lock()
+			c.err = err
+			c.mu.Unlock()
+			close(c.updates)
+			close(c.configs)
+			return
+		}
+		c.handle(ev)
+	}
+}
+
+// Err returns why the connection to the daemon ended, once Updates is
+// closed.
+func (c *Client) Err() error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.err
+}
+
+func (c *Client) handle(ev daemonEvent) {
+	switch ev.Type {
+	case "repos":
+		c.mu.Lock()
+		c.repos = ev.Repos
+		c.mu.Unlock()
+		c.fetching.Store(ev.Fetching)
+		select {
+		case <-c.updates: // drop a stale snapshot nobody read yet
+		default:
+		}
+		c.updates <- append([]sidegit.Repo(nil), ev.Repos...)
+	case "config":
+		select {
+		case c.configs <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (c *Client) send(req daemonRequest) {
+	c.encMu.Lock()
+	defer c.encMu.Unlock()
+	_ = c.enc.Encode(req)
+}
+
+func (c *Client) Refresh()                       { c.send(daemonRequest{Op: "refresh"}) }
+func (c *Client) RefreshRepo(repoPath string)    { c.send(daemonRequest{Op: "refresh", Repo: repoPath}) }
+func (c *Client) Updates() <-chan []sidegit.Repo { return c.updates }
+func (c *Client) Fetching() bool                 { return c.fetching.Load() }
+func (c *Client) CancelFetch()                   { c.send(daemonRequest{Op: "cancel-fetch"}) }
+func (c *Client) ConfigChanges() <-chan struct{} { return c.configs }
+func (c *Client) Stop()                          { c.conn.Close() }
+
+func (c *Client) Snapshot() []sidegit.Repo {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return append([]sidegit.Repo(nil), c.repos...)
+}
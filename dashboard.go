@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/hermanschutte/sidegit/pkg/sidegit"
+)
+
+// dashSorts are the dashboard's sort columns, cycled with s. Names sort
+// A to Z, everything else biggest or most recent first.
+var dashSorts = []string{"repo", "changes", "ahead", "behind", "commit", "fetched"}
+
+// dashStatuses are the status columns of the dashboard.
+var dashStatuses = []sidegit.StatusCode{
+	sidegit.StatusModified,
+	sidegit.StatusAdded,
+	sidegit.StatusDeleted,
+	sidegit.StatusRenamed,
+	sidegit.StatusUntracked,
+	sidegit.StatusConflict,
+}
+
+func (m *model) openDashboard() {
+	m.dashOpen = true
+	m.dashCursor = 0
+}
+
+// dashRows returns the repos in the dashboard's sort order.
+func (m model) dashRows() []sidegit.Repo {
+	rows := append([]sidegit.Repo(nil), m.repos...)
+	sidegit.SortReposByPath(rows)
+	var less func(a, b sidegit.Repo) bool
+	switch dashSorts[m.dashSort] {
+	case "changes":
+		less = func(a, b sidegit.Repo) bool { return len(a.Files) > len(b.Files) }
+	case "ahead":
+		less = func(a, b sidegit.Repo) bool { return a.Ahead > b.Ahead }
+	case "behind":
+		less = func(a, b sidegit.Repo) bool { return a.Behind > b.Behind }
+	case "commit":
+		less = func(a, b sidegit.Repo) bool { return a.LastCommit.After(b.LastCommit) }
+	case "fetched":
+		less = func(a, b sidegit.Repo) bool { return a.Fetched.After(b.Fetched) }
+	}
+	if less != nil {
+		sort.SliceStable(rows, func(i, j int) bool { return less(rows[i], rows[j]) })
+	}
+	if m.dashReverse {
+		for i, j := 0, len(rows)-1; i < j; i, j = i+1, j-1 {
+			rows[i], rows[j] = rows[j], rows[i]
+		}
+	}
+	return rows
+}
+
+func (m model) dashVisible() int {
+	// Outer margin, border and the header row
+	return max(1, m.height-2-2-1)
+}
+
+func (m model) handleDashboardKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc", "q", "I":
+		m.dashOpen = false
+	case "up", "k":
+		m.dashCursor = max(0, m.dashCursor-1)
+	case "down", "j":
+		m.dashCursor = min(len(m.repos)-1, m.dashCursor+1)
+	case "s":
+		m.dashSort = (m.dashSort + 1) % len(dashSorts)
+	case "r":
+		m.dashReverse = !m.dashReverse
+	case "enter":
+		// Jump to the repo in the tree
+		if rows := m.dashRows(); m.dashCursor < len(rows) {
+			m.dashOpen = false
+			m.focused = panelTree
+			m.tree.SelectRepo(rows[m.dashCursor].Path)
+			m.trackVisit()
+		}
+	}
+	return m, nil
+}
+
+func (m model) renderDashboard() string {
+	boxWidth := m.width - 2
+	innerWidth := boxWidth - 2
+	dim := lipgloss.NewStyle().Foreground(themeColor(m.config.Theme.FileCount))
+	header := lipgloss.NewStyle().Bold(true).Foreground(themeColor(m.config.Theme.Title))
+	cursor := lipgloss.NewStyle().Background(themeColor(m.config.Theme.CursorBg))
+
+	rows := m.dashRows()
+	// Widths include a space after the column
+	nameWidth, branchWidth := len("Repo")+1, len("Branch")+1
+	for _, r := range rows {
+		nameWidth = max(nameWidth, lipgloss.Width(r.RelPath)+1)
+		branchWidth = max(branchWidth, lipgloss.Width(r.Branch)+1)
+	}
+	// The fixed columns: ↑ ↓, a count per status, commit and fetch ages
+	fixed := 2*5 + len(dashStatuses)*4 + 2*8
+	nameWidth = min(nameWidth, max(8, (innerWidth-fixed)*3/5))
+	branchWidth = min(branchWidth, max(6, innerWidth-fixed-nameWidth-2))
+
+	cell := func(s string, w int) string {
+		return lipgloss.NewStyle().Width(w).MaxWidth(w).Render(truncateStr(s, w-1))
+	}
+
+	head := cell("Repo", nameWidth) + " " + cell("Branch", branchWidth) + " " + fmt.Sprintf("%5s%5s", "↑", "↓")
+	for _, code := range dashStatuses {
+		head += fmt.Sprintf("%4s", string(code))
+	}
+	head += fmt.Sprintf("%8s%8s", "commit", "fetched")
+	lines := []string{header.Render(head)}
+
+	start := 0
+	if m.dashCursor >= m.dashVisible() {
+		start = m.dashCursor - m.dashVisible() + 1
+	}
+	for i := start; i < len(rows) && i < start+m.dashVisible(); i++ {
+		r := rows[i]
+		plain, faint := lipgloss.NewStyle(), dim
+		if i == m.dashCursor {
+			plain, faint = cursor, dim.Background(themeColor(m.config.Theme.CursorBg))
+		}
+		num := func(n int, w int) string {
+			if n == 0 {
+				return faint.Render(fmt.Sprintf("%*s", w, "·"))
+			}
+			return plain.Render(fmt.Sprintf("%*d", w, n))
+		}
+		age := func(t time.Time) string {
+			if t.IsZero() {
+				return faint.Render(fmt.Sprintf("%8s", "-"))
+			}
+			return plain.Render(fmt.Sprintf("%8s", formatAge(time.Since(t))))
+		}
+		counts := map[sidegit.StatusCode]int{}
+		for _, f := range r.Files {
+			counts[f.Status]++
+		}
+		line := plain.Render(cell(r.RelPath, nameWidth)+" "+cell(r.Branch, branchWidth)+" ") + num(r.Ahead, 5) + num(r.Behind, 5)
+		for _, code := range dashStatuses {
+			line += num(counts[code], 4)
+		}
+		line += age(r.LastCommit) + age(r.Fetched)
+		if vis := lipgloss.Width(line); vis < innerWidth {
+			line += plain.Render(strings.Repeat(" ", innerWidth-vis))
+		}
+		lines = append(lines, line)
+	}
+
+	order := "↓"
+	if m.dashReverse {
+		order = "↑"
+	}
+	title := fmt.Sprintf("Dashboard: %d repos, sorted by %s %s (s sort, r reverse, ↵ go to repo)", len(rows), dashSorts[m.dashSort], order)
+	box := renderBorderedPanel(title, strings.Join(lines, "\n"), boxWidth, m.height-2, m.config.Theme.BorderFocused, m.config.Theme.Title)
+	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, box,
+		lipgloss.WithWhitespaceChars(" "),
+		lipgloss.WithWhitespaceForeground(lipgloss.Color("0")))
+}
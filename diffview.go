@@ -0,0 +1,243 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/hermanschutte/sidegit/pkg/sidegit"
+)
+
+type diffKind int
+
+const (
+	diffMeta      diffKind = iota // file headers, and anything outside a hunk
+	diffHunk                      // "@@ -12,5 +12,7 @@"
+	diffContext                   // unchanged
+	diffAdded                     // "+"
+	diffRemoved                   // "-"
+	diffNoNewline                 // "\ No newline at end of file"
+)
+
+// diffLine is a row of the diff panel: a line of a sidegit.FileDiff,
+// styled.
+type diffLine struct {
+	kind     diffKind
+	text     string
+	old, new int // line numbers, 0 where the line isn't on that side
+}
+
+// flattenDiff lays the files of a diff out line by line, as git printed
+// them, in the theme's colors.
+func flattenDiff(files []sidegit.FileDiff, theme Theme) []diffLine {
+	meta := lipgloss.NewStyle().Bold(true)
+	hunk := lipgloss.NewStyle().Foreground(themeColor(theme.Title))
+	plain := lipgloss.NewStyle()
+	styles := map[sidegit.LineKind]lipgloss.Style{
+		sidegit.LineContext:   plain,
+		sidegit.LineAdded:     lipgloss.NewStyle().Foreground(themeColor(theme.StatusAdded)),
+		sidegit.LineRemoved:   lipgloss.NewStyle().Foreground(themeColor(theme.StatusDeleted)),
+		sidegit.LineNoNewline: lipgloss.NewStyle().Foreground(themeColor(theme.LineNumber)),
+	}
+	prefixes := map[sidegit.LineKind]string{sidegit.LineContext: " ", sidegit.LineAdded: "+", sidegit.LineRemoved: "-"}
+	kinds := map[sidegit.LineKind]diffKind{
+		sidegit.LineContext: diffContext, sidegit.LineAdded: diffAdded,
+		sidegit.LineRemoved: diffRemoved, sidegit.LineNoNewline: diffNoNewline,
+	}
+
+	var lines []diffLine
+	for _, f := range files {
+		for _, h := range f.Header {
+			lines = append(lines, diffLine{kind: diffMeta, text: meta.Render(h)})
+		}
+		for _, h := range f.Hunks {
+			// Color the range like git does, not the function after it
+			at := strings.Index(h.Header[2:], "@@") + 4
+			lines = append(lines, diffLine{kind: diffHunk, text: hunk.Render(h.Header[:at]) + plain.Render(h.Header[at:])})
+			for _, l := range h.Lines {
+				lines = append(lines, diffLine{kind: kinds[l.Kind], text: styles[l.Kind].Render(prefixes[l.Kind] + l.Text), old: l.Old, new: l.New})
+			}
+		}
+	}
+	return lines
+}
+
+// foldKeep is how many unchanged lines stay in view on each side of a
+// fold, and foldMin the fewest lines worth folding away.
+const (
+	foldKeep = 3
+	foldMin  = 10
+)
+
+// diffFold is a run of unchanged lines, lines[start:end], that the diff
+// panel can show as a single marker.
+type diffFold struct{ start, end int }
+
+// diffFolds finds the runs of unchanged lines long enough to fold, keeping
+// foldKeep lines next to the changes around them.
+func diffFolds(lines []diffLine) []diffFold {
+	var folds []diffFold
+	for i := 0; i < len(lines); {
+		if lines[i].kind != diffContext {
+			i++
+			continue
+		}
+		j := i
+		for j < len(lines) && lines[j].kind == diffContext {
+			j++
+		}
+		start, end := i, j
+		if i > 0 && lines[i-1].kind != diffHunk {
+			start += foldKeep // a change comes before the run
+		}
+		if j < len(lines) && (lines[j].kind == diffAdded || lines[j].kind == diffRemoved) {
+			end -= foldKeep
+		}
+		if end-start >= foldMin {
+			folds = append(folds, diffFold{start, end})
+		}
+		i = j
+	}
+	return folds
+}
+
+// renderDiff lays out a diff for the panel: with line number columns if
+// numbers is set, and each fold not in open as a marker. rows maps every
+// row of the result to its line, or to the first line of a fold.
+func renderDiff(lines []diffLine, folds []diffFold, open map[int]bool, numbers bool, theme Theme) (string, []int) {
+	top := 0
+	if numbers {
+		for _, l := range lines {
+			top = max(top, l.old, l.new)
+		}
+	}
+	w := len(strconv.Itoa(top))
+	dim := lipgloss.NewStyle().Foreground(themeColor(theme.LineNumber))
+	num := func(v int) string {
+		if v == 0 {
+			return strings.Repeat(" ", w)
+		}
+		return fmt.Sprintf("%*d", w, v)
+	}
+
+	var out []string
+	var rows []int
+	for i := 0; i < len(lines); i++ {
+		if len(folds) > 0 && folds[0].start == i {
+			f := folds[0]
+			folds = folds[1:]
+			if !open[f.start] {
+				marker := dim.Render(trf("··· %d unchanged lines ···", f.end-f.start))
+				if top > 0 {
+					marker = strings.Repeat(" ", 2*w+2) + marker
+				}
+				out = append(out, marker)
+				rows = append(rows, i)
+				i = f.end - 1
+				continue
+			}
+		}
+		text := lines[i].text
+		if top > 0 {
+			text = dim.Render(num(lines[i].old)+" "+num(lines[i].new)) + " " + text
+		}
+		out = append(out, text)
+		rows = append(rows, i)
+	}
+	return strings.Join(out, "\n"), rows
+}
+
+// setDiffContent shows the open diff in the diff panel. A diff_pager's
+// output, or a note like "(no changes)", shows as it is.
+func (m *model) setDiffContent() {
+	if m.diffFiles == nil {
+		m.diffLines, m.diffRows = nil, nil
+		m.diffViewport.SetContent(m.diffContent)
+		return
+	}
+	m.diffLines = flattenDiff(m.diffFiles, m.config.Theme)
+	var folds []diffFold
+	if m.config.DiffFold {
+		folds = diffFolds(m.diffLines)
+	}
+	var text string
+	text, m.diffRows = renderDiff(m.diffLines, folds, m.diffUnfolded, m.config.DiffLineNums, m.config.Theme)
+	m.diffViewport.SetContent(text)
+}
+
+// diffRow returns the panel row showing line i of the diff.
+func (m model) diffRow(i int) int {
+	for row, line := range m.diffRows {
+		if line >= i {
+			return row
+		}
+	}
+	return i
+}
+
+// unfoldInView opens the first fold marker on screen in the diff panel.
+func (m *model) unfoldInView() bool {
+	if !m.config.DiffFold || m.diffLines == nil {
+		return false
+	}
+	folded := map[int]bool{}
+	for _, f := range diffFolds(m.diffLines) {
+		folded[f.start] = !m.diffUnfolded[f.start]
+	}
+	end := min(len(m.diffRows), m.diffViewport.YOffset+m.diffViewport.Height)
+	for row := m.diffViewport.YOffset; row < end; row++ {
+		if i := m.diffRows[row]; folded[i] {
+			if m.diffUnfolded == nil {
+				m.diffUnfolded = map[int]bool{}
+			}
+			m.diffUnfolded[i] = true
+			offset := m.diffViewport.YOffset
+			m.setDiffContent()
+			m.diffViewport.SetYOffset(offset)
+			return true
+		}
+	}
+	return false
+}
+
+// diffFileAt returns the file of a repo diff at the top of the diff panel.
+func (m model) diffFileAt() int {
+	if len(m.diffRows) == 0 {
+		return 0
+	}
+	top := m.diffRows[min(m.diffViewport.YOffset, len(m.diffRows)-1)]
+	at, line := 0, 0
+	for i, f := range m.diffFiles {
+		if line > top {
+			break
+		}
+		at = i
+		line += f.Len()
+	}
+	return at
+}
+
+// jumpDiffFile scrolls the diff panel to the next file of a repo diff, or
+// back to the start of the file at the top, then the one before it.
+func (m *model) jumpDiffFile(back bool) {
+	if len(m.diffFiles) == 0 {
+		return
+	}
+	var starts []int
+	line := 0
+	for _, f := range m.diffFiles {
+		starts = append(starts, m.diffRow(line))
+		line += f.Len()
+	}
+	i := m.diffFileAt()
+	switch {
+	case !back && i+1 < len(starts):
+		i++
+	case back && starts[i] >= m.diffViewport.YOffset && i > 0:
+		i--
+	case !back:
+		return
+	}
+	m.diffViewport.SetYOffset(starts[i])
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// editors are the values of the editor setting besides "auto". All but
+// terminal hand the file to an editor that's already running and return
+// straight away, instead of suspending the TUI.
+var editors = []string{"terminal", "nvim", "vscode", "jetbrains"}
+
+// jetbrainsLaunchers are the command-line launchers of JetBrains IDEs, in
+// the order they are looked for on $PATH.
+var jetbrainsLaunchers = []string{"idea", "goland", "pycharm", "webstorm", "phpstorm", "rubymine", "clion", "rider", "rustrover"}
+
+// detectEditor picks an editor for "auto" from the terminal sidegit runs
+// in: Neovim's :terminal sets $NVIM, VS Code's sets TERM_PROGRAM and
+// JetBrains IDEs set TERMINAL_EMULATOR.
+func detectEditor() string {
+	switch {
+	case os.Getenv("NVIM") != "":
+		return "nvim"
+	case os.Getenv("TERM_PROGRAM") == "vscode":
+		return "vscode"
+	case os.Getenv("TERMINAL_EMULATOR") == "JetBrains-JediTerm":
+		return "jetbrains"
+	}
+	return "terminal"
+}
+
+// nvimServer returns the address of the Neovim to send files to.
+func nvimServer(configured string) string {
+	if configured != "" {
+		return configured
+	}
+	if addr := os.Getenv("NVIM"); addr != "" {
+		return addr
+	}
+	return os.Getenv("NVIM_LISTEN_ADDRESS")
+}
+
+// openFileCmd opens filePath in repoPath with the configured editor.
+func openFileCmd(cfg Config, repoPath, filePath string) tea.Cmd {
+	editor := cfg.Editor
+	if editor == "auto" {
+		editor = detectEditor()
+	}
+	absPath := filepath.Join(repoPath, filePath)
+	var args []string
+	var name string
+	switch editor {
+	case "nvim":
+		server := nvimServer(cfg.NvimServer)
+		if server == "" {
+			return func() tea.Msg {
+				return editorFinishedMsg{repo: repoPath, note: "can't open " + filePath, err: fmt.Errorf("no Neovim server; set nvim_server or run sidegit in a Neovim terminal")}
+			}
+		}
+		name, args = "Neovim", []string{"nvim", "--server", server, "--remote", absPath}
+	case "vscode":
+		name, args = "VS Code", []string{"code", "-r", "--goto", absPath}
+	case "jetbrains":
+		for _, l := range jetbrainsLaunchers {
+			if _, err := exec.LookPath(l); err == nil {
+				name, args = l, []string{l, absPath}
+				break
+			}
+		}
+		if args == nil {
+			return func() tea.Msg {
+				return editorFinishedMsg{repo: repoPath, note: "can't open " + filePath, err: fmt.Errorf("no JetBrains launcher on $PATH (%s)", strings.Join(jetbrainsLaunchers, ", "))}
+			}
+		}
+	default:
+		return openInEditorCmd(repoPath, filePath)
+	}
+	return func() tea.Msg {
+		out, err := exec.Command(args[0], args[1:]...).CombinedOutput()
+		if err != nil {
+			if msg := strings.TrimSpace(string(out)); msg != "" {
+				err = fmt.Errorf("%s: %s", args[0], msg)
+			}
+			return editorFinishedMsg{repo: repoPath, note: "can't open " + filePath + " in " + name, err: err}
+		}
+		return editorFinishedMsg{repo: repoPath, note: "opened " + filePath + " in " + name}
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/hermanschutte/sidegit/pkg/sidegit"
+)
+
+// Filters hides files from the tree by status.
+type Filters struct {
+	HideUntracked bool   `yaml:"hide_untracked"`
+	HideStaged    bool   `yaml:"hide_staged"`
+	Only          string `yaml:"only"` // show only this status, see statusNames
+}
+
+// statusNames are the values accepted by Filters.Only.
+var statusNames = []struct {
+	name string
+	code sidegit.StatusCode
+}{
+	{"modified", sidegit.StatusModified},
+	{"added", sidegit.StatusAdded},
+	{"deleted", sidegit.StatusDeleted},
+	{"renamed", sidegit.StatusRenamed},
+	{"untracked", sidegit.StatusUntracked},
+	{"conflict", sidegit.StatusConflict},
+}
+
+func statusByName(name string) (sidegit.StatusCode, bool) {
+	for _, s := range statusNames {
+		if s.name == name {
+			return s.code, true
+		}
+	}
+	return "", false
+}
+
+// Keep reports whether f passes the filters.
+func (fl Filters) Keep(f sidegit.FileStatus) bool {
+	if fl.HideUntracked && f.Status == sidegit.StatusUntracked {
+		return false
+	}
+	if fl.HideStaged && f.IsStaged {
+		return false
+	}
+	if code, ok := statusByName(fl.Only); ok && f.Status != code {
+		return false
+	}
+	return true
+}
+
+// Active reports whether any filter is on.
+func (fl Filters) Active() bool {
+	return fl.HideUntracked || fl.HideStaged || fl.Only != ""
+}
+
+// String describes the active filters for the status bar.
+func (fl Filters) String() string {
+	var parts []string
+	if fl.Only != "" {
+		parts = append(parts, fl.Only+" only")
+	}
+	if fl.HideUntracked {
+		parts = append(parts, "no untracked")
+	}
+	if fl.HideStaged {
+		parts = append(parts, "no staged")
+	}
+	return strings.Join(parts, ", ")
+}
+
+// visibleRepos applies exclude_repos and hide_files to a scan. The scanner
+// leaves excluded repos out itself, but one excluded while sidegit runs
+// stays in the scans until the next start.
+func visibleRepos(repos []sidegit.Repo, cfg Config) []sidegit.Repo {
+	if len(cfg.ExcludeRepos) > 0 {
+		var kept []sidegit.Repo
+		for _, r := range repos {
+			if !sidegit.MatchGlob(cfg.ExcludeRepos, r.RelPath) {
+				kept = append(kept, r)
+			}
+		}
+		repos = kept
+	}
+	return hideFiles(repos, cfg.HideFiles)
+}
+
+// hideFiles drops files matching any of the hide_files patterns. Patterns
+// without a slash match the file name at any depth, like .gitignore; "**"
+// matches any number of directories.
+func hideFiles(repos []sidegit.Repo, patterns []string) []sidegit.Repo {
+	if len(patterns) == 0 {
+		return repos
+	}
+	out := make([]sidegit.Repo, len(repos))
+	for i, r := range repos {
+		var files []sidegit.FileStatus
+		for _, f := range r.Files {
+			if !sidegit.MatchGlob(patterns, f.Path) {
+				files = append(files, f)
+			}
+		}
+		r.Files = files
+		out[i] = r
+	}
+	return out
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/hermanschutte/sidegit/pkg/sidegit"
+)
+
+// openInfoMsg opens the details popup once its rows are gathered.
+type openInfoMsg struct {
+	title string
+	rows  [][2]string
+}
+
+// repoInfoCmd gathers the repo details popup off the UI loop; some rows
+// take a git call or two each.
+func repoInfoCmd(r sidegit.Repo, note string) tea.Cmd {
+	return func() tea.Msg {
+		rows := repoInfoRows(r)
+		if note != "" {
+			rows = append(rows, [2]string{"Note", note})
+		}
+		return openInfoMsg{title: "Repo: " + r.RelPath, rows: rows}
+	}
+}
+
+// repoInfoRows returns the label/value rows shown in the repo details popup.
+func repoInfoRows(r sidegit.Repo) [][2]string {
+	upstream := r.Upstream
+	if upstream == "" {
+		upstream = "(none)"
+	}
+	fetched := "never"
+	if !r.Fetched.IsZero() {
+		fetched = formatAge(time.Since(r.Fetched)) + " ago"
+	}
+	rows := [][2]string{
+		{"Path", r.Path},
+		{"Branch", r.Branch},
+		{"Upstream", upstream},
+	}
+	if remotes, err := sidegit.ListRemotes(r.Path); err == nil {
+		if len(remotes) == 0 {
+			rows = append(rows, [2]string{"Remote", "(none)"})
+		}
+		for _, rm := range remotes {
+			rows = append(rows, [2]string{"Remote", rm.Name + "  " + rm.URL})
+		}
+	}
+	if log, err := sidegit.GetLog(r.Path, 1); err == nil && len(log) > 0 {
+		c := log[0]
+		rows = append(rows, [2]string{"HEAD", c.Short + " " + c.Subject + " (" + c.Author + ", " + c.When + ")"})
+	}
+	if desc := sidegit.DescribeHead(r.Path); desc != "" {
+		rows = append(rows, [2]string{"Tag", desc})
+	}
+	stashes := "none"
+	if n := sidegit.StashCount(r.Path); n > 0 {
+		stashes = fmt.Sprint(n)
+	}
+	rows = append(rows, [2]string{"Stashes", stashes})
+	if size, err := sidegit.ObjectsSize(r.Path); err == nil {
+		rows = append(rows, [2]string{"Size", formatSize(size) + " of git objects"})
+	}
+	rows = append(rows, [2]string{"Fetched", fetched})
+	if r.Shallow {
+		history := "only recent history was fetched"
+		if n := sidegit.HistoryDepth(r.Path); n > 0 {
+			history = plural(n, "commit") + " of history fetched"
+		}
+		rows = append(rows, [2]string{"Shallow", history + "; the repo menu can fetch the rest"})
+	}
+	if r.Partial {
+		partial := "objects are fetched from the remote when needed"
+		if filter := sidegit.PartialCloneFilter(r.Path); filter != "" {
+			partial = "filter " + filter + ", " + partial
+		}
+		rows = append(rows, [2]string{"Partial", partial})
+	}
+	if r.Sparse {
+		sparse := "files outside the sparse-checkout patterns aren't in the worktree"
+		if patterns := sidegit.SparsePatterns(r.Path); len(patterns) > 0 {
+			sparse = "the worktree only has " + strings.Join(patterns, ", ")
+		}
+		rows = append(rows, [2]string{"Sparse", sparse})
+	}
+	for _, w := range r.Warnings {
+		rows = append(rows, [2]string{"Warning", w})
+	}
+	return rows
+}
+
+// cloneBadge labels a repo that isn't fully local, for its tree row.
+func cloneBadge(r sidegit.Repo) string {
+	var parts []string
+	if r.Shallow {
+		parts = append(parts, "shallow")
+	}
+	if r.Partial {
+		parts = append(parts, "partial")
+	}
+	if r.Sparse {
+		parts = append(parts, "sparse")
+	}
+	return strings.Join(parts, " ")
+}
+
+func (m *model) openInfo(title string, rows [][2]string) {
+	m.infoTitle = title
+	m.infoRows = rows
+	m.infoOpen = true
+}
+
+func (m model) renderInfo() string {
+	borderColor := m.config.Theme.BorderFocused
+	keyColor := themeColor(m.config.Theme.Title)
+
+	boxWidth := m.width - 2
+	innerWidth := boxWidth - 2
+
+	labelWidth := 0
+	for _, row := range m.infoRows {
+		labelWidth = max(labelWidth, lipgloss.Width(row[0]))
+	}
+	labelWidth += 2
+
+	var lines []string
+	for _, row := range m.infoRows {
+		label := lipgloss.NewStyle().Foreground(keyColor).Width(labelWidth).Render(row[0])
+		value := truncateStr(row[1], innerWidth-labelWidth)
+		line := label + value
+		vis := lipgloss.Width(line)
+		if vis < innerWidth {
+			line += strings.Repeat(" ", innerWidth-vis)
+		}
+		lines = append(lines, line)
+	}
+
+	content := strings.Join(lines, "\n")
+	box := renderBorderedPanel(m.infoTitle, content, boxWidth, len(lines)+2, borderColor, m.config.Theme.Title)
+
+	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, box,
+		lipgloss.WithWhitespaceChars(" "),
+		lipgloss.WithWhitespaceForeground(lipgloss.Color("0")))
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// minInlineHeight fits the tree panel's border, a row of it and the
+// status bar.
+const minInlineHeight = 6
+
+// parseHeight reads the height setting: a number of rows, or a percentage
+// of the terminal's rows like "40%", as in fzf's --height.
+func parseHeight(s string) (n int, percent bool, err error) {
+	v, percent := strings.CutSuffix(s, "%")
+	n, err = strconv.Atoi(v)
+	if err != nil || n <= 0 || percent && n > 100 {
+		return 0, false, fmt.Errorf("invalid height %q (rows like 20, or a percentage like 40%%)", s)
+	}
+	return n, percent, nil
+}
+
+// viewHeight returns the rows the UI draws in on a terminal with rows
+// rows: all of them on the alternate screen, the height setting inline.
+func (c Config) viewHeight(rows int) int {
+	if !c.Inline {
+		return rows
+	}
+	n, percent, err := parseHeight(c.Height)
+	if err != nil {
+		n, percent = 40, true
+	}
+	if percent {
+		n = rows * n / 100
+	}
+	return max(min(n, rows), min(minInlineHeight, rows))
+}
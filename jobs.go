@@ -0,0 +1,308 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Jobs are the operations that run in the background: fetches, pulls and
+// pushes, bulk operations, commands run in repos, scans and background
+// fetches. The jobs panel (J) lists them with how long they took and what
+// they printed, and cancels the ones still running.
+
+// maxJobs is how many finished jobs the jobs panel keeps.
+const maxJobs = 100
+
+// job is one background operation.
+type job struct {
+	id      int
+	title   string // e.g. "push api"
+	repo    string // the repo's path, "" for a job across repos
+	started time.Time
+	ended   time.Time // zero while running
+	output  string
+	err     error
+	cancel  context.CancelFunc // nil for a job that can't be canceled
+}
+
+func (j *job) running() bool {
+	return j.ended.IsZero()
+}
+
+func (j *job) canceled() bool {
+	return errors.Is(j.err, context.Canceled)
+}
+
+// duration is how long the job took, or has been running.
+func (j *job) duration() time.Duration {
+	if j.running() {
+		return time.Since(j.started)
+	}
+	return j.ended.Sub(j.started)
+}
+
+// finish records that the job ended, with err for a failure.
+func (j *job) finish(err error) {
+	j.ended = time.Now()
+	j.err = err
+	if j.cancel != nil {
+		j.cancel() // release the context
+	}
+}
+
+// addJob records a job starting now. cancel stops it, nil if it can't be.
+func (m *model) addJob(title, repo string, cancel context.CancelFunc) *job {
+	m.jobID++
+	j := &job{id: m.jobID, title: title, repo: repo, started: time.Now(), cancel: cancel}
+	m.jobs = append(m.jobs, j)
+	// Forget the oldest finished jobs past maxJobs
+	for i := 0; len(m.jobs) > maxJobs && i < len(m.jobs); {
+		if m.jobs[i].running() {
+			i++
+			continue
+		}
+		m.jobs = append(m.jobs[:i], m.jobs[i+1:]...)
+	}
+	return j
+}
+
+// jobByID finds a job the panel still keeps, nil if it's gone.
+func (m model) jobByID(id int) *job {
+	for _, j := range m.jobs {
+		if j.id == id {
+			return j
+		}
+	}
+	return nil
+}
+
+// runningJobs counts the jobs still running.
+func (m model) runningJobs() int {
+	n := 0
+	for _, j := range m.jobs {
+		if j.running() {
+			n++
+		}
+	}
+	return n
+}
+
+// stopJobs cancels every job still running, when quitting.
+func (m model) stopJobs() {
+	for _, j := range m.jobs {
+		if j.running() && j.cancel != nil {
+			j.cancel()
+		}
+	}
+}
+
+// jobStartMsg runs op as a job named title. Once it's done, a
+// fileChangedMsg with note follows, or a gitErrorMsg with its error.
+type jobStartMsg struct {
+	title, repo, note string
+	op                func(ctx context.Context) error
+}
+
+// jobDoneMsg reports how job id ended, then passes on then.
+type jobDoneMsg struct {
+	id   int
+	err  error
+	then tea.Msg
+}
+
+// jobCmd runs op on repoPath as a job that can be canceled from the jobs
+// panel, notifying with note once it succeeds.
+func jobCmd(title, repoPath, note string, op func(ctx context.Context) error) tea.Cmd {
+	return func() tea.Msg {
+		return jobStartMsg{title: title, repo: repoPath, note: note, op: op}
+	}
+}
+
+func (m *model) startJob(msg jobStartMsg) tea.Cmd {
+	ctx, cancel := context.WithCancel(context.Background())
+	id := m.addJob(msg.title, msg.repo, cancel).id
+	return func() tea.Msg {
+		err := msg.op(ctx)
+		var then tea.Msg = fileChangedMsg{repo: msg.repo, note: msg.note}
+		switch {
+		case errors.Is(err, context.Canceled):
+			then = fileChangedMsg{repo: msg.repo, note: "canceled " + msg.title}
+		case err != nil:
+			then = gitErrorMsg{repo: msg.repo, err: err, changed: true}
+		}
+		return jobDoneMsg{id: id, err: err, then: then}
+	}
+}
+
+// scanJobStarted records a scan asked for with r, unless one is running.
+// The next snapshot from the engine ends it.
+func (m *model) scanJobStarted() {
+	if m.scanJob == 0 {
+		m.scanJob = m.addJob("scan "+m.scanRoot, "", nil).id
+	}
+}
+
+// trackJobs ends the scan job once a snapshot arrives, and follows the
+// engine's background fetch with a job of its own, returning its alert
+// when it ends.
+func (m *model) trackJobs() tea.Cmd {
+	if j := m.jobByID(m.scanJob); j != nil {
+		j.output = plural(len(m.repos), "repo")
+		j.finish(nil)
+	}
+	m.scanJob = 0
+
+	var alert tea.Cmd
+	fetching := m.service != nil && m.service.Fetching()
+	switch {
+	case fetching && m.fetchJob == 0:
+		ctx, cancel := context.WithCancel(context.Background())
+		m.fetchStop = context.AfterFunc(ctx, m.service.CancelFetch)
+		m.fetchJob = m.addJob("background fetch", "", cancel).id
+	case !fetching && m.fetchJob != 0:
+		// Detached first, so finishing the job doesn't stop the next fetch
+		canceled := !m.fetchStop()
+		if j := m.jobByID(m.fetchJob); j != nil {
+			var failed []string
+			for _, r := range m.repos {
+				if r.FetchError != "" {
+					failed = append(failed, r.RelPath+": "+r.FetchError)
+				}
+			}
+			var err error
+			switch {
+			case canceled:
+				err = context.Canceled
+			case len(failed) > 0:
+				err = fmt.Errorf("%d of %d repos failed", len(failed), len(m.repos))
+			}
+			j.output = strings.Join(failed, "\n")
+			j.finish(err)
+			alert = m.jobAlert(j, err != nil)
+		}
+		m.fetchJob = 0
+	}
+	return alert
+}
+
+// jobsTickMsg redraws the jobs panel, so running jobs' times go up.
+type jobsTickMsg struct{}
+
+func jobsTickCmd() tea.Cmd {
+	return tea.Tick(time.Second, func(time.Time) tea.Msg { return jobsTickMsg{} })
+}
+
+func (m *model) openJobs() tea.Cmd {
+	m.jobsOpen = true
+	m.jobsCursor = 0
+	return jobsTickCmd()
+}
+
+// jobsNewestFirst returns the jobs in the order the panel lists them.
+func (m model) jobsNewestFirst() []*job {
+	jobs := make([]*job, len(m.jobs))
+	for i, j := range m.jobs {
+		jobs[len(jobs)-1-i] = j
+	}
+	return jobs
+}
+
+func (m model) handleJobsKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	jobs := m.jobsNewestFirst()
+	switch msg.String() {
+	case "up", "k":
+		m.jobsCursor = max(0, m.jobsCursor-1)
+	case "down", "j":
+		m.jobsCursor = max(0, min(len(jobs)-1, m.jobsCursor+1))
+	case "x":
+		if m.jobsCursor < len(jobs) {
+			if j := jobs[m.jobsCursor]; j.running() && j.cancel != nil {
+				j.cancel()
+			}
+		}
+	case "enter":
+		// A command's output opens in its own panel, where it scrolls
+		if m.jobsCursor < len(jobs) {
+			if t := m.tasks.last(jobs[m.jobsCursor].repo); t != nil && t.job == jobs[m.jobsCursor] {
+				m.jobsOpen = false
+				m.showTask(t.repo.Path)
+			}
+		}
+	case "esc", "q", "J":
+		m.jobsOpen = false
+	}
+	return m, nil
+}
+
+// jobStatus says how a job is doing, for the jobs panel, and the theme
+// color it's shown in.
+func jobStatus(j *job, theme Theme) (string, string) {
+	switch {
+	case j.running():
+		return tr("running"), theme.Warning
+	case j.canceled():
+		return tr("canceled"), theme.StatusBar
+	case j.err != nil:
+		return tr("failed"), theme.StatusConflict
+	}
+	return tr("done"), theme.StatusAdded
+}
+
+// renderJobs lists the jobs, newest first, with the output of the one
+// under the cursor below them.
+func (m model) renderJobs() string {
+	boxWidth := m.width - 2
+	innerWidth := boxWidth - 2
+	dim := lipgloss.NewStyle().Foreground(themeColor(m.config.Theme.StatusBar))
+	cursorBg := themeColor(m.config.Theme.CursorBg)
+
+	jobs := m.jobsNewestFirst()
+	listHeight := min(max(1, len(jobs)), max(3, (m.height-6)/2))
+	start := max(0, m.jobsCursor-listHeight+1)
+	var lines []string
+	if len(jobs) == 0 {
+		lines = append(lines, dim.Render(tr("nothing has run yet")))
+	}
+	for i := start; i < len(jobs) && i < start+listHeight; i++ {
+		j := jobs[i]
+		status, color := jobStatus(j, m.config.Theme)
+		right := fmt.Sprintf(" %s %8s", j.started.Format("15:04:05"), j.duration().Round(100*time.Millisecond))
+		left := fmt.Sprintf("%-9s ", status)
+		title := truncateStr(j.title, max(1, innerWidth-lipgloss.Width(left)-lipgloss.Width(right)))
+		pad := strings.Repeat(" ", max(0, innerWidth-lipgloss.Width(left)-lipgloss.Width(title)-lipgloss.Width(right)))
+		st, plain, faint := lipgloss.NewStyle().Foreground(themeColor(color)), lipgloss.NewStyle(), dim
+		if i == m.jobsCursor {
+			st, plain, faint = st.Background(cursorBg), plain.Background(cursorBg), faint.Background(cursorBg)
+		}
+		lines = append(lines, st.Render(left)+plain.Render(title+pad)+faint.Render(right))
+	}
+
+	if m.jobsCursor < len(jobs) {
+		j := jobs[m.jobsCursor]
+		out := strings.TrimRight(j.output, "\n")
+		if j.err != nil && !j.canceled() {
+			out = strings.TrimLeft(out+"\n"+j.err.Error(), "\n")
+		}
+		lines = append(lines, dim.Render(strings.Repeat("─", innerWidth)))
+		if out == "" {
+			out = dim.Render(tr("no output"))
+		}
+		outHeight := max(1, m.height-6-len(lines))
+		wrapped := strings.Split(lipgloss.NewStyle().Width(innerWidth).Render(taskText(out)), "\n")
+		lines = append(lines, wrapped[max(0, len(wrapped)-outHeight):]...)
+	}
+
+	hints := tr("↑↓ pick · x cancel · ↵ open a command's output · esc close")
+	lines = append(lines, dim.Render(truncateStr(hints, innerWidth)))
+	title := trf("Jobs: %d running", m.runningJobs())
+	box := renderBorderedPanel(title, strings.Join(lines, "\n"), boxWidth, len(lines)+2, m.config.Theme.BorderFocused, m.config.Theme.Title)
+	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, box,
+		lipgloss.WithWhitespaceChars(" "),
+		lipgloss.WithWhitespaceForeground(lipgloss.Color("0")))
+}
@@ -0,0 +1,77 @@
+package main
+
+// Layout is a named arrangement of the tree and diff panels.
+type Layout struct {
+	Name     string `yaml:"name"`
+	Position string `yaml:"position"` // right, bottom, or tree (no split: the tree, then the diff alone once opened)
+	Tree     int    `yaml:"tree"`     // percent of the width (right) or height (bottom) given to the tree
+}
+
+// builtinLayouts come first in the p cycle, before layouts from the config.
+var builtinLayouts = []Layout{
+	{Name: "right", Position: "right", Tree: 40},
+	{Name: "bottom", Position: "bottom", Tree: 50},
+}
+
+// allLayouts returns the built-in layouts followed by the configured ones.
+// A configured layout with a built-in name replaces it.
+func (c Config) allLayouts() []Layout {
+	var out []Layout
+	for _, b := range builtinLayouts {
+		if l, ok := findLayout(c.Layouts, b.Name); ok {
+			b = l
+		}
+		out = append(out, b)
+	}
+	for _, l := range c.Layouts {
+		if _, ok := findLayout(builtinLayouts, l.Name); !ok {
+			out = append(out, l)
+		}
+	}
+	return out
+}
+
+func findLayout(layouts []Layout, name string) (Layout, bool) {
+	for _, l := range layouts {
+		if l.Name == name {
+			return l, true
+		}
+	}
+	return Layout{}, false
+}
+
+// layout returns the active layout, picked by diff_position.
+func (c Config) layout() Layout {
+	l, ok := findLayout(c.allLayouts(), c.DiffPosition)
+	if !ok {
+		return builtinLayouts[0]
+	}
+	return l
+}
+
+// normalizeLayouts fixes up configured layouts so the rest of the code can
+// trust them.
+func normalizeLayouts(layouts []Layout) []Layout {
+	var out []Layout
+	for _, l := range layouts {
+		if l.Name == "" {
+			continue
+		}
+		switch l.Position {
+		case "right", "bottom", "tree":
+		default:
+			l.Position = "right"
+		}
+		if l.Tree <= 0 || l.Tree >= 100 {
+			l.Tree = 40
+		}
+		out = append(out, l)
+	}
+	return out
+}
+
+// diffOnly reports whether the diff gets the whole screen: zen mode, a
+// layout without a split, or the accessible setting.
+func (m model) diffOnly() bool {
+	return m.zen || accessible || m.config.layout().Position == "tree"
+}
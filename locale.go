@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// catalogs hold the translations of the UI text, keyed by the English
+// text as it is written in the code. Text missing from a catalog shows in
+// English, so a catalog can be filled in piece by piece.
+var catalogs = map[string]map[string]string{
+	"es": catalogES,
+	"ja": catalogJA,
+}
+
+// catalog is the translation in use; nil shows English.
+var catalog map[string]string
+
+// languages returns the values the language setting takes besides "auto".
+func languages() []string {
+	names := []string{"en"}
+	for name := range catalogs {
+		names = append(names, name)
+	}
+	sort.Strings(names[1:])
+	return names
+}
+
+// setLanguage picks the catalog for the language setting. "auto" follows
+// the locale in $LC_ALL, $LC_MESSAGES or $LANG, like gettext.
+func setLanguage(lang string) {
+	if lang == "auto" {
+		lang = detectLanguage()
+	}
+	catalog = catalogs[lang]
+}
+
+// detectLanguage returns the language of the first locale variable set,
+// "es" for es_AR.UTF-8, or "en" if it names no catalog.
+func detectLanguage() string {
+	for _, v := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		locale := os.Getenv(v)
+		if locale == "" {
+			continue
+		}
+		lang, _, _ := strings.Cut(locale, ".")
+		lang, _, _ = strings.Cut(lang, "_")
+		if slices.Contains(languages(), lang) {
+			return lang
+		}
+		return "en"
+	}
+	return "en"
+}
+
+// tr translates UI text.
+func tr(s string) string {
+	if t, ok := catalog[s]; ok {
+		return t
+	}
+	return s
+}
+
+// trf translates a format string, then formats it.
+func trf(format string, args ...any) string {
+	return fmt.Sprintf(tr(format), args...)
+}
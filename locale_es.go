@@ -0,0 +1,180 @@
+package main
+
+// catalogES is the Spanish translation, see catalogs.
+var catalogES = map[string]string{
+	// Status bar and empty states
+	"%d repo(s) | %d change(s)": "%d repo(s) | %d cambio(s)",
+	"SAFE MODE":                 "MODO SEGURO",
+	"fetching":                  "trayendo",
+	"reviewed %d/%d":            "revisados %d/%d",
+	"every file is reviewed":    "todos los archivos están revisados",
+	"running":                   "en curso",
+	"exit %d":                   "salida %d",
+	"stopped":                   "detenido",
+	"canceled":                  "cancelado",
+	"failed":                    "fallido",
+	"done":                      "hecho",
+	"no output":                 "sin salida",
+	"nothing has run yet":       "todavía no se ha ejecutado nada",
+	"%d job(s)":                 "%d tarea(s)",
+	"watch limit: %d polled":    "límite de vigilancia: %d sondeado(s)",
+	"Out of file watches: %d repo(s) are polled instead. Raise the limit with sudo sysctl fs.inotify.max_user_watches=524288": "Sin vigilancias de archivos: %d repo(s) se sondean en su lugar. Sube el límite con sudo sysctl fs.inotify.max_user_watches=524288",
+	"%d repo(s) got changes":    "%d repo(s) con cambios nuevos",
+	"%d branch(es) fell behind": "%d rama(s) se quedaron atrás",
+	"%s finished":               "%s terminó",
+	"%s failed":                 "%s falló",
+	"after %s":                  "tras %s",
+	"and %d more":               "y %d más",
+	"Jobs: %d running":          "Tareas: %d en curso",
+	"(?) help":                  "(?) ayuda",
+	"No git repositories found.\nRun sidegit in a directory containing git repos.": "No se encontraron repositorios git.\nEjecuta sidegit en un directorio que contenga repos git.",
+	"no matching repos": "ningún repo coincide",
+	"repo name":         "nombre del repo",
+
+	// Panel and menu titles
+	"Files":       "Archivos",
+	"Help":        "Ayuda",
+	"Go to repo":  "Ir a un repo",
+	"Theme":       "Tema",
+	"Show only":   "Mostrar solo",
+	"Commit type": "Tipo de commit",
+	"All repos":   "Todos los repos",
+	"Delete file": "Eliminar archivo",
+	"more":        "más",
+
+	"··· %d unchanged lines ···": "··· %d líneas sin cambios ···",
+
+	"↑↓ pick · ←→ step · ↵ type · r reset · s save to %s · esc cancel": "↑↓ elegir · ←→ ajustar · ↵ escribir · r restablecer · s guardar en %s · esc cancelar",
+	"↑↓ scroll · x stop · esc close (it keeps running)":                "↑↓ desplazar · x detener · esc cerrar (sigue en curso)",
+	"↑↓ scroll · r run again · esc close":                              "↑↓ desplazar · r ejecutar de nuevo · esc cerrar",
+	"↑↓ pick · x cancel · ↵ open a command's output · esc close":       "↑↓ elegir · x cancelar · ↵ abrir la salida de un comando · esc cerrar",
+	"no commands set up; add them under commands in the config":        "no hay comandos; añádelos en commands en la configuración",
+
+	// Help
+	"Show this help":                            "Mostrar esta ayuda",
+	"View diff / actions":                       "Ver diff / acciones",
+	"Close diff":                                "Cerrar diff",
+	"Switch panel":                              "Cambiar de panel",
+	"Move up":                                   "Subir",
+	"Move down":                                 "Bajar",
+	"Collapse/expand":                           "Contraer/expandir",
+	"Open in editor":                            "Abrir en el editor",
+	"Rename file":                               "Renombrar archivo",
+	"Discard changes":                           "Descartar cambios",
+	"Dashboard of every repo":                   "Panel de todos los repos",
+	"Undo last discard/delete":                  "Deshacer el último descarte o borrado",
+	"Switch branch":                             "Cambiar de rama",
+	"Sync (pull/push)":                          "Sincronizar (pull/push)",
+	"Log: commit details / cherry-pick":         "Log: detalles del commit / cherry-pick",
+	"Remotes / upstream":                        "Remotos / upstream",
+	"Open in browser":                           "Abrir en el navegador",
+	"Commit staged changes":                     "Hacer commit de los cambios preparados",
+	"Mark reviewed, go to the next":             "Marcar como revisado e ir al siguiente",
+	"Repo details, or a file's note":            "Detalles del repo, o la nota de un archivo",
+	"Note on a repo or file":                    "Nota en un repo o archivo",
+	"Run a command from the config in the repo": "Ejecutar en el repo un comando de la configuración",
+	"Run a git or shell command in the repo":    "Ejecutar en el repo un comando de git o de la shell",
+	"Shell in the repo, refreshed on exit":      "Shell en el repo, se refresca al salir",
+	"Suspend to the shell":                      "Suspender y volver a la shell",
+	"Resolve conflict":                          "Resolver conflicto",
+	"Conflicts only":                            "Solo conflictos",
+	"Hide untracked":                            "Ocultar no rastreados",
+	"Hide staged":                               "Ocultar preparados",
+	"Show only a status":                        "Mostrar solo un estado",
+	"Next layout":                               "Siguiente disposición",
+	"Full-screen diff":                          "Diff a pantalla completa",
+	"Switch tab":                                "Cambiar de pestaña",
+	"Open / close tab":                          "Abrir / cerrar pestaña",
+	"Follow diff":                               "Seguir el diff",
+	"Ignore whitespace":                         "Ignorar espacios en blanco",
+	"Line numbers":                              "Números de línea",
+	"Fold unchanged lines":                      "Plegar las líneas sin cambios",
+	"Next / previous file":                      "Archivo siguiente / anterior",
+	"Diff context":                              "Contexto del diff",
+	"Repo/dir actions, more of a diff":          "Acciones de repo o directorio, más del diff",
+	"Cycle repo sort (name/frecency/recent)":    "Cambiar el orden de los repos (name/frecency/recent)",
+	"Pick or edit a theme":                      "Elegir o editar un tema",
+	"Message log":                               "Registro de mensajes",
+	"Jobs: what's running, cancel it":           "Tareas: qué se está ejecutando, cancelarlo",
+	"Fetch/pull/push/stash all":                 "Fetch/pull/push/stash de todo",
+	"Refresh":                                   "Actualizar",
+	"Quit":                                      "Salir",
+	"Quit and cd to the repo":                   "Salir e ir (cd) al repo",
+	"Export a markdown report":                  "Exportar un informe en markdown",
+
+	// Menus
+	"Cancel":                             "Cancelar",
+	"Fetch":                              "Traer (fetch)",
+	"Pull":                               "Integrar (pull)",
+	"Pull (fetch & merge)":               "Integrar (fetch y merge)",
+	"Push":                               "Publicar (push)",
+	"Diff all changes":                   "Diff de todos los cambios",
+	"Delete":                             "Eliminar",
+	"Stage all changes":                  "Preparar todos los cambios",
+	"Unstage all":                        "Quitar todo de la preparación",
+	"Stash all changes…":                 "Guardar todos los cambios en el stash…",
+	"Snapshots…":                         "Instantáneas…",
+	"Take a snapshot now":                "Tomar una instantánea ahora",
+	"Clean up…":                          "Limpiar…",
+	"Open a shell here":                  "Abrir una shell aquí",
+	"Open remote in browser":             "Abrir el remoto en el navegador",
+	"Copy path":                          "Copiar ruta",
+	"Copy to clipboard":                  "Copiar al portapapeles",
+	"Save to file…":                      "Guardar en un archivo…",
+	"Refresh this repo":                  "Actualizar este repo",
+	"Hide (add to exclude_repos)":        "Ocultar (añadir a exclude_repos)",
+	"Run a command…":                     "Ejecutar un comando…",
+	"Show the output of the last run":    "Ver la salida de la última ejecución",
+	"Fetch the full history (unshallow)": "Traer el historial completo (unshallow)",
+	"Collapse other directories":         "Contraer los demás directorios",
+	"Discard all changes":                "Descartar todos los cambios",
+	"Write message…":                     "Escribir el mensaje…",
+	"Write in $EDITOR (uses commit.template)": "Escribir en $EDITOR (usa commit.template)",
+	"Edit message…":                           "Editar el mensaje…",
+	"Conventional commit…":                    "Commit convencional…",
+	"Commit anyway":                           "Hacer commit de todos modos",
+	"Commit without hooks (--no-verify)":      "Hacer commit sin hooks (--no-verify)",
+	"Take ours":                               "Quedarse con la nuestra (ours)",
+	"Take theirs":                             "Quedarse con la suya (theirs)",
+	"Mark resolved":                           "Marcar como resuelto",
+	"Open mergetool":                          "Abrir mergetool",
+	"Soft: keep their changes staged":         "Soft: conservar sus cambios preparados",
+	"Mixed: keep their changes, unstaged":     "Mixed: conservar sus cambios sin preparar",
+	"Set upstream…":                           "Definir upstream…",
+	"Add remote… (name url)":                  "Añadir remoto… (nombre url)",
+	"Change URL…":                             "Cambiar la URL…",
+	"Remove remote":                           "Quitar el remoto",
+	"Prune":                                   "Podar (prune)",
+	"Prune stale origin branches…":            "Podar las ramas obsoletas de origin…",
+	"Delete merged local branches…":           "Eliminar las ramas locales ya fusionadas…",
+	"Garbage collect":                         "Recolectar basura",
+	"Garbage collect (git gc)…":               "Recolectar basura (git gc)…",
+	"Create pull request":                     "Crear pull request",
+	"Browse branch":                           "Explorar la rama",
+	"Apply to the worktree":                   "Aplicar al árbol de trabajo",
+	"Run it in the terminal":                  "Ejecutarlo en la terminal",
+	"All statuses":                            "Todos los estados",
+	"Edit colors…":                            "Editar colores…",
+
+	// Accessible mode
+	"%d of %d":     "%d de %d",
+	"group":        "grupo",
+	"directory":    "directorio",
+	"collapsed":    "contraído",
+	"branch %s":    "rama %s",
+	"unavailable":  "no disponible",
+	"warning":      "aviso",
+	"%d change(s)": "%d cambio(s)",
+	"%d ahead":     "%d por delante",
+	"%d behind":    "%d por detrás",
+	"note %s":      "nota %s",
+	"staged":       "preparado",
+	"reviewed":     "revisado",
+	"modified":     "modificado",
+	"added":        "añadido",
+	"deleted":      "eliminado",
+	"renamed":      "renombrado",
+	"copied":       "copiado",
+	"untracked":    "no rastreado",
+	"conflict":     "conflicto",
+}
@@ -0,0 +1,184 @@
+package main
+
+// catalogJA is the Japanese translation, see catalogs.
+var catalogJA = map[string]string{
+	// Status bar and empty states
+	"%d repo(s) | %d change(s)": "リポジトリ %d | 変更 %d",
+	"SAFE MODE":                 "セーフモード",
+	"fetching":                  "フェッチ中",
+	"reviewed %d/%d":            "レビュー済み %d/%d",
+	"every file is reviewed":    "すべてのファイルがレビュー済み",
+	"running":                   "実行中",
+	"exit %d":                   "終了コード %d",
+	"stopped":                   "停止",
+	"canceled":                  "キャンセル済み",
+	"failed":                    "失敗",
+	"done":                      "完了",
+	"no output":                 "出力なし",
+	"nothing has run yet":       "まだ何も実行していません",
+	"%d job(s)":                 "ジョブ %d 件",
+	"watch limit: %d polled":    "監視上限: %d 件をポーリング",
+	"Out of file watches: %d repo(s) are polled instead. Raise the limit with sudo sysctl fs.inotify.max_user_watches=524288": "ファイル監視の上限に達しました: %d 件のリポジトリをポーリングで代用します。sudo sysctl fs.inotify.max_user_watches=524288 で上限を上げてください",
+	"%d repo(s) got changes":    "%d 件のリポジトリに変更",
+	"%d branch(es) fell behind": "%d 件のブランチが遅れています",
+	"%s finished":               "%s が完了",
+	"%s failed":                 "%s が失敗",
+	"after %s":                  "%s 経過",
+	"and %d more":               "ほか %d 件",
+	"Jobs: %d running":          "ジョブ: %d 件実行中",
+	"(?) help":                  "(?) ヘルプ",
+	"No git repositories found.\nRun sidegit in a directory containing git repos.": "git リポジトリが見つかりません。\ngit リポジトリを含むディレクトリで sidegit を実行してください。",
+	"no matching repos": "一致するリポジトリがありません",
+	"repo name":         "リポジトリ名",
+
+	// Panel and menu titles
+	"Files":       "ファイル",
+	"Diff":        "差分",
+	"Help":        "ヘルプ",
+	"Go to repo":  "リポジトリへ移動",
+	"Theme":       "テーマ",
+	"Show only":   "表示する状態",
+	"Commit type": "コミットの種類",
+	"All repos":   "すべてのリポジトリ",
+	"Delete file": "ファイルを削除",
+	"more":        "続き",
+
+	"··· %d unchanged lines ···": "··· 変更のない %d 行 ···",
+
+	"↑↓ pick · ←→ step · ↵ type · r reset · s save to %s · esc cancel": "↑↓ 選択 · ←→ 調整 · ↵ 入力 · r 元に戻す · s %s に保存 · esc キャンセル",
+	"↑↓ scroll · x stop · esc close (it keeps running)":                "↑↓ スクロール · x 停止 · esc 閉じる（実行は続く）",
+	"↑↓ scroll · r run again · esc close":                              "↑↓ スクロール · r もう一度実行 · esc 閉じる",
+	"↑↓ pick · x cancel · ↵ open a command's output · esc close":       "↑↓ 選択 · x キャンセル · ↵ コマンドの出力を開く · esc 閉じる",
+	"no commands set up; add them under commands in the config":        "コマンドが未設定です。設定の commands に追加してください",
+
+	// Help
+	"Show this help":                            "このヘルプを表示",
+	"View diff / actions":                       "差分を表示 / 操作",
+	"Close diff":                                "差分を閉じる",
+	"Switch panel":                              "パネルを切り替え",
+	"Move up":                                   "上へ移動",
+	"Move down":                                 "下へ移動",
+	"Collapse/expand":                           "折りたたみ/展開",
+	"Open in editor":                            "エディタで開く",
+	"Rename file":                               "ファイル名を変更",
+	"Discard changes":                           "変更を破棄",
+	"Dashboard of every repo":                   "全リポジトリのダッシュボード",
+	"Undo last discard/delete":                  "直前の破棄/削除を元に戻す",
+	"Switch branch":                             "ブランチを切り替え",
+	"Sync (pull/push)":                          "同期（プル/プッシュ）",
+	"Log: commit details / cherry-pick":         "ログ: コミットの詳細 / チェリーピック",
+	"Remotes / upstream":                        "リモート / upstream",
+	"Open in browser":                           "ブラウザで開く",
+	"Commit staged changes":                     "ステージした変更をコミット",
+	"Mark reviewed, go to the next":             "レビュー済みにして次へ",
+	"Repo details, or a file's note":            "リポジトリの詳細、またはファイルのメモ",
+	"Note on a repo or file":                    "リポジトリやファイルにメモ",
+	"Run a command from the config in the repo": "設定のコマンドをリポジトリで実行",
+	"Run a git or shell command in the repo":    "git やシェルのコマンドをリポジトリで実行",
+	"Shell in the repo, refreshed on exit":      "リポジトリでシェルを開き、終了時に更新",
+	"Suspend to the shell":                      "中断してシェルに戻る",
+	"Resolve conflict":                          "コンフリクトを解決",
+	"Conflicts only":                            "コンフリクトのみ",
+	"Hide untracked":                            "未追跡を隠す",
+	"Hide staged":                               "ステージ済みを隠す",
+	"Show only a status":                        "特定の状態のみ表示",
+	"Next layout":                               "次のレイアウト",
+	"Full-screen diff":                          "差分を全画面表示",
+	"Switch tab":                                "タブを切り替え",
+	"Open / close tab":                          "タブを開く / 閉じる",
+	"Follow diff":                               "差分を追従",
+	"Ignore whitespace":                         "空白を無視",
+	"Line numbers":                              "行番号",
+	"Fold unchanged lines":                      "変更のない行を折りたたむ",
+	"Next / previous file":                      "次 / 前のファイル",
+	"Diff context":                              "差分の前後の行数",
+	"Repo/dir actions, more of a diff":          "リポジトリ/ディレクトリの操作、差分の続き",
+	"Cycle repo sort (name/frecency/recent)":    "リポジトリの並び順を切り替え（name/frecency/recent）",
+	"Pick or edit a theme":                      "テーマを選択・編集",
+	"Message log":                               "メッセージ履歴",
+	"Jobs: what's running, cancel it":           "ジョブ: 実行中のものを確認・キャンセル",
+	"Fetch/pull/push/stash all":                 "すべてをフェッチ/プル/プッシュ/スタッシュ",
+	"Refresh":                                   "更新",
+	"Quit":                                      "終了",
+	"Quit and cd to the repo":                   "終了してリポジトリへ cd",
+	"Export a markdown report":                  "Markdown レポートを書き出す",
+
+	// Menus
+	"Cancel":                             "キャンセル",
+	"Fetch":                              "フェッチ",
+	"Pull":                               "プル",
+	"Pull (fetch & merge)":               "プル（フェッチしてマージ）",
+	"Push":                               "プッシュ",
+	"Diff all changes":                   "すべての変更の差分",
+	"Delete":                             "削除",
+	"Stage all changes":                  "すべての変更をステージ",
+	"Unstage all":                        "すべてアンステージ",
+	"Stash all changes…":                 "すべての変更をスタッシュ…",
+	"Snapshots…":                         "スナップショット…",
+	"Take a snapshot now":                "今すぐスナップショットを取る",
+	"Clean up…":                          "クリーンアップ…",
+	"Open a shell here":                  "ここでシェルを開く",
+	"Open remote in browser":             "リモートをブラウザで開く",
+	"Copy path":                          "パスをコピー",
+	"Copy to clipboard":                  "クリップボードにコピー",
+	"Save to file…":                      "ファイルに保存…",
+	"Refresh this repo":                  "このリポジトリを更新",
+	"Hide (add to exclude_repos)":        "非表示にする（exclude_repos に追加）",
+	"Run a command…":                     "コマンドを実行…",
+	"Show the output of the last run":    "前回の実行の出力を表示",
+	"Fetch the full history (unshallow)": "全履歴をフェッチ（unshallow）",
+	"Collapse other directories":         "他のディレクトリを折りたたむ",
+	"Discard all changes":                "すべての変更を破棄",
+	"Write message…":                     "メッセージを書く…",
+	"Write in $EDITOR (uses commit.template)": "$EDITOR で書く（commit.template を使用）",
+	"Edit message…":                           "メッセージを編集…",
+	"Conventional commit…":                    "Conventional Commits 形式でコミット…",
+	"Commit anyway":                           "このままコミット",
+	"Commit without hooks (--no-verify)":      "フックなしでコミット（--no-verify）",
+	"Take ours":                               "自分側（ours）を採用",
+	"Take theirs":                             "相手側（theirs）を採用",
+	"Mark resolved":                           "解決済みにする",
+	"Open mergetool":                          "mergetool を開く",
+	"Soft: keep their changes staged":         "Soft: 変更をステージしたまま残す",
+	"Mixed: keep their changes, unstaged":     "Mixed: 変更をアンステージして残す",
+	"Set upstream…":                           "upstream を設定…",
+	"Add remote… (name url)":                  "リモートを追加…（名前 URL）",
+	"Change URL…":                             "URL を変更…",
+	"Remove remote":                           "リモートを削除",
+	"Prune":                                   "プルーン",
+	"Prune stale origin branches…":            "origin の古いブランチを整理…",
+	"Delete merged local branches…":           "マージ済みのローカルブランチを削除…",
+	"Garbage collect":                         "ガベージコレクション",
+	"Garbage collect (git gc)…":               "ガベージコレクション（git gc）…",
+	"Create pull request":                     "プルリクエストを作成",
+	"Browse branch":                           "ブランチを表示",
+	"Apply to the worktree":                   "作業ツリーに適用",
+	"Run it in the terminal":                  "ターミナルで実行",
+	"All statuses":                            "すべての状態",
+	"Edit colors…":                            "色を編集…",
+
+	// Accessible mode
+	"cursor":       "カーソル",
+	"%d of %d":     "%d / %d",
+	"group":        "グループ",
+	"repo":         "リポジトリ",
+	"directory":    "ディレクトリ",
+	"collapsed":    "折りたたみ",
+	"branch %s":    "ブランチ %s",
+	"unavailable":  "利用不可",
+	"warning":      "警告",
+	"%d repo(s)":   "リポジトリ %d",
+	"%d change(s)": "変更 %d",
+	"%d ahead":     "%d 件先行",
+	"%d behind":    "%d 件遅れ",
+	"note %s":      "メモ %s",
+	"staged":       "ステージ済み",
+	"reviewed":     "レビュー済み",
+	"modified":     "変更",
+	"added":        "追加",
+	"deleted":      "削除",
+	"renamed":      "名前変更",
+	"copied":       "コピー",
+	"untracked":    "未追跡",
+	"conflict":     "コンフリクト",
+}
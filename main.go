@@ -1,26 +1,463 @@
 package main
 
 import (
+	"flag"
 	"fmt"
+	"log"
 	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/hermanschutte/sidegit/pkg/sidegit"
 )
 
 func main() {
-	root, err := os.Getwd()
+	// Every git call names its repo with -C; a GIT_DIR or GIT_WORK_TREE
+	// left in the environment (by a dotfiles alias, say) would point all
+	// of them at that one repo instead. The repo they name is resolved
+	// here and shown as one of the bare_repos, whose git calls name it
+	// explicitly.
+	envBare := gitEnvRepo()
+	os.Unsetenv("GIT_DIR")
+	os.Unsetenv("GIT_WORK_TREE")
+
+	args := os.Args[1:]
+	var command string
+	if len(args) > 0 {
+		switch args[0] {
+		case "export-settings", "import-settings":
+			runSettingsCommand(args[0], args[1:])
+			return
+		case "config":
+			runConfigCommand(args[1:])
+			return
+		case "status":
+			args = append([]string{"--once"}, args[1:]...)
+		case "daemon":
+			args = append([]string{"--daemon"}, args[1:]...)
+		case "completion", listReposArg:
+			// Both need the flags below
+			command, args = args[0], args[1:]
+		}
+	}
+
+	safeMode := flag.Bool("safe-mode", false, "start with default config, no file watcher and no background network access")
+	o := Overrides{Depth: -1}
+	flag.StringVar(&o.ConfigFile, "config", "", "read config from `file` instead of ~/.config/sidegit/config.yaml")
+	flag.Func("depth", "scan `N` directory levels below the root's children", func(v string) error {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return fmt.Errorf("want a number >= 0")
+		}
+		o.Depth = n
+		return nil
+	})
+	flag.StringVar(&o.Layout, "layout", "", "layout `name`: right, bottom, or one from layouts in the config")
+	flag.StringVar(&o.Theme, "theme", "", "theme `name`: "+builtinThemeNames()+", or a file in ~/.config/sidegit/themes")
+	flag.BoolVar(&o.NoWatch, "no-watch", false, "don't watch files for changes, rely on polling")
+	flag.BoolVar(&o.Accessible, "accessible", false, "plain text for screen readers: no box drawing, states in words, one panel at a time")
+	flag.BoolVar(&o.Inline, "no-altscreen", false, "draw below the prompt, keeping the scrollback, instead of taking the whole screen")
+	flag.StringVar(&o.Height, "height", "", "rows to use below the prompt, a `count` or a percentage like 40%; implies --no-altscreen")
+	flag.BoolVar(&o.NoMotion, "reduced-motion", false, "don't blink the text cursor")
+	jsonOut := flag.Bool("json", false, "print repo status as JSON and exit")
+	markdown := flag.Bool("markdown", false, "print repo status as a markdown report and exit")
+	once := flag.Bool("once", false, "print a colored summary of every repo and exit")
+	tmuxSegment := flag.Bool("tmux-segment", false, "print a short count of dirty and unpushed repos for a tmux status line and exit")
+	promptSegment := flag.Bool("prompt-segment", false, "like --tmux-segment, with ANSI colors for shell prompts such as starship")
+	debugFile := flag.String("debug", "", "log scans, git timings, watcher events and UI messages to `file`")
+	cpuProfile := flag.String("cpuprofile", "", "write a CPU profile to `file`")
+	memProfile := flag.String("memprofile", "", "write a heap profile to `file` on exit")
+	cdFile := flag.String("cd-file", "", "on Q, write the selected repo's path to `file` instead of printing it")
+	daemonMode := flag.Bool("daemon", false, "keep scanning and watching in the background and serve repo state to other sidegit instances")
+	var repoNames []string
+	flag.Func("repo", "limit status, --json, --markdown and the segments to the repo `name`; repeat for more", func(v string) error {
+		repoNames = append(repoNames, v)
+		return nil
+	})
+	flag.Usage = printUsage
+	if command == "completion" {
+		runCompletionCommand(args)
+		return
+	}
+	flag.CommandLine.Parse(args)
+
+	root, err := scanRoot(flag.Arg(0))
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
+	// Any further paths open in tabs of their own
+	var tabRoots []string
+	for _, arg := range flag.Args()[min(1, flag.NArg()):] {
+		r, err := scanRoot(arg)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		tabRoots = append(tabRoots, r)
+	}
+
+	var cfg Config
+	var firstRun bool
+	var configErrs []error // problems to show once the UI is up
+	var state *State
+	if *safeMode {
+		// Touch nothing on disk: a broken config or state file is the
+		// usual reason to reach for safe mode
+		cfg = DefaultConfig()
+		cfg.SafeMode = true
+		state = &State{Repos: map[string]*RepoState{}}
+	} else {
+		if o.ConfigFile != "" {
+			if cfg, err = LoadConfigFile(o.ConfigFile); err != nil && !isConfigProblems(err) {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+		} else {
+			cfg, firstRun, err = LoadConfig()
+		}
+		if err != nil {
+			configErrs = append(configErrs, err)
+		}
+		if cfg, err = LoadProjectConfig(cfg, root); err != nil {
+			configErrs = append(configErrs, err)
+		}
+		for _, err := range configErrs {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+		state = LoadState()
+	}
+	if err := o.Apply(&cfg); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+	if envBare != nil {
+		cfg.BareRepos = append(cfg.BareRepos, *envBare)
+	}
+	if *debugFile != "" {
+		f, err := os.OpenFile(*debugFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		debugLog = log.New(f, "", log.Ltime|log.Lmicroseconds)
+	}
+	backend.set(cfg)
+	sidegit.Backend = &backend
+	if *cpuProfile != "" {
+		f, err := os.Create(*cpuProfile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		if err := pprof.StartCPUProfile(f); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		defer pprof.StopCPUProfile()
+	}
+	if *memProfile != "" {
+		defer writeHeapProfile(*memProfile)
+	}
+
+	if command == listReposArg {
+		repos, err := sidegit.FindRepos(root, cfg.serviceOptions(root).Scan)
+		if err != nil {
+			os.Exit(1)
+		}
+		for _, r := range repos {
+			fmt.Println(r.RelPath)
+		}
+		return
+	}
+
+	opts := cfg.serviceOptions(root)
+	opts.Logger = debugLog
+	service := sidegit.NewService(root, opts)
+
+	if *daemonMode {
+		service.Start()
+		defer service.Stop()
+		if err := RunDaemon(service, root); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
 
-	cfg := LoadConfig()
-	m := initialModel(cfg, root)
+	// A running daemon has every repo scanned already; use it if there is one
+	var client *Client
+	if !*safeMode {
+		client, _ = DialDaemon(root)
+	}
+	snapshot := service.ScanOnce
+	if client != nil {
+		snapshot = client.Snapshot
+	}
+	if len(repoNames) > 0 {
+		all := snapshot
+		snapshot = func() []sidegit.Repo {
+			repos, err := pickRepos(all(), repoNames)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			return repos
+		}
+	}
 
-	p := tea.NewProgram(m, tea.WithAltScreen())
+	if *jsonOut {
+		repos := visibleRepos(snapshot(), cfg)
+		if err := writeJSON(os.Stdout, repos); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
 
-	if _, err := p.Run(); err != nil {
+	if *markdown {
+		repos := visibleRepos(snapshot(), cfg)
+		if err := writeMarkdown(os.Stdout, root, repos, time.Now()); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *tmuxSegment || *promptSegment {
+		// Status lines run this every few seconds, so don't ask the
+		// terminal for its background: only an explicit light one counts
+		repos := visibleRepos(snapshot(), cfg)
+		if err := writeSegment(os.Stdout, repos, cfg.Theme, cfg.Background == "light", *tmuxSegment); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	var screen []tea.ProgramOption
+	if !*once {
+		if tty := ttyOutput(); tty != nil {
+			defer tty.Close()
+			lipgloss.SetDefaultRenderer(lipgloss.NewRenderer(tty))
+			screen = append(screen, tea.WithOutput(tty))
+		}
+	}
+	applyBackground(cfg.Background)
+	setLanguage(cfg.Language)
+	accessible = cfg.Accessible
+	if *once {
+		repos := visibleRepos(snapshot(), cfg)
+		if err := writeReport(os.Stdout, repos, cfg.Theme); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	var engine Engine = service
+	if client != nil {
+		engine = client
+	} else {
+		service.Start()
+	}
+	m := initialModel(cfg, state, engine, root, firstRun)
+	m.configErrs = configErrs
+	for _, r := range tabRoots {
+		tc := tabConfig(cfg, r)
+		m.tabs = append(m.tabs, workspace{root: r, service: startEngine(tc, r), config: tc})
+	}
+
+	if !cfg.Inline {
+		screen = append(screen, tea.WithAltScreen())
+	}
+	p := tea.NewProgram(m, screen...)
+
+	final, err := p.Run()
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error running sidegit: %v\n", err)
 		os.Exit(1)
 	}
+	final.(model).stopTabs()
+	if dir := final.(model).cdPath; dir != "" {
+		if *cdFile != "" {
+			if err := os.WriteFile(*cdFile, []byte(dir+"\n"), 0o644); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			}
+		} else {
+			fmt.Println(dir)
+		}
+	}
+
+	if !*safeMode {
+		_ = state.Save()
+	}
+}
+
+// ttyOutput opens the terminal to draw on when stdout isn't one, as in
+// cd "$(sidegit)" where stdout takes the path Q prints. It returns nil
+// when stdout is the terminal, or there's none to open.
+func ttyOutput() *os.File {
+	if info, err := os.Stdout.Stat(); err != nil || info.Mode()&os.ModeCharDevice != 0 {
+		return nil
+	}
+	tty, err := os.OpenFile("/dev/tty", os.O_WRONLY, 0)
+	if err != nil {
+		return nil
+	}
+	return tty
+}
+
+// debugLog is set by --debug; nil otherwise.
+var debugLog *log.Logger
+
+// gitEnvRepo resolves the repo GIT_DIR names, with its work tree from
+// GIT_WORK_TREE, core.worktree or the current directory the way git would,
+// or returns nil without one.
+func gitEnvRepo() *BareRepoConfig {
+	if os.Getenv("GIT_DIR") == "" {
+		return nil
+	}
+	out, err := exec.Command("git", "rev-parse", "--absolute-git-dir", "--show-toplevel").Output()
+	if err != nil {
+		return nil
+	}
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	if len(lines) != 2 {
+		return nil // a bare repo without a work tree has no status
+	}
+	return &BareRepoConfig{GitDir: lines[0], WorkTree: lines[1], Name: filepath.Base(lines[1])}
+}
+
+// backend is sidegit.Backend. It is rebuilt from the config whenever the
+// config changes, while scans may be using it.
+var backend liveBackend
+
+type liveBackend struct{ v atomic.Value } // a gitBackendBox
+
+type gitBackendBox struct{ sidegit.GitBackend }
+
+// set switches to a backend for cfg's git_timeout and untracked_files.
+func (b *liveBackend) set(cfg Config) {
+	var g sidegit.GitBackend = sidegit.ExecBackend{Timeout: time.Duration(cfg.GitTimeout) * time.Second, Untracked: cfg.UntrackedFiles}
+	if debugLog != nil {
+		g = sidegit.LogBackend(g, debugLog)
+	}
+	b.v.Store(gitBackendBox{g})
+}
+
+func (b *liveBackend) get() sidegit.GitBackend {
+	return b.v.Load().(gitBackendBox).GitBackend
+}
+
+func (b *liveBackend) Status(repoPath string) (sidegit.GitStatus, error) {
+	return b.get().Status(repoPath)
+}
+
+func (b *liveBackend) Diff(repoPath, filePath string, opts sidegit.DiffOptions) (sidegit.Diff, error) {
+	return b.get().Diff(repoPath, filePath, opts)
+}
+
+func (b *liveBackend) RepoDiff(repoPath string, opts sidegit.DiffOptions) (sidegit.Diff, error) {
+	return b.get().RepoDiff(repoPath, opts)
+}
+
+func (b *liveBackend) Query(repoPath string, args ...string) (string, error) {
+	return b.get().Query(repoPath, args...)
+}
+
+func writeHeapProfile(path string) {
+	f, err := os.Create(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return
+	}
+	defer f.Close()
+	runtime.GC() // up-to-date statistics
+	if err := pprof.WriteHeapProfile(f); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	}
+}
+
+// pickRepos returns the repos named in names, by display name or folder
+// name, in scan order.
+func pickRepos(repos []sidegit.Repo, names []string) ([]sidegit.Repo, error) {
+	var picked []sidegit.Repo
+	found := map[string]bool{}
+	for _, r := range repos {
+		for _, name := range names {
+			if name == r.RelPath || name == filepath.Base(r.Path) {
+				picked = append(picked, r)
+				found[name] = true
+				break
+			}
+		}
+	}
+	for _, name := range names {
+		if !found[name] {
+			return nil, fmt.Errorf("no repo named %q", name)
+		}
+	}
+	return picked, nil
+}
+
+// scanRoot resolves the workspace to scan: path if given, otherwise the
+// working directory.
+func scanRoot(path string) (string, error) {
+	if path == "" {
+		return os.Getwd()
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	info, err := os.Stat(abs)
+	if err != nil {
+		return "", err
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("%s is not a directory", path)
+	}
+	return abs, nil
+}
+
+func runSettingsCommand(name string, args []string) {
+	var files []string
+	var err error
+	if name == "export-settings" {
+		dest := "sidegit-settings.tar.gz"
+		if len(args) > 0 {
+			dest = args[0]
+		}
+		files, err = ExportSettings(dest)
+		if err == nil {
+			fmt.Printf("Exported %d file(s) to %s\n", len(files), dest)
+		}
+	} else {
+		if len(args) == 0 {
+			fmt.Fprintln(os.Stderr, "usage: sidegit import-settings <archive>")
+			os.Exit(2)
+		}
+		files, err = ImportSettings(args[0])
+		if err == nil {
+			fmt.Printf("Imported %d file(s) into %s\n", len(files), configDir())
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	for _, f := range files {
+		fmt.Println("  " + f)
+	}
 }
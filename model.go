@@ -1,16 +1,25 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/hermanschutte/sidegit/pkg/sidegit"
 )
 
 type panel int
@@ -22,103 +31,722 @@ const (
 
 // Messages
 type reposScannedMsg struct {
-	repos []Repo
+	repos []sidegit.Repo
+	from  Engine
 }
 
 type diffLoadedMsg struct {
-	content string
-	file    string
+	content   string
+	files     []sidegit.FileDiff // content parsed, nil with a diff_pager
+	truncated bool
+	size      int64 // bytes on disk, 0 when unknown
+	repo      string
+	file      string
 }
 
-type fileChangedMsg struct{}
-type pollTickMsg time.Time
-type gitErrorMsg struct{ err error }
+// repoDiffMsg opens the diff of every change in a repo.
+type repoDiffMsg struct{ repo string }
+
+// diffReloadedMsg carries a follow-mode reload of the open diff.
+type diffReloadedMsg struct {
+	content   string
+	files     []sidegit.FileDiff
+	truncated bool
+	repo      string
+	file      string
+}
+
+// openMenuMsg lets a menu action open a follow-up menu.
+type openMenuMsg struct {
+	title   string
+	options []menuOption
+	preview string // shown in a scrollable box under the options, e.g. a diff
+	cursor  int    // option selected at first, for menus that reopen themselves
+	// previewTitle titles the preview box, "Preview" when empty
+	previewTitle string
+}
+
+// fileChangedMsg reports that repo changed on disk; an empty repo means
+// anything may have changed.
+type fileChangedMsg struct {
+	repo string
+	note string // notification to show, e.g. "pushed api"
+}
+type gitErrorMsg struct {
+	repo    string
+	err     error
+	changed bool // the operation may have changed the repo before failing, so it's refreshed
+}
+
+// fileRenamedMsg reports a rename from the tree, so the cursor can follow
+// the file to its new path once the rescan lands.
+type fileRenamedMsg struct{ repo, from, to string }
+
+// backedUpMsg reports a discard or delete that went through after its
+// file was backed up.
+type backedUpMsg struct {
+	backup *backup // nil when there was nothing on disk to save
+	repo   string
+}
+
+// prTickMsg asks for another round of pull request checks.
+type prTickMsg struct{}
+
+// prStatusMsg carries the open pull requests found, by repo path.
+type prStatusMsg struct {
+	prs map[string]*sidegit.PullRequest
+}
+
+// configChangedMsg reports that config.yaml was saved.
+type configChangedMsg struct {
+	from Engine
+}
+
+// setOnlyStatusMsg limits the tree to one status; an empty name shows all.
+type setOnlyStatusMsg struct{ name string }
+
+// setThemeMsg switches to a built-in theme for this session.
+type setThemeMsg struct{ name string }
+
+// configProblemsMsg carries the config problems found at startup to the
+// status bar.
+type configProblemsMsg struct{ errs []error }
+
+// repoExcludedMsg reports that pattern was added to exclude_repos in the
+// config file, so the tree can drop the repo before the reload lands.
+type repoExcludedMsg struct{ pattern string }
 
 type menuOption struct {
 	key    string         // shortcut key displayed (e.g. "x", "u"), empty for Cancel
 	label  string         // display text
+	color  string         // label color, empty for the default
 	action func() tea.Cmd // nil means cancel/close
 }
 
 // Model
 type model struct {
-	repos        []Repo
+	repos        []sidegit.Repo
 	tree         TreeModel
 	diffOpen     bool
 	diffContent  string
-	diffFile     string
+	diffFile     string // "" for a repo diff
+	diffRepo     string
+	diffPages    int  // diff_max_lines pages loaded, raised by "m"
+	diffCut      bool // the diff was cut off at the page limit
+	diffSize     int64
+	diffFiles    []sidegit.FileDiff
+	diffLines    []diffLine   // diffFiles line by line, nil with a diff_pager
+	diffRows     []int        // the line of diffLines on each row of the panel
+	diffUnfolded map[int]bool // folds opened with ↵, by their first line
+	bracket      string       // "[" or "]" pressed in a repo diff, waiting for "f"
+	followDiff   bool
+	zen          bool // full-screen diff, toggled with f
 	diffViewport viewport.Model
 	config       Config
 	width        int
-	height       int
+	height       int // rows drawn in, see Config.viewHeight
+	rows         int // the terminal's rows
 	focused      panel
 	ready        bool
 	scanRoot     string
+	service      Engine
 
 	menuOpen         bool
 	menuTitle        string
 	menuOptions      []menuOption
 	menuCursor       int
 	menuScrollOffset int
+	menuHasPreview   bool
+	menuPreview      viewport.Model
+	menuPreviewTitle string
 
 	helpOpen  bool
 	statusMsg string
+	statusErr string // the error in statusMsg, kept across keypresses
+	statusSeq int    // bumped by every notification, see clearStatusMsg
+
+	notices        []notice // the message log, oldest first
+	messagesOpen   bool
+	messagesOffset int
+
+	configErrs []error // config problems found at startup, shown once the UI is up
+
+	themeEditOpen bool
+	themeEdit     themeEditor
+
+	infoOpen  bool
+	infoTitle string
+	infoRows  [][2]string
+
+	// The progress overlay of a bulk operation (A)
+	bulkOpen  bool
+	bulkTitle string
+	bulkRows  []bulkRow
+	bulkID    int
+	bulkJob   *job
+
+	// The repo dashboard (I)
+	dashOpen    bool
+	dashCursor  int
+	dashSort    int // index into dashSorts
+	dashReverse bool
+
+	// The commit detail view, opened from the log
+	commitOpen bool
+	commit     commitView
+
+	// The snapshots panel, opened from a repo's menu
+	snapOpen bool
+	snap     snapshotView
+
+	// Commands from the config run in repos (!), and their output panel
+	tasks    taskRuns
+	taskOpen bool
+	taskRepo string // the repo whose last run the panel shows
+	taskView viewport.Model
+
+	// Background operations, and the jobs panel (J)
+	jobs       []*job // oldest first
+	jobID      int
+	jobsOpen   bool
+	jobsCursor int
+	scanJob    int // the scan asked for with r, 0 for none
+	fetchJob   int // the engine's background fetch, 0 while there's none
+	// fetchStop detaches the fetch job's cancel from the engine, reporting
+	// false if the job was canceled
+	fetchStop func() bool
+
+	bell bool // the frame rings the bell, see bellMsg
+
+	// The dirty repos listed when quitting with confirm_quit_when_dirty
+	quitOpen  bool
+	quitRepos []sidegit.Repo
+	quitting  bool
+	cdPath    string // the repo to cd to after quitting with Q
+
+	tourOpen bool
+	tourStep int
+
+	state       *State
+	visitedRepo string // repo path the cursor was last on, for frecency
+
+	// selectRepo/selectFile is a file to put the cursor on once it shows
+	// up in a scan, e.g. after a rename
+	selectRepo string
+	selectFile string
+
+	lastBackup *backup // most recent discard or delete, for U
+
+	prs map[string]*sidegit.PullRequest // open PRs by repo path, with pr_status on
+
+	autoCommits map[string]autoCommitState // auto_commit repos with changes, by path
+
+	switcherOpen   bool
+	switcherInput  textinput.Model
+	switcherCursor int
+
+	promptOpen  bool
+	prompt      openPromptMsg
+	promptInput textinput.Model
+	promptErr   string // why the last submit was refused
+	promptHist  int    // position in the prompt's history; its length when not browsing
+
+	// Tabs, one workspace each; the active one is also in the fields above
+	tabs      []workspace
+	activeTab int
 }
 
-func initialModel(cfg Config, root string) model {
+func initialModel(cfg Config, state *State, service Engine, root string, firstRun bool) model {
 	return model{
 		config:   cfg,
+		state:    state,
+		service:  service,
 		scanRoot: root,
+		tourOpen: firstRun,
+		tabs:     []workspace{{root: root, service: service, config: cfg}},
+		tasks:    taskRuns{},
 	}
 }
 
 func (m model) Init() tea.Cmd {
-	cmds := []tea.Cmd{scanReposCmd(m.scanRoot)}
-	if m.config.PollInterval > 0 {
-		cmds = append(cmds, pollTickCmd(m.config.PollInterval))
+	cmds := []tea.Cmd{prTickCmd(time.Second), snapshotTickCmd(time.Duration(m.config.SnapshotInterval) * time.Second)}
+	for _, w := range m.tabs {
+		w.service.Refresh()
+		cmds = append(cmds, waitForReposCmd(w.service), waitForConfigCmd(w.service))
+	}
+	if len(m.configErrs) > 0 {
+		errs := m.configErrs
+		cmds = append(cmds, func() tea.Msg { return configProblemsMsg{errs: errs} })
 	}
 	return tea.Batch(cmds...)
 }
 
+// refresh asks the service to rescan repo, or everything if repo is empty.
+func (m model) refresh(repo string) {
+	if repo == "" {
+		m.service.Refresh()
+	} else {
+		m.service.RefreshRepo(repo)
+	}
+}
+
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if debugLog != nil {
+		if key, ok := msg.(tea.KeyMsg); ok {
+			debugLog.Printf("update: key %q", key.String())
+		} else {
+			debugLog.Printf("update: %T", msg)
+		}
+	}
+
 	switch msg := msg.(type) {
 
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
-		m.height = msg.Height
+		m.rows = msg.Height
+		m.height = m.config.viewHeight(msg.Height)
 		m.ready = true
 		m.diffViewport = viewport.New(m.diffWidth(), m.diffHeight())
-		m.diffViewport.SetContent(m.diffContent)
+		m.setDiffContent()
+		m.commit.vp.Width = m.width - 4
+		m.commit.vp.Height = m.commitViewHeight()
+		m.snap.vp.Width = max(10, m.width-4-snapshotListWidth-1)
+		m.snap.vp.Height = m.commitViewHeight()
+		if m.taskOpen {
+			m.taskView.Width, m.taskView.Height = m.width-4, m.taskViewHeight()
+			m.setTaskContent()
+		}
 		return m, nil
 
 	case reposScannedMsg:
-		m.repos = msg.repos
-		m.tree = NewTreeModel(m.repos, m.config.Theme)
+		if i := m.tabOf(msg.from); i != m.activeTab {
+			if i < 0 {
+				return m, nil // the tab was closed
+			}
+			repos := visibleRepos(msg.repos, m.tabs[i].config)
+			// Alerts compare what's shown, so a hidden repo never sets one off
+			notes := tea.Batch(m.fetchErrorNotices(m.tabs[i].repos, msg.repos), m.watchLimitNotice(m.tabs[i].repos, msg.repos), m.repoAlerts(m.tabs[i].repos, repos))
+			m.tabs[i].repos = repos
+			return m, tea.Batch(notes, m.checkAutoCommits(msg.repos), m.checkReviews(msg.repos), waitForReposCmd(msg.from))
+		}
+		repos := visibleRepos(msg.repos, m.config)
+		notes := tea.Batch(m.fetchErrorNotices(m.repos, msg.repos), m.watchLimitNotice(m.repos, msg.repos), m.repoAlerts(m.repos, repos), m.checkAutoCommits(msg.repos), m.checkReviews(msg.repos))
+		if m.followChanged(repos) {
+			notes = tea.Batch(notes, reloadDiffCmd(m.diffRepo, m.diffFile, m.diffOptions(), m.diffPager()))
+		}
+		m.repos = repos
+		m.applyAccents()
+		m.applyPRs()
+		m.sortRepos()
+		m.rebuildTree()
+		notes = tea.Batch(notes, m.trackJobs())
+		if m.selectFile != "" && m.tree.SelectFile(m.selectRepo, m.selectFile) {
+			m.selectRepo, m.selectFile = "", ""
+		}
+		return m, tea.Batch(notes, waitForReposCmd(msg.from))
+
+	case bellMsg:
+		m.bell = true
+		return m, tea.Tick(bellTime, func(time.Time) tea.Msg { return bellDoneMsg{} })
+
+	case bellDoneMsg:
+		m.bell = false
+		return m, nil
+
+	case noteSetMsg:
+		m.state.SetNote(msg.repo, msg.file, msg.note)
+		// Notes are typed by hand, so they're saved right away rather
+		// than on quit
+		if !m.config.SafeMode {
+			if err := m.state.Save(); err != nil {
+				return m, m.notifyError("note: " + err.Error())
+			}
+		}
 		return m, nil
 
+	case reviewsCheckedMsg:
+		for _, mk := range msg.stale {
+			// Unless it was marked again while the hashes were checked
+			if rs, ok := m.state.Repos[mk.repo]; ok && rs.Reviewed[mk.file] == mk.hash {
+				m.state.UnmarkReviewed(mk.repo, mk.file)
+			}
+		}
+		return m, nil
+
+	case tabOpenedMsg:
+		return m, m.addTab(msg.root, msg.engine, msg.config)
+
+	case engineLostMsg:
+		return m, m.replaceEngine(msg.from)
+
 	case diffLoadedMsg:
 		m.diffContent = msg.content
+		m.diffFiles = msg.files
+		m.diffCut = msg.truncated
+		m.diffSize = msg.size
 		m.diffFile = msg.file
+		m.diffRepo = msg.repo
 		m.diffOpen = true
+		m.diffUnfolded = nil
+		if m.diffOnly() {
+			m.focused = panelDiff // the tree isn't on screen
+		}
 		m.diffViewport = viewport.New(m.diffWidth(), m.diffHeight())
-		m.diffViewport.SetContent(m.diffContent)
+		m.setDiffContent()
+		return m, nil
+
+	case repoDiffMsg:
+		m.diffPages = 1
+		return m, loadDiffCmd(msg.repo, "", m.diffOptions(), m.diffPager())
+
+	case diffReloadedMsg:
+		if msg.repo != m.diffRepo || msg.file != m.diffFile || msg.content == m.diffContent {
+			return m, nil
+		}
+		line := changedHunkLine(m.diffContent, msg.content)
+		m.diffContent = msg.content
+		m.diffFiles = msg.files
+		m.diffCut = msg.truncated
+		m.setDiffContent()
+		m.diffViewport.SetYOffset(m.diffRow(line))
 		return m, nil
 
 	case fileChangedMsg:
-		return m, scanReposCmd(m.scanRoot)
+		m.refresh(msg.repo)
+		if msg.note != "" {
+			return m, m.notify(msg.note)
+		}
+		return m, nil
 
-	case editorFinishedMsg:
-		return m, scanReposCmd(m.scanRoot)
+	case reportWrittenMsg:
+		if msg.err != nil {
+			return m, m.notifyError("report: " + msg.err.Error())
+		}
+		return m, m.notify(msg.note)
+
+	case clearStatusMsg:
+		if msg.seq == m.statusSeq {
+			m.statusMsg = ""
+			m.statusErr = ""
+		}
+		return m, nil
+
+	case prTickMsg:
+		if !m.config.PRStatus || m.config.SafeMode {
+			return m, prTickCmd(time.Duration(m.config.PRInterval) * time.Second)
+		}
+		return m, checkPRsCmd(m.repos, m.config.FetchWorkers)
+
+	case snapshotTickMsg:
+		if !m.config.Snapshots || m.config.SafeMode {
+			return m, snapshotTickCmd(time.Duration(m.config.SnapshotInterval) * time.Second)
+		}
+		repos := append([]sidegit.Repo(nil), m.repos...)
+		for i, w := range m.tabs {
+			if i != m.activeTab {
+				repos = append(repos, w.repos...)
+			}
+		}
+		return m, takeSnapshotsCmd(repos, m.config.SnapshotKeep, true)
+
+	case snapshotsTakenMsg:
+		var cmd tea.Cmd
+		switch {
+		case msg.err != nil:
+			cmd = m.notifyError("snapshot " + msg.err.Error())
+		case !msg.scheduled && msg.taken == 0:
+			cmd = m.notify("no changes since the last snapshot")
+		case !msg.scheduled:
+			cmd = m.notify("snapshot taken")
+		case debugLog != nil:
+			debugLog.Printf("snapshots: %d taken", msg.taken)
+		}
+		if msg.scheduled {
+			cmd = tea.Batch(cmd, snapshotTickCmd(time.Duration(m.config.SnapshotInterval)*time.Second))
+		}
+		if m.snapOpen {
+			cmd = tea.Batch(cmd, loadSnapshotsCmd(m.snap.repo))
+		}
+		return m, cmd
+
+	case prStatusMsg:
+		m.prs = msg.prs
+		m.applyPRs()
+		m.rebuildTree()
+		return m, prTickCmd(time.Duration(m.config.PRInterval) * time.Second)
+
+	case backedUpMsg:
+		m.refresh(msg.repo)
+		if msg.backup != nil {
+			m.lastBackup = msg.backup
+			return m, m.notify("saved a copy of " + msg.backup.file + " (U to undo)")
+		}
+		return m, nil
+
+	case fileRenamedMsg:
+		m.selectRepo, m.selectFile = msg.repo, msg.to
+		m.refresh(msg.repo)
+		if m.diffOpen && m.diffRepo == msg.repo && m.diffFile == msg.from {
+			m.diffFile = msg.to
+			return m, m.reloadOpenDiff()
+		}
+		return m, nil
+
+	case setOnlyStatusMsg:
+		m.config.Filters.Only = msg.name
+		m.rebuildTree()
+		return m, nil
 
-	case pollTickMsg:
-		cmds := []tea.Cmd{scanReposCmd(m.scanRoot)}
-		if m.config.PollInterval > 0 {
-			cmds = append(cmds, pollTickCmd(m.config.PollInterval))
+	case setThemeMsg:
+		m.config.Theme = builtinThemes[msg.name]()
+		m.rebuildTree()
+		m.statusMsg = "theme: " + msg.name + " (set theme: " + msg.name + " in config.yaml to keep it)"
+		return m, nil
+
+	case openThemeEditorMsg:
+		m.openThemeEditor()
+		return m, nil
+
+	case themeColorMsg:
+		m.setThemeColor(msg.i, msg.value)
+		return m, nil
+
+	case themeSavedMsg:
+		if msg.err != nil {
+			return m, m.notifyError("theme: " + msg.err.Error())
+		}
+		return m, m.notify(fmt.Sprintf("saved %s to %s", plural(msg.n, "color"), m.config.Overrides.configFile()))
+
+	case configProblemsMsg:
+		var cmds []tea.Cmd
+		for _, err := range msg.errs {
+			cmds = append(cmds, m.notifyError(configNotice(err)))
 		}
 		return m, tea.Batch(cmds...)
 
+	case repoExcludedMsg:
+		if !slices.Contains(m.config.ExcludeRepos, msg.pattern) {
+			m.config.ExcludeRepos = append(m.config.ExcludeRepos, msg.pattern)
+		}
+		m.repos = visibleRepos(m.repos, m.config)
+		m.rebuildTree()
+		return m, m.notify("hid " + msg.pattern + " (remove it from exclude_repos to bring it back)")
+
+	case configChangedMsg:
+		i := m.tabOf(msg.from)
+		if i < 0 {
+			return m, nil
+		} else if i != m.activeTab {
+			// Picked up when the tab is switched to
+			m.tabs[i].config = tabConfig(m.tabs[i].config, m.tabs[i].root)
+			return m, waitForConfigCmd(msg.from)
+		}
+		cfg, err := ReloadConfig(m.scanRoot, m.config.Overrides)
+		if err != nil && !isConfigProblems(err) {
+			return m, tea.Batch(m.notifyError("config: "+err.Error()), waitForConfigCmd(msg.from))
+		}
+		cfg.SafeMode = m.config.SafeMode
+		cfg.Inline = m.config.Inline // the screen is picked at start
+		if cfg.UntrackedFiles != m.config.UntrackedFiles {
+			// Statuses from before were taken in the old mode
+			m.service.Refresh()
+		}
+		m.useConfig(cfg)
+		m.repos = visibleRepos(m.service.Snapshot(), cfg)
+		m.applyAccents()
+		m.applyPRs()
+		m.sortRepos()
+		m.rebuildTree()
+		m.diffViewport.Width = m.diffWidth()
+		m.diffViewport.Height = m.diffHeight()
+		var note tea.Cmd
+		if err != nil {
+			note = m.notifyError("config reloaded with problems: " + configNotice(err))
+		} else {
+			note = m.notify("Config reloaded")
+		}
+		return m, tea.Batch(note, m.reloadOpenDiff(), waitForConfigCmd(msg.from))
+
+	case editorFinishedMsg:
+		m.refresh(msg.repo)
+		switch {
+		case msg.note != "" && msg.err != nil:
+			return m, m.notifyError(msg.note + ": " + msg.err.Error())
+		case msg.note != "":
+			return m, m.notify(msg.note)
+		}
+		return m, nil
+
 	case gitErrorMsg:
-		m.statusMsg = "git: " + msg.err.Error()
+		notice := m.notifyError("git: " + msg.err.Error())
+		// A failed operation may still have changed the worktree (e.g. a
+		// conflicted cherry-pick), so refresh to surface it in the tree
+		if msg.changed {
+			m.refresh(msg.repo)
+		}
+		var credErr *sidegit.CredentialsError
+		if errors.As(msg.err, &credErr) {
+			repoPath, args := msg.repo, credErr.Args
+			m.openMenu("git "+strings.Join(args, " ")+" needs a password or passphrase", []menuOption{
+				{key: "t", label: "Run it in the terminal", action: func() tea.Cmd {
+					return terminalGitCmd(repoPath, args)
+				}},
+				{label: "Cancel"},
+			})
+		}
+		return m, notice
+
+	case stagedAllMsg:
+		for i := range m.repos {
+			if m.repos[i].Path != msg.repo {
+				continue
+			}
+			// A copy: the slice is shared with the engine's snapshot
+			files := append([]sidegit.FileStatus(nil), m.repos[i].Files...)
+			for j := range files {
+				files[j].IsStaged = msg.staged
+			}
+			m.repos[i].Files = files
+			verb := "unstaged"
+			if msg.staged {
+				verb = "staged"
+			}
+			m.rebuildTree()
+			m.refresh(msg.repo)
+			return m, m.notify(fmt.Sprintf("%s %d files in %s", verb, len(m.repos[i].Files), m.repos[i].RelPath))
+		}
+		m.refresh(msg.repo)
+		return m, nil
+
+	case committedMsg:
+		m.refresh(msg.repo)
+		if msg.push {
+			return m, jobCmd("push "+filepath.Base(msg.repo), msg.repo, "committed and pushed "+filepath.Base(msg.repo), func(ctx context.Context) error {
+				return sidegit.GitPushSetUpstream(ctx, msg.repo)
+			})
+		}
+		return m, m.notify("committed in " + filepath.Base(msg.repo))
+
+	case autoCommitMsg:
+		if st, ok := m.autoCommits[msg.repo]; !ok || st.gen != msg.gen {
+			return m, nil // changed again since, or committed by hand
+		}
+		for _, w := range append([]workspace{{repos: m.repos}}, m.tabs...) {
+			for _, r := range w.repos {
+				if r.Path == msg.repo {
+					return m, autoCommitCmd(r, m.config.AutoCommitPush)
+				}
+			}
+		}
+		return m, nil
+
+	case bulkOpenMsg:
+		return m, m.startBulk(msg)
+
+	case jobStartMsg:
+		return m, m.startJob(msg)
+
+	case jobDoneMsg:
+		var alert tea.Cmd
+		if j := m.jobByID(msg.id); j != nil {
+			j.finish(msg.err)
+			alert = m.jobAlert(j, msg.err != nil)
+		}
+		then := msg.then
+		return m, tea.Batch(alert, func() tea.Msg { return then })
+
+	case jobsTickMsg:
+		if m.jobsOpen {
+			return m, jobsTickCmd()
+		}
+		return m, nil
+
+	case taskStartMsg:
+		return m, m.startTask(msg.repo, msg.name, msg.command)
+
+	case taskShowMsg:
+		m.showTask(msg.repo)
+		return m, nil
+
+	case taskOutputMsg:
+		return m, m.taskOutput(msg)
+
+	case taskDoneMsg:
+		return m, m.taskDone(msg)
+
+	case bulkResultMsg:
+		if msg.id != m.bulkID {
+			return m, nil
+		}
+		m.bulkRows[msg.row].done = true
+		m.bulkRows[msg.row].err = msg.err
+		m.refresh(m.bulkRows[msg.row].repo.Path)
+		if !m.bulkRunning() {
+			alert := m.finishBulkJob()
+			failed := 0
+			for _, r := range m.bulkRows {
+				if r.err != nil {
+					failed++
+				}
+			}
+			if failed > 0 {
+				return m, tea.Batch(alert, m.notifyError(fmt.Sprintf("%s: %d of %d repos failed", m.bulkTitle, failed, len(m.bulkRows))))
+			}
+			return m, tea.Batch(alert, m.notify(fmt.Sprintf("%s: %d repos done", m.bulkTitle, len(m.bulkRows))))
+		}
+		return m, nil
+
+	case collapseOthersMsg:
+		m.tree.CollapseOthers()
+		return m, nil
+
+	case openMenuMsg:
+		m.openMenu(msg.title, msg.options)
+		m.menuCursor = min(msg.cursor, len(msg.options)-1)
+		if visible := m.maxMenuVisible(); visible > 0 && m.menuCursor >= visible {
+			m.menuScrollOffset = m.menuCursor - visible + 1
+		}
+		if msg.preview != "" {
+			m.menuHasPreview = true
+			m.menuPreviewTitle = msg.previewTitle
+			m.menuPreview = viewport.New(m.width-4, m.menuPreviewHeight())
+			m.menuPreview.SetContent(msg.preview)
+		}
+		return m, nil
+
+	case openPromptMsg:
+		return m, m.openPrompt(msg)
+
+	case openInfoMsg:
+		m.openInfo(msg.title, msg.rows)
+		return m, nil
+
+	case snapshotsLoadedMsg:
+		return m, m.openSnapshots(msg)
+
+	case snapshotDiffMsg:
+		if m.snapOpen && m.snap.cursor < len(m.snap.snaps) && m.snap.snaps[m.snap.cursor].Hash == msg.hash {
+			m.snap.vp.SetContent(msg.text)
+		}
+		return m, nil
+
+	case commitLoadedMsg:
+		m.openCommit(msg)
+		return m, nil
+
+	case commitFileMsg:
+		if m.commitOpen && msg.hash == m.commit.detail.Hash && msg.file == m.commit.file {
+			text := msg.text
+			if msg.files != nil {
+				text, _ = renderDiff(flattenDiff(msg.files, m.config.Theme), nil, nil, m.config.DiffLineNums, m.config.Theme)
+			}
+			m.commit.vp.SetContent(text + msg.note)
+		}
+		return m, nil
+
+	case tea.ResumeMsg:
+		// Anything may have changed while sidegit was suspended
+		m.refresh("")
 		return m, nil
 
 	case tea.KeyMsg:
@@ -135,12 +763,27 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
+func (m *model) openMenu(title string, options []menuOption) {
+	m.menuTitle = title
+	m.menuOptions = options
+	m.menuCursor = 0
+	m.menuScrollOffset = 0
+	m.menuOpen = true
+}
+
 func (m *model) closeMenu() {
 	m.menuOpen = false
 	m.menuTitle = ""
 	m.menuOptions = nil
 	m.menuCursor = 0
 	m.menuScrollOffset = 0
+	m.menuHasPreview = false
+}
+
+// menuPreviewHeight is how many preview lines fit under the menu's options.
+func (m model) menuPreviewHeight() int {
+	// Outer margin, the options box and the preview box's own border
+	return max(3, m.height-2-(len(m.menuOptions)+2)-2)
 }
 
 func (m model) maxMenuVisible() int {
@@ -152,7 +795,76 @@ func (m model) maxMenuVisible() int {
 }
 
 func (m model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
-	m.statusMsg = ""
+	// Errors stay until they time out, so the next keypress doesn't hide
+	// them before they're read
+	if m.statusMsg != m.statusErr {
+		m.statusMsg = ""
+	}
+
+	// Suspend to the shell like any terminal program, whatever is open
+	if msg.String() == "ctrl+z" {
+		return m, tea.Suspend
+	}
+
+	// Before the tour, which opens the quit confirmation over itself
+	if m.quitOpen {
+		return m.handleQuitKey(msg)
+	}
+
+	// The onboarding tour captures navigation keys until dismissed
+	if m.tourOpen {
+		switch msg.String() {
+		case "enter", " ", "right", "l", "n":
+			m.nextTourStep()
+		case "left", "h":
+			m.prevTourStep()
+		case "esc":
+			m.closeTour()
+		case "q", "ctrl+c":
+			return m.quit()
+		}
+		return m, nil
+	}
+
+	if m.switcherOpen {
+		return m.handleSwitcherKey(msg)
+	}
+
+	if m.promptOpen {
+		return m.handlePromptKey(msg)
+	}
+
+	if m.themeEditOpen {
+		return m.handleThemeEditorKey(msg)
+	}
+
+	if m.messagesOpen {
+		return m.handleMessagesKey(msg)
+	}
+
+	if m.bulkOpen {
+		return m.handleBulkKey(msg)
+	}
+
+	if m.taskOpen {
+		return m.handleTaskKey(msg)
+	}
+
+	if m.jobsOpen {
+		return m.handleJobsKey(msg)
+	}
+
+	if m.commitOpen {
+		return m.handleCommitKey(msg)
+	}
+
+	if m.snapOpen {
+		return m.handleSnapshotsKey(msg)
+	}
+
+	if m.dashOpen {
+		return m.handleDashboardKey(msg)
+	}
 
 	// Any key closes help overlay
 	if m.helpOpen {
@@ -160,6 +872,12 @@ func (m model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		return m, nil
 	}
 
+	// Any key closes the details popup
+	if m.infoOpen {
+		m.infoOpen = false
+		return m, nil
+	}
+
 	// Intercept keys when menu is open
 	if m.menuOpen {
 		switch msg.String() {
@@ -186,6 +904,10 @@ func (m model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			}
 		case "esc":
 			m.closeMenu()
+		case "pgdown", "ctrl+d":
+			m.menuPreview.HalfPageDown()
+		case "pgup", "ctrl+u":
+			m.menuPreview.HalfPageUp()
 		default:
 			// Check shortcut keys
 			key := msg.String()
@@ -202,13 +924,25 @@ func (m model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		return m, nil
 	}
 
+	// "]f" and "[f" move between the files of a repo diff; a bracket
+	// followed by any other key switches tabs, as it does on its own
+	if b := m.bracket; b != "" {
+		m.bracket = ""
+		if msg.String() == "f" {
+			m.jumpDiffFile(b == "[")
+			return m, nil
+		}
+		m.cycleTab(b == "[")
+	}
+
 	switch msg.String() {
 	case "q", "ctrl+c":
-		return m, tea.Quit
+		return m.quit()
 
 	case "up", "k":
 		if m.focused == panelTree {
 			m.tree.MoveUp()
+			m.trackVisit()
 		} else {
 			var cmd tea.Cmd
 			m.diffViewport, cmd = m.diffViewport.Update(msg)
@@ -218,6 +952,7 @@ func (m model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	case "down", "j":
 		if m.focused == panelTree {
 			m.tree.MoveDown()
+			m.trackVisit()
 		} else {
 			var cmd tea.Cmd
 			m.diffViewport, cmd = m.diffViewport.Update(msg)
@@ -228,8 +963,19 @@ func (m model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		if m.focused == panelTree {
 			node := m.tree.SelectedNode()
 			if node != nil && node.Kind == NodeFile {
-				return m, loadDiffCmd(node.Repo.Path, node.File.Path)
+				m.trackAction(node)
+				return m, m.openFileDiff(node)
+			}
+			if node != nil && node.Kind == NodeRepo {
+				m.trackAction(node)
+				m.openMenu("Repo: "+node.Repo.RelPath, m.repoMenuOptions(*node.Repo))
+			}
+			if node != nil && node.Kind == NodeDir {
+				m.trackAction(node)
+				m.openMenu(node.Repo.RelPath+": "+node.dirFull+"/", m.dirMenuOptions(node))
 			}
+		} else {
+			m.unfoldInView()
 		}
 
 	case "esc":
@@ -237,7 +983,7 @@ func (m model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		m.focused = panelTree
 
 	case "tab":
-		if m.diffOpen {
+		if m.diffOpen && !m.diffOnly() {
 			if m.focused == panelTree {
 				m.focused = panelDiff
 			} else {
@@ -254,7 +1000,8 @@ func (m model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		if m.focused == panelTree {
 			node := m.tree.SelectedNode()
 			if node != nil && node.Kind == NodeFile {
-				return m, openInEditorCmd(node.Repo.Path, node.File.Path)
+				m.trackAction(node)
+				return m, openFileCmd(m.config, node.Repo.Path, node.File.Path)
 			}
 		}
 
@@ -262,35 +1009,135 @@ func (m model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		if m.focused == panelTree {
 			node := m.tree.SelectedNode()
 			if node != nil && node.Kind == NodeFile {
+				m.trackAction(node)
 				repoPath := node.Repo.Path
 				filePath := node.File.Path
-				isUntracked := node.File.Status == StatusUntracked
+				isUntracked := node.File.Status == sidegit.StatusUntracked
+				keepDays := m.config.BackupDays
 				discardAll := func() tea.Cmd {
+					return backupCmd(repoPath, filePath, keepDays, func() error {
+						return sidegit.DiscardAllChanges(repoPath, filePath, isUntracked)
+					})
+				}
+				return m, discardMenuCmd(repoPath, filePath, []sidegit.FileStatus{*node.File}, m.config.DiffMaxLines, discardAll)
+			}
+		}
+
+	case "I":
+		m.openDashboard()
+
+	case "D":
+		node := m.tree.SelectedNode()
+		if m.focused == panelTree && node != nil && node.Kind == NodeFile && node.File.Status != sidegit.StatusDeleted {
+			m.trackAction(node)
+			repoPath := node.Repo.Path
+			filePath := node.File.Path
+			isUntracked := node.File.Status == sidegit.StatusUntracked
+			label := "git rm " + filePath
+			if isUntracked {
+				label = "Remove " + filePath
+			}
+			keepDays := m.config.BackupDays
+			m.openMenu("Delete file", []menuOption{
+				{key: "x", label: label, action: func() tea.Cmd {
+					return backupCmd(repoPath, filePath, keepDays, func() error {
+						return sidegit.DeleteFile(repoPath, filePath, isUntracked)
+					})
+				}},
+				{label: "Cancel"},
+			})
+		}
+
+	case "U":
+		if b := m.lastBackup; b != nil {
+			m.lastBackup = nil
+			m.statusMsg = "restored " + b.file
+			return m, gitCmd(b.repo, b.restore)
+		}
+		m.statusMsg = "nothing to undo"
+
+	case "n":
+		if m.focused == panelTree {
+			node := m.tree.SelectedNode()
+			if node != nil && node.Kind == NodeFile && node.File.Status != sidegit.StatusDeleted {
+				m.trackAction(node)
+				repoPath := node.Repo.Path
+				// Untracked directories are listed with a trailing slash
+				from := strings.TrimSuffix(node.File.Path, "/")
+				suffix := strings.TrimPrefix(node.File.Path, from)
+				isUntracked := node.File.Status == sidegit.StatusUntracked
+				return m, promptCmd(openPromptMsg{title: "Rename " + from, value: from, onSubmit: func(to string) tea.Cmd {
+					to = path.Clean(filepath.ToSlash(to))
+					if to == from {
+						return nil
+					}
 					return func() tea.Msg {
-						_ = DiscardAllChanges(repoPath, filePath, isUntracked)
-						return fileChangedMsg{}
+						if err := sidegit.RenameFile(repoPath, from, to, isUntracked); err != nil {
+							return gitErrorMsg{repo: repoPath, err: err}
+						}
+						return fileRenamedMsg{repo: repoPath, from: from + suffix, to: to + suffix}
 					}
-				}
-				m.menuTitle = "Discard changes"
-				m.menuOptions = []menuOption{
-					{key: "x", label: "Discard all changes", action: discardAll},
-					{label: "Cancel"},
-				}
-				m.menuCursor = 0
-				m.menuOpen = true
+				}})
 			}
 		}
 
+	case "1", "2", "3", "4", "5", "6", "7", "8", "9":
+		m.switchTab(int(msg.String()[0] - '1'))
+
+	case "[", "]":
+		if m.focused == panelDiff && m.diffOpen && m.diffFile == "" {
+			m.bracket = msg.String() // "]f" or "[f" may follow
+			return m, nil
+		}
+		m.cycleTab(msg.String() == "[")
+
+	case "ctrl+t":
+		return m, m.openTabCmd()
+
+	case "ctrl+w":
+		m.closeTab()
+
 	case "p":
-		if m.config.DiffPosition == "right" {
-			m.config.DiffPosition = "bottom"
-		} else {
-			m.config.DiffPosition = "right"
+		// Cycle through the layouts
+		layouts := m.config.allLayouts()
+		next := layouts[0]
+		for i, l := range layouts {
+			if l.Name == m.config.DiffPosition {
+				next = layouts[(i+1)%len(layouts)]
+			}
+		}
+		m.config.DiffPosition = next.Name
+		m.statusMsg = "layout: " + next.Name
+		if m.diffOpen {
+			m.diffViewport = viewport.New(m.diffWidth(), m.diffHeight())
+			m.setDiffContent()
+		}
+
+	case "f":
+		// Zen: the open diff takes the whole screen
+		m.zen = !m.zen
+		m.statusMsg = "zen off"
+		if m.zen {
+			m.focused = panelDiff
+			m.statusMsg = "zen on"
 		}
 		if m.diffOpen {
 			m.diffViewport = viewport.New(m.diffWidth(), m.diffHeight())
-			m.diffViewport.SetContent(m.diffContent)
+			m.setDiffContent()
+		}
+
+	case "H":
+		m.openMessages()
+
+	case "J":
+		return m, m.openJobs()
+
+	case "A":
+		if m.bulkRunning() {
+			m.bulkOpen = true
+			break
 		}
+		m.openMenu("All repos", m.bulkMenuOptions())
 
 	case "?":
 		m.helpOpen = true
@@ -299,12 +1146,21 @@ func (m model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		if m.focused == panelTree {
 			node := m.tree.SelectedNode()
 			if node != nil && node.Kind == NodeRepo {
+				m.trackAction(node)
 				repoPath := node.Repo.Path
-				branches, current, err := ListBranches(repoPath)
+				branches, current, err := sidegit.ListBranches(repoPath)
 				if err != nil {
 					m.statusMsg = "git: " + err.Error()
 					return m, nil
 				}
+				// Unprefixed branches (main, develop) first, then one group
+				// per branch_colors prefix
+				colors := m.config.BranchColors
+				sort.SliceStable(branches, func(i, j int) bool {
+					pi, _ := branchPrefix(colors, branches[i])
+					pj, _ := branchPrefix(colors, branches[j])
+					return pi < pj
+				})
 				var opts []menuOption
 				for _, br := range branches {
 					br := br // capture
@@ -314,72 +1170,653 @@ func (m model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 						key = "*"
 						label = br + " (current)"
 					}
+					_, color := branchPrefix(colors, br)
+					opts = append(opts, menuOption{
+						key:   key,
+						label: label,
+						color: color,
+						action: func() tea.Cmd {
+							return checkoutBranchCmd(repoPath, br)
+						},
+					})
+				}
+				// A new branch takes uncommitted changes along, so this is
+				// also the rescue for work started on the wrong branch
+				title := "Branches: " + node.Repo.RelPath
+				label := "New branch with current changes…"
+				if node.Repo.Detached != "" {
+					title += " " + node.Repo.Detached
+					label = "New branch here…"
+				}
+				opts = append([]menuOption{{key: "n", label: label, action: func() tea.Cmd {
+					return promptCmd(openPromptMsg{
+						title:       "New branch name",
+						placeholder: "feature/…",
+						history:     "branch",
+						validate:    func(v string) error { return sidegit.CheckNewBranchName(repoPath, v) },
+						onSubmit: func(v string) tea.Cmd {
+							return gitCmd(repoPath, func() error { return sidegit.SwitchNewBranch(repoPath, v) })
+						},
+					})
+				}}}, opts...)
+				opts = append(opts, menuOption{label: "Cancel"})
+				m.openMenu(title, opts)
+			}
+		}
+
+	case "s":
+		if m.focused == panelTree {
+			node := m.tree.SelectedNode()
+			if node != nil && node.Kind == NodeRepo {
+				m.trackAction(node)
+				repoPath := node.Repo.Path
+				title := "Sync: " + node.Repo.RelPath
+				if !node.Repo.Fetched.IsZero() {
+					title += " (fetched " + formatAge(time.Since(node.Repo.Fetched)) + " ago)"
+				}
+				if node.Repo.Ahead > 0 {
+					title += fmt.Sprintf(" ↑%d", node.Repo.Ahead)
+				}
+				if node.Repo.Behind > 0 {
+					title += fmt.Sprintf(" ↓%d", node.Repo.Behind)
+				}
+				m.menuTitle = title
+				m.menuOptions = []menuOption{
+					{key: "f", label: "Fetch", action: func() tea.Cmd {
+						return jobCmd("fetch "+filepath.Base(repoPath), repoPath, "fetched "+filepath.Base(repoPath), func(ctx context.Context) error { return sidegit.GitFetch(ctx, repoPath) })
+					}},
+					{key: "l", label: "Pull (fetch & merge)", action: func() tea.Cmd {
+						return gitPullCmd(repoPath)
+					}},
+					{key: "p", label: "Push", action: func() tea.Cmd {
+						return gitPushCmd(repoPath)
+					}},
+					{label: "Cancel"},
+				}
+				m.menuCursor = 0
+				m.menuScrollOffset = 0
+				m.menuOpen = true
+			}
+		}
+
+	case "i":
+		if m.focused == panelTree {
+			node := m.tree.SelectedNode()
+			if node != nil && node.Kind == NodeRepo {
+				return m, repoInfoCmd(*node.Repo, m.state.Note(node.Repo.Path, ""))
+			}
+			m.showNote()
+		}
+
+	case "N":
+		if m.focused == panelTree {
+			return m, m.editNoteCmd()
+		}
+
+	case "!":
+		if m.focused == panelTree {
+			m.openTaskMenu()
+		}
+
+	case ":":
+		if m.focused == panelTree {
+			return m, m.execPromptCmd()
+		}
+
+	case "t":
+		if node := m.tree.SelectedNode(); node != nil && node.Repo != nil {
+			return m, shellCmd(node.Repo.Path)
+		}
+
+	case "Q":
+		if node := m.tree.SelectedNode(); node != nil && node.Repo != nil {
+			m.cdPath = node.Repo.Path
+			return m.quit()
+		}
+
+	case "M":
+		if m.focused == panelTree {
+			node := m.tree.SelectedNode()
+			if node != nil && node.Kind == NodeFile && node.File.Status == sidegit.StatusConflict {
+				m.trackAction(node)
+				repoPath := node.Repo.Path
+				filePath := node.File.Path
+				m.openMenu("Resolve conflict: "+filePath, []menuOption{
+					{key: "o", label: "Take ours", action: func() tea.Cmd {
+						return gitCmd(repoPath, func() error { return sidegit.ResolveConflict(repoPath, filePath, "ours") })
+					}},
+					{key: "t", label: "Take theirs", action: func() tea.Cmd {
+						return gitCmd(repoPath, func() error { return sidegit.ResolveConflict(repoPath, filePath, "theirs") })
+					}},
+					{key: "m", label: "Open mergetool", action: func() tea.Cmd {
+						return mergetoolCmd(repoPath, filePath)
+					}},
+					{key: "a", label: "Mark resolved", action: func() tea.Cmd {
+						return gitCmd(repoPath, func() error { return sidegit.MarkResolved(repoPath, filePath) })
+					}},
+					{label: "Cancel"},
+				})
+			}
+		}
+
+	case "E":
+		if len(m.repos) > 0 {
+			m.openMenu("Export a report of "+m.scanRoot, m.reportOptions())
+		}
+
+	case "C":
+		if m.config.Filters.Only == "conflict" {
+			m.config.Filters.Only = ""
+		} else {
+			m.config.Filters.Only = "conflict"
+		}
+		m.rebuildTree()
+
+	case "u":
+		m.config.Filters.HideUntracked = !m.config.Filters.HideUntracked
+		m.rebuildTree()
+
+	case "S":
+		m.config.Filters.HideStaged = !m.config.Filters.HideStaged
+		m.rebuildTree()
+
+	case "V":
+		opts := []menuOption{{key: "a", label: "All statuses", action: func() tea.Cmd {
+			return func() tea.Msg { return setOnlyStatusMsg{} }
+		}}}
+		for i, st := range statusNames {
+			name := st.name
+			opts = append(opts, menuOption{
+				key:   strconv.Itoa(i + 1),
+				label: name,
+				action: func() tea.Cmd {
+					return func() tea.Msg { return setOnlyStatusMsg{name: name} }
+				},
+			})
+		}
+		opts = append(opts, menuOption{label: "Cancel"})
+		m.openMenu("Show only", opts)
+
+	case "R":
+		if m.focused == panelTree {
+			node := m.tree.SelectedNode()
+			if node != nil && node.Kind == NodeRepo {
+				m.trackAction(node)
+				opts, err := remoteMenuOptions(*node.Repo)
+				if err != nil {
+					m.statusMsg = "git: " + err.Error()
+					return m, nil
+				}
+				title := "Remotes: " + node.Repo.RelPath
+				if node.Repo.Upstream != "" {
+					title += " → " + node.Repo.Upstream
+				} else {
+					title += " (no upstream)"
+				}
+				m.openMenu(title, opts)
+			}
+		}
+
+	case "K":
+		if m.focused == panelTree {
+			node := m.tree.SelectedNode()
+			if node == nil || node.Repo == nil || node.Repo.Unavailable {
+				break
+			}
+			m.trackAction(node)
+			staged := 0
+			for _, f := range node.Repo.Files {
+				if f.IsStaged {
+					staged++
+				}
+			}
+			if staged == 0 {
+				m.statusMsg = "nothing staged in " + node.Repo.RelPath
+				break
+			}
+			m.openMenu(commitMenuTitle(*node.Repo), m.commitMenuOptions(*node.Repo, m.config.CommitPush))
+		}
+
+	case "W":
+		if m.focused == panelTree {
+			node := m.tree.SelectedNode()
+			if node == nil || node.Repo == nil {
+				break
+			}
+			m.trackAction(node)
+			repo, urls := *node.Repo, m.config.WebURLs
+			switch node.Kind {
+			case NodeFile:
+				return m, openWebCmd(urls, repo, "file", node.File.Path)
+			case NodeRepo:
+				pr := menuOption{key: "p", label: "Create pull request", action: func() tea.Cmd {
+					return openWebCmd(urls, repo, "compare", "")
+				}}
+				if repo.PR != nil {
+					pr.label = fmt.Sprintf("Open pull request #%d", repo.PR.Number)
+					pr.action = func() tea.Cmd {
+						return func() tea.Msg {
+							if err := openURL(repo.PR.URL); err != nil {
+								return gitErrorMsg{repo: repo.Path, err: err}
+							}
+							return nil
+						}
+					}
+				}
+				m.openMenu("Open in browser: "+repo.RelPath, []menuOption{
+					{key: "b", label: "Browse branch", action: func() tea.Cmd {
+						return openWebCmd(urls, repo, "tree", "")
+					}},
+					pr,
+					{label: "Cancel"},
+				})
+			}
+		}
+
+	case "ctrl+k":
+		m.focused = panelTree
+		return m, m.openSwitcher()
+
+	case "L":
+		if m.focused == panelTree {
+			node := m.tree.SelectedNode()
+			if node != nil && node.Kind == NodeRepo {
+				m.trackAction(node)
+				commits, err := sidegit.GetLog(node.Repo.Path, 50)
+				if err != nil {
+					m.statusMsg = "git: " + err.Error()
+					return m, nil
+				}
+				source := *node.Repo
+				var opts []menuOption
+				for _, c := range commits {
+					c := c // capture
 					opts = append(opts, menuOption{
-						key:   key,
-						label: label,
+						label: fmt.Sprintf("%s %s (%s, %s)", c.Short, c.Subject, c.Author, c.When),
 						action: func() tea.Cmd {
-							return checkoutBranchCmd(repoPath, br)
+							return loadCommitCmd(source, c.Hash)
 						},
 					})
 				}
 				opts = append(opts, menuOption{label: "Cancel"})
-				m.menuTitle = "Branches: " + node.Repo.RelPath
-				m.menuOptions = opts
-				m.menuCursor = 0
-				m.menuScrollOffset = 0
-				m.menuOpen = true
+				m.openMenu("Log: "+node.Repo.RelPath, opts)
 			}
 		}
 
-	case "s":
+	case "w":
+		m.config.DiffIgnoreWS = !m.config.DiffIgnoreWS
+		return m, m.reloadOpenDiff()
+
+	case "v":
+		return m, m.toggleReviewed()
+
+	case "#":
+		m.config.DiffLineNums = !m.config.DiffLineNums
+		m.setDiffContent()
+		return m, nil
+
+	case "z":
+		m.config.DiffFold = !m.config.DiffFold
+		m.setDiffContent()
+		return m, nil
+
+	case "m":
 		if m.focused == panelTree {
-			node := m.tree.SelectedNode()
-			if node != nil && node.Kind == NodeRepo {
-				repoPath := node.Repo.Path
-				title := "Sync: " + node.Repo.RelPath
-				if node.Repo.Ahead > 0 {
-					title += fmt.Sprintf(" ↑%d", node.Repo.Ahead)
-				}
-				if node.Repo.Behind > 0 {
-					title += fmt.Sprintf(" ↓%d", node.Repo.Behind)
-				}
-				m.menuTitle = title
-				m.menuOptions = []menuOption{
-					{key: "l", label: "Pull (fetch & merge)", action: func() tea.Cmd {
-						return gitPullCmd(repoPath)
+			if node := m.tree.SelectedNode(); node != nil && node.Kind == NodeRepo {
+				m.trackAction(node)
+				m.openMenu("Repo: "+node.Repo.RelPath, m.repoMenuOptions(*node.Repo))
+				return m, nil
+			} else if node != nil && node.Kind == NodeDir {
+				m.trackAction(node)
+				m.openMenu(node.Repo.RelPath+": "+node.dirFull+"/", m.dirMenuOptions(node))
+				return m, nil
+			}
+		}
+		// Load the next page of a diff cut off at diff_max_lines
+		if m.diffOpen && m.diffCut {
+			m.diffPages++
+			return m, reloadDiffCmd(m.diffRepo, m.diffFile, m.diffOptions(), m.diffPager())
+		}
+
+	case "+", "=":
+		m.config.DiffContext++
+		return m, m.reloadOpenDiff()
+
+	case "-":
+		if m.config.DiffContext > 0 {
+			m.config.DiffContext--
+		}
+		return m, m.reloadOpenDiff()
+
+	case "F":
+		m.followDiff = !m.followDiff
+		if m.followDiff {
+			m.statusMsg = "follow: on"
+			if m.diffOpen {
+				return m, reloadDiffCmd(m.diffRepo, m.diffFile, m.diffOptions(), m.diffPager())
+			}
+			return m, nil
+		}
+		m.statusMsg = "follow: off"
+
+	case "O":
+		switch m.config.RepoSort {
+		case "name":
+			m.config.RepoSort = "frecency"
+		case "frecency":
+			m.config.RepoSort = "recent"
+		default:
+			m.config.RepoSort = "name"
+		}
+		m.sortRepos()
+		m.rebuildTree()
+		m.statusMsg = "sort: " + m.config.RepoSort
+
+	case "T":
+		var opts []menuOption
+		for i, name := range sortedThemeNames() {
+			name := name
+			opts = append(opts, menuOption{
+				key:   strconv.Itoa(i + 1),
+				label: name,
+				action: func() tea.Cmd {
+					return func() tea.Msg { return setThemeMsg{name: name} }
+				},
+			})
+		}
+		opts = append(opts,
+			menuOption{key: "e", label: "Edit colors…", action: func() tea.Cmd {
+				return func() tea.Msg { return openThemeEditorMsg{} }
+			}},
+			menuOption{label: "Cancel"})
+		m.openMenu("Theme", opts)
+
+	case "r":
+		m.scanJobStarted()
+		m.service.Refresh()
+	}
+
+	return m, nil
+}
+
+// cherryPickTargets lists every place commit c from source can be picked
+// onto: the current branch of each other repo, then the other local
+// branches of source itself.
+func (m model) cherryPickTargets(source sidegit.Repo, c sidegit.Commit) []menuOption {
+	var opts []menuOption
+	for _, r := range m.repos {
+		if r.Path == source.Path {
+			continue
+		}
+		target := r.Path
+		opts = append(opts, menuOption{
+			label: fmt.Sprintf("%s [%s]", r.RelPath, r.Branch),
+			action: func() tea.Cmd {
+				return cherryPickCmd(target, source.Path, c.Hash)
+			},
+		})
+	}
+	branches, _, _ := sidegit.ListBranches(source.Path)
+	for _, br := range branches {
+		if br == source.Branch {
+			continue
+		}
+		br := br // capture
+		opts = append(opts, menuOption{
+			label: fmt.Sprintf("%s [%s]", source.RelPath, br),
+			action: func() tea.Cmd {
+				return cherryPickOntoBranchCmd(source.Path, br, c.Hash)
+			},
+		})
+	}
+	return append(opts, menuOption{label: "Cancel"})
+}
+
+// remoteMenuOptions builds the remote management menu for a repo.
+func remoteMenuOptions(repo sidegit.Repo) ([]menuOption, error) {
+	remotes, err := sidegit.ListRemotes(repo.Path)
+	if err != nil {
+		return nil, err
+	}
+	repoPath := repo.Path
+
+	suggested := repo.Upstream
+	if suggested == "" && len(remotes) > 0 {
+		suggested = remotes[0].Name + "/" + repo.Branch
+	}
+	opts := []menuOption{
+		{key: "u", label: "Set upstream…", action: func() tea.Cmd {
+			return openPromptCmd("Upstream for "+repo.Branch, suggested, func(v string) tea.Cmd {
+				return gitCmd(repoPath, func() error { return sidegit.SetUpstream(repoPath, v) })
+			})
+		}},
+		{key: "a", label: "Add remote… (name url)", action: func() tea.Cmd {
+			return promptCmd(openPromptMsg{
+				title:       "Add remote (name url)",
+				placeholder: "upstream git@github.com:owner/repo.git",
+				validate: func(v string) error {
+					if len(strings.Fields(v)) != 2 {
+						return fmt.Errorf("expected \"name url\", got %q", v)
+					}
+					return nil
+				},
+				onSubmit: func(v string) tea.Cmd {
+					fields := strings.Fields(v)
+					return gitCmd(repoPath, func() error { return sidegit.AddRemote(repoPath, fields[0], fields[1]) })
+				},
+			})
+		}},
+	}
+	for _, r := range remotes {
+		r := r // capture
+		opts = append(opts, menuOption{
+			label: r.Name + "  " + r.URL,
+			action: func() tea.Cmd {
+				return openMenuCmd("Remote: "+r.Name, []menuOption{
+					{key: "e", label: "Change URL…", action: func() tea.Cmd {
+						return openPromptCmd("URL for "+r.Name, r.URL, func(v string) tea.Cmd {
+							return gitCmd(repoPath, func() error { return sidegit.SetRemoteURL(repoPath, r.Name, v) })
+						})
 					}},
-					{key: "p", label: "Push", action: func() tea.Cmd {
-						return gitPushCmd(repoPath)
+					{key: "x", label: "Remove remote", action: func() tea.Cmd {
+						return gitCmd(repoPath, func() error { return sidegit.RemoveRemote(repoPath, r.Name) })
 					}},
 					{label: "Cancel"},
-				}
-				m.menuCursor = 0
-				m.menuScrollOffset = 0
-				m.menuOpen = true
+				})
+			},
+		})
+	}
+	return append(opts, menuOption{label: "Cancel"}), nil
+}
+
+// useConfig makes cfg the running config, along with the settings kept
+// outside the model.
+func (m *model) useConfig(cfg Config) {
+	m.config = cfg
+	backend.set(cfg)
+	m.height = cfg.viewHeight(m.rows)
+	applyBackground(cfg.Background)
+	setLanguage(cfg.Language)
+	accessible = cfg.Accessible
+}
+
+// rebuildTree rebuilds the tree from m.repos, keeping collapsed state and
+// the cursor.
+func (m *model) rebuildTree() {
+	tree := NewTreeModel(m.treeRepos(), m.config.Groups, m.config.Theme, m.config.RepoSort == "recent", m.config.NestedRepos)
+	tree.ShowAges = m.config.FileAges
+	tree.Reviewed = m.state.IsReviewed
+	tree.Note = m.state.Note
+	tree.Task = m.tasks.last
+	staleDays := m.config.StaleDays
+	tree.Stale = func(r sidegit.Repo) bool { return staleRepo(r, staleDays, time.Now()) }
+	tree.Restore(m.tree)
+	m.tree = tree
+}
+
+// applyAccents sets each repo's accent from repo_accents (matched by
+// display name or folder name), falling back to a hashed color when
+// auto_accent is on. The tree draws stale repos dimmed over it.
+func (m *model) applyAccents() {
+	for i := range m.repos {
+		r := &m.repos[i]
+		if c, ok := m.config.RepoAccents[r.RelPath]; ok {
+			r.Accent = c
+		} else if c, ok := m.config.RepoAccents[filepath.Base(r.Path)]; ok {
+			r.Accent = c
+		} else if m.config.AutoAccent {
+			r.Accent = autoAccent(r.RelPath)
+		}
+		_, r.BranchColor = branchPrefix(m.config.BranchColors, r.Branch)
+		r.AutoCommit = isAutoCommit(m.config, *r)
+	}
+}
+
+// branchPrefix returns the longest branch_colors prefix of branch and its
+// color, or empty strings when none matches.
+func branchPrefix(colors map[string]string, branch string) (prefix, color string) {
+	for p, c := range colors {
+		if strings.HasPrefix(branch, p) && len(p) > len(prefix) {
+			prefix, color = p, c
+		}
+	}
+	return prefix, color
+}
+
+func optionColor(opt menuOption) lipgloss.TerminalColor {
+	if opt.color == "" {
+		return lipgloss.NoColor{}
+	}
+	return themeColor(opt.color)
+}
+
+// applyPRs attaches the last pull request check results to the repos. A
+// repo whose branch changed since, or that the last check found no PR for,
+// keeps nothing until the next check.
+func (m *model) applyPRs() {
+	for i := range m.repos {
+		r := &m.repos[i]
+		branch := r.Branch
+		if _, b, ok := strings.Cut(r.Upstream, "/"); ok {
+			branch = b // the name the PR knows it by
+		}
+		r.PR = nil
+		if pr := m.prs[r.Path]; pr != nil && pr.Branch == branch {
+			r.PR = pr
+		}
+	}
+}
+
+// treeRepos returns the repos to show in the tree with view filters applied.
+func (m model) treeRepos() []sidegit.Repo {
+	fl := m.config.Filters
+	if !fl.Active() {
+		return m.repos
+	}
+	var repos []sidegit.Repo
+	for _, r := range m.repos {
+		var files []sidegit.FileStatus
+		for _, f := range r.Files {
+			if fl.Keep(f) {
+				files = append(files, f)
 			}
 		}
+		// With a single status picked, repos without any are just noise
+		if fl.Only != "" && len(files) == 0 {
+			continue
+		}
+		r.Files = files
+		repos = append(repos, r)
+	}
+	return repos
+}
 
-	case "r":
-		return m, scanReposCmd(m.scanRoot)
+// sortRepos orders repos by the configured sort mode.
+func (m *model) sortRepos() {
+	sidegit.SortReposByPath(m.repos)
+	switch m.config.RepoSort {
+	case "frecency":
+		now := time.Now()
+		sort.SliceStable(m.repos, func(i, j int) bool {
+			return m.state.Frecency(m.repos[i].Path, now) > m.state.Frecency(m.repos[j].Path, now)
+		})
+	case "recent":
+		sort.SliceStable(m.repos, func(i, j int) bool {
+			return sidegit.NewestChange(m.repos[i]).After(sidegit.NewestChange(m.repos[j]))
+		})
 	}
+	if m.config.StaleLast {
+		now := time.Now()
+		sort.SliceStable(m.repos, func(i, j int) bool {
+			return !m.stale(m.repos[i], now) && m.stale(m.repos[j], now)
+		})
+	}
+}
 
-	return m, nil
+// stale reports whether r has had no commit and no file change for
+// stale_days. A repo without either isn't stale, just new.
+func (m model) stale(r sidegit.Repo, now time.Time) bool {
+	return staleRepo(r, m.config.StaleDays, now)
+}
+
+// staleRepo is stale for a stale_days of days.
+func staleRepo(r sidegit.Repo, days int, now time.Time) bool {
+	if days == 0 || r.Unavailable {
+		return false
+	}
+	last := sidegit.LastActive(r)
+	return !last.IsZero() && now.Sub(last) > time.Duration(days)*24*time.Hour
+}
+
+// trackVisit records a frecency visit when the cursor enters a different repo.
+func (m *model) trackVisit() {
+	node := m.tree.SelectedNode()
+	if node == nil || node.Repo == nil || node.Repo.Path == m.visitedRepo {
+		return
+	}
+	m.visitedRepo = node.Repo.Path
+	m.state.RecordVisit(node.Repo.Path, time.Now())
+}
+
+// trackAction records a frecency visit for an action taken on a repo.
+func (m *model) trackAction(node *TreeNode) {
+	m.visitedRepo = node.Repo.Path
+	m.state.RecordVisit(node.Repo.Path, time.Now())
 }
 
 func (m model) View() string {
+	if m.quitting {
+		return "" // inline, leave no frame behind in the scrollback
+	}
 	if !m.ready {
 		return "Loading..."
 	}
 
 	statusBar := m.renderStatusBar()
-	// 1 row status bar + 1 row margin bottom
-	contentHeight := m.height - 2
+	// 1 row status bar + 1 row margin bottom, and the tab bar if any
+	contentHeight := m.height - 2 - m.tabBarHeight()
 	// 2 columns margin (1 left + 1 right)
 	contentWidth := m.width - 2
 
+	// The tour box sits between the panels and the status bar so the
+	// element each step describes stays visible
+	var tourBox string
+	if m.tourOpen {
+		tourBox = m.renderTour(contentWidth)
+		contentHeight -= lipgloss.Height(tourBox)
+	}
+
 	var content string
-	if !m.diffOpen {
+	if m.themeEditOpen {
+		// The tree stays in view to preview the colors
+		editorWidth := min(themeEditorWidth, contentWidth/2)
+		content = lipgloss.JoinHorizontal(lipgloss.Top,
+			m.renderTreePanel(contentWidth-editorWidth, contentHeight),
+			m.renderThemeEditor(editorWidth, contentHeight))
+	} else if !m.diffOpen && !m.tourHighlights(tourTargetDiff) {
 		content = m.renderTreePanel(contentWidth, contentHeight)
+	} else if m.diffOnly() {
+		content = m.renderDiffPanel(contentWidth, contentHeight)
 	} else {
 		content = m.renderSplitView(contentWidth, contentHeight)
 	}
@@ -390,7 +1827,16 @@ func (m model) View() string {
 
 	statusBarWithMargin := lipgloss.NewStyle().MarginBottom(1).MarginLeft(1).Render(statusBar)
 
+	if m.tabBarHeight() > 0 {
+		tabBar := lipgloss.NewStyle().MarginLeft(1).Render(m.renderTabBar())
+		outer = lipgloss.JoinVertical(lipgloss.Left, tabBar, outer)
+	}
+
 	view := lipgloss.JoinVertical(lipgloss.Left, outer, statusBarWithMargin)
+	if m.tourOpen {
+		tourWithMargin := lipgloss.NewStyle().MarginLeft(1).Render(tourBox)
+		view = lipgloss.JoinVertical(lipgloss.Left, outer, tourWithMargin, statusBarWithMargin)
+	}
 
 	if m.menuOpen {
 		view = m.renderMenu()
@@ -400,6 +1846,54 @@ func (m model) View() string {
 		view = m.renderHelp()
 	}
 
+	if m.infoOpen {
+		view = m.renderInfo()
+	}
+
+	if m.messagesOpen {
+		view = m.renderMessages()
+	}
+
+	if m.bulkOpen {
+		view = m.renderBulk()
+	}
+
+	if m.taskOpen {
+		view = m.renderTask()
+	}
+
+	if m.jobsOpen {
+		view = m.renderJobs()
+	}
+
+	if m.commitOpen {
+		view = m.renderCommit()
+	}
+
+	if m.snapOpen {
+		view = m.renderSnapshots()
+	}
+
+	if m.dashOpen {
+		view = m.renderDashboard()
+	}
+
+	if m.quitOpen {
+		view = m.renderQuit()
+	}
+
+	if m.switcherOpen {
+		view = m.renderSwitcher()
+	}
+
+	if m.promptOpen {
+		view = m.renderPrompt()
+	}
+
+	if m.bell {
+		view = "\a" + view
+	}
+
 	return view
 }
 
@@ -408,13 +1902,17 @@ func (m model) renderTreePanel(width, height int) string {
 	if m.focused == panelTree {
 		borderColor = m.config.Theme.BorderFocused
 	}
+	if m.tourHighlights(tourTargetTree) {
+		borderColor = m.config.Theme.Title
+	}
 
-	return renderBorderedPanel("Files", m.tree.Render(width-2, height-2), width, height, borderColor, m.config.Theme.Title)
+	return renderBorderedPanel(tr("Files"), m.tree.Render(width-2, height-2), width, height, borderColor, m.config.Theme.Title)
 }
 
 func (m model) renderSplitView(width, height int) string {
-	if m.config.DiffPosition == "bottom" {
-		treeH := height / 2
+	layout := m.config.layout()
+	if layout.Position == "bottom" {
+		treeH := height * layout.Tree / 100
 		diffH := height - treeH
 		tree := m.renderTreePanel(width, treeH)
 		diff := m.renderDiffPanel(width, diffH)
@@ -422,7 +1920,7 @@ func (m model) renderSplitView(width, height int) string {
 	}
 
 	// Right (default)
-	treeW := width * 2 / 5
+	treeW := width * layout.Tree / 100
 	diffW := width - treeW
 	tree := m.renderTreePanel(treeW, height)
 	diff := m.renderDiffPanel(diffW, height)
@@ -434,6 +1932,9 @@ func (m model) renderDiffPanel(width, height int) string {
 	if m.focused == panelDiff {
 		borderColor = m.config.Theme.BorderFocused
 	}
+	if m.tourHighlights(tourTargetDiff) {
+		borderColor = m.config.Theme.Title
+	}
 
 	innerWidth := width - 2
 	innerHeight := height - 2
@@ -441,22 +1942,45 @@ func (m model) renderDiffPanel(width, height int) string {
 	m.diffViewport.Width = innerWidth
 	m.diffViewport.Height = innerHeight
 
-	return renderBorderedPanel("Diff: "+m.diffFile, m.diffViewport.View(), width, height, borderColor, m.config.Theme.Title)
+	title := tr("Diff")
+	if m.diffFile != "" {
+		title += ": " + m.diffFile
+	} else if len(m.diffFiles) > 0 {
+		i := m.diffFileAt()
+		title += fmt.Sprintf(": %s [%d/%d]", m.diffFiles[i].Path(), i+1, len(m.diffFiles))
+	}
+	if flags := m.diffFlags(); flags != "" {
+		title += " [" + flags + "]"
+	}
+	if m.followDiff {
+		title += " [follow]"
+	}
+	if m.diffCut {
+		title += fmt.Sprintf(" [first %d lines, m: more]", m.diffOptions().MaxLines)
+	}
+	if warn := int64(m.config.DiffWarnKB) * 1024; warn > 0 && m.diffSize > warn {
+		title += " ⚠ " + formatSize(m.diffSize)
+	}
+
+	return renderBorderedPanel(title, m.diffViewport.View(), width, height, borderColor, m.config.Theme.Title)
 }
 
 // renderBorderedPanel draws a box with a title embedded in the top border.
 func renderBorderedPanel(title, content string, width, height int, borderColor, titleColor string) string {
-	bc := lipgloss.Color(borderColor)
-	titleStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color(titleColor))
+	bc := themeColor(borderColor)
+	titleStyle := lipgloss.NewStyle().Bold(true).Foreground(themeColor(titleColor))
 
 	border := lipgloss.NormalBorder()
+	if accessible {
+		border = plainBorder
+	}
 	innerWidth := width - 2 // left + right border chars
 
 	// Top border with title: ┌─ Title ─────────┐
 	// Truncate title if it would overflow (keep room for "┌─ " + " ─┐")
 	maxTitle := innerWidth - 5
-	if maxTitle > 0 && len(title) > maxTitle {
-		title = title[:maxTitle-1] + "…"
+	if maxTitle > 0 {
+		title = truncateStr(title, maxTitle)
 	}
 	titleStr := titleStyle.Render(title)
 	titleVisible := lipgloss.Width(titleStr)
@@ -506,7 +2030,7 @@ func renderBorderedPanel(title, content string, width, height int, borderColor,
 }
 
 func (m model) renderMenu() string {
-	cursorBg := lipgloss.Color(m.config.Theme.CursorBg)
+	cursorBg := themeColor(m.config.Theme.CursorBg)
 	borderColor := m.config.Theme.BorderFocused
 
 	// Full window width minus outer margin (1 left + 1 right)
@@ -529,7 +2053,7 @@ func (m model) renderMenu() string {
 	var lines []string
 
 	if startIdx > 0 {
-		indicator := lipgloss.NewStyle().Foreground(lipgloss.Color(m.config.Theme.Title)).Render("  ▴ more")
+		indicator := lipgloss.NewStyle().Foreground(themeColor(m.config.Theme.Title)).Render("  " + moreGlyph("▴") + tr("more"))
 		vis := lipgloss.Width(indicator)
 		if vis < innerWidth {
 			indicator += strings.Repeat(" ", innerWidth-vis)
@@ -545,13 +2069,17 @@ func (m model) renderMenu() string {
 			bg = bg.Background(cursorBg)
 		}
 
+		// Keep long labels (e.g. commit subjects) inside the box
+		mark := cursorPrefix(selected)
+		label := truncateStr(tr(opt.label), innerWidth-2-lipgloss.Width(mark))
+
 		var line string
 		if opt.key != "" {
-			keyStyled := bg.Foreground(lipgloss.Color(m.config.Theme.Title)).Render(opt.key)
-			labelStyled := bg.Foreground(lipgloss.NoColor{}).Render(" " + opt.label)
+			keyStyled := bg.Render(mark) + bg.Foreground(themeColor(m.config.Theme.Title)).Render(opt.key)
+			labelStyled := bg.Foreground(optionColor(opt)).Render(" " + label)
 			line = keyStyled + labelStyled
 		} else {
-			line = bg.Render("  " + opt.label)
+			line = bg.Foreground(optionColor(opt)).Render("  " + mark + label)
 		}
 
 		// Pad to full inner width with the same background
@@ -564,7 +2092,7 @@ func (m model) renderMenu() string {
 	}
 
 	if endIdx < total {
-		indicator := lipgloss.NewStyle().Foreground(lipgloss.Color(m.config.Theme.Title)).Render("  ▾ more")
+		indicator := lipgloss.NewStyle().Foreground(themeColor(m.config.Theme.Title)).Render("  " + moreGlyph("▾") + tr("more"))
 		vis := lipgloss.Width(indicator)
 		if vis < innerWidth {
 			indicator += strings.Repeat(" ", innerWidth-vis)
@@ -575,7 +2103,19 @@ func (m model) renderMenu() string {
 	content := strings.Join(lines, "\n")
 	boxHeight := len(lines) + 2
 
-	box := renderBorderedPanel(m.menuTitle, content, boxWidth, boxHeight, borderColor, m.config.Theme.Title)
+	box := renderBorderedPanel(tr(m.menuTitle), content, boxWidth, boxHeight, borderColor, m.config.Theme.Title)
+	if m.menuHasPreview {
+		title := "Preview"
+		if m.menuPreviewTitle != "" {
+			title = m.menuPreviewTitle
+		}
+		if !m.menuPreview.AtTop() || !m.menuPreview.AtBottom() {
+			title += fmt.Sprintf(" %d%% (pgup/pgdn)", int(m.menuPreview.ScrollPercent()*100))
+		}
+		m.menuPreview.Width = innerWidth
+		preview := renderBorderedPanel(title, m.menuPreview.View(), boxWidth, m.menuPreview.Height+2, m.config.Theme.BorderNormal, m.config.Theme.Title)
+		box = lipgloss.JoinVertical(lipgloss.Left, box, preview)
+	}
 
 	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, box,
 		lipgloss.WithWhitespaceChars(" "),
@@ -584,23 +2124,61 @@ func (m model) renderMenu() string {
 
 func (m model) renderHelp() string {
 	borderColor := m.config.Theme.BorderFocused
-	keyColor := lipgloss.Color(m.config.Theme.Title)
+	keyColor := themeColor(m.config.Theme.Title)
 
 	shortcuts := [][2]string{
 		{"?", "Show this help"},
-		{"↵", "View diff"},
+		{"↵", "View diff / actions"},
 		{"esc", "Close diff"},
 		{"⇥", "Switch panel"},
 		{"↑/k", "Move up"},
 		{"↓/j", "Move down"},
 		{"c/e", "Collapse/expand"},
 		{"o", "Open in editor"},
+		{"n", "Rename file"},
 		{"d", "Discard changes"},
+		{"D", "Delete file"},
+		{"U", "Undo last discard/delete"},
 		{"b", "Switch branch"},
 		{"s", "Sync (pull/push)"},
-		{"p", "Toggle layout"},
+		{"L", "Log: commit details / cherry-pick"},
+		{"R", "Remotes / upstream"},
+		{"W", "Open in browser"},
+		{"K", "Commit staged changes"},
+		{"v", "Mark reviewed, go to the next"},
+		{"i", "Repo details, or a file's note"},
+		{"I", "Dashboard of every repo"},
+		{"N", "Note on a repo or file"},
+		{"!", "Run a command from the config in the repo"},
+		{":", "Run a git or shell command in the repo"},
+		{"t", "Shell in the repo, refreshed on exit"},
+		{"^z", "Suspend to the shell"},
+		{"M", "Resolve conflict"},
+		{"C", "Conflicts only"},
+		{"u", "Hide untracked"},
+		{"S", "Hide staged"},
+		{"V", "Show only a status"},
+		{"^k", "Go to repo"},
+		{"p", "Next layout"},
+		{"f", "Full-screen diff"},
+		{"1-9 [ ]", "Switch tab"},
+		{"^t / ^w", "Open / close tab"},
+		{"F", "Follow diff"},
+		{"w", "Ignore whitespace"},
+		{"#", "Line numbers"},
+		{"z", "Fold unchanged lines"},
+		{"]f / [f", "Next / previous file"},
+		{"+/-", "Diff context"},
+		{"m", "Repo/dir actions, more of a diff"},
+		{"O", "Cycle repo sort (name/frecency/recent)"},
+		{"T", "Pick or edit a theme"},
+		{"H", "Message log"},
+		{"J", "Jobs: what's running, cancel it"},
+		{"A", "Fetch/pull/push/stash all"},
+		{"E", "Export a markdown report"},
 		{"r", "Refresh"},
 		{"q", "Quit"},
+		{"Q", "Quit and cd to the repo"},
 	}
 
 	boxWidth := m.width - 2
@@ -609,7 +2187,7 @@ func (m model) renderHelp() string {
 	var lines []string
 	for _, sc := range shortcuts {
 		key := lipgloss.NewStyle().Foreground(keyColor).Width(6).Render(sc[0])
-		desc := lipgloss.NewStyle().Render(sc[1])
+		desc := lipgloss.NewStyle().Render(truncateStr(tr(sc[1]), innerWidth-6))
 		line := key + desc
 		vis := lipgloss.Width(line)
 		if vis < innerWidth {
@@ -621,7 +2199,7 @@ func (m model) renderHelp() string {
 	content := strings.Join(lines, "\n")
 	boxHeight := len(shortcuts) + 2
 
-	box := renderBorderedPanel("Help", content, boxWidth, boxHeight, borderColor, m.config.Theme.Title)
+	box := renderBorderedPanel(tr("Help"), content, boxWidth, boxHeight, borderColor, m.config.Theme.Title)
 
 	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, box,
 		lipgloss.WithWhitespaceChars(" "),
@@ -634,89 +2212,381 @@ func (m model) renderStatusBar() string {
 		totalChanges += len(r.Files)
 	}
 
-	left := fmt.Sprintf(" %d repo(s) | %d change(s)", len(m.repos), totalChanges)
-	hints := " | (?) help"
+	left := " " + trf("%d repo(s) | %d change(s)", len(m.repos), totalChanges)
+	if accessible && !m.diffOpen {
+		left = " " + m.announce() + " |" + left
+	}
+	if m.config.SafeMode {
+		left = " " + tr("SAFE MODE") + " |" + left
+	}
+	if m.service != nil && m.service.Fetching() {
+		left += " | ⇅ " + tr("fetching")
+	}
+	if n := m.runningJobs(); n > 0 {
+		left += " | " + trf("%d job(s)", n)
+	}
+	if n := watchLimited(m.repos); n > 0 {
+		left += " | ⚠ " + trf("watch limit: %d polled", n)
+	}
+	if m.config.Filters.Active() {
+		left += " | " + m.config.Filters.String()
+	}
+	if node := m.tree.SelectedNode(); node != nil && node.Repo != nil {
+		if n := m.reviewProgress(node.Repo); n > 0 {
+			left += " | " + trf("reviewed %d/%d", n, len(node.Repo.Files))
+		}
+	}
+	hints := " | " + tr("(?) help")
 	if m.statusMsg != "" {
 		hints = " | " + m.statusMsg
 	}
 
 	full := left + hints
 
-	return lipgloss.NewStyle().
+	color := m.config.Theme.StatusBar
+	if m.tourHighlights(tourTargetStatusBar) {
+		color = m.config.Theme.Title
+	}
+
+	style := lipgloss.NewStyle().
 		MaxHeight(1).
-		Foreground(lipgloss.Color(m.config.Theme.StatusBar)).
-		Render(full)
+		MaxWidth(m.width - 2). // by cell, for wide characters
+		Foreground(themeColor(color))
+	if m.statusMsg != "" && m.statusMsg == m.statusErr {
+		prefix := style.Render(left + " | ")
+		return prefix + style.MaxWidth(max(0, m.width-2-lipgloss.Width(prefix))).Foreground(themeColor(m.config.Theme.StatusConflict)).Render(m.statusMsg)
+	}
+	return style.Render(full)
 }
 
 func (m model) diffWidth() int {
 	contentWidth := m.width - 2
-	if m.config.DiffPosition == "bottom" {
-		return contentWidth - 2
+	if layout := m.config.layout(); !m.diffOnly() && layout.Position == "right" {
+		return (contentWidth - contentWidth*layout.Tree/100) - 2
 	}
-	return (contentWidth - contentWidth*2/5) - 2
+	return contentWidth - 2
 }
 
 func (m model) diffHeight() int {
-	contentHeight := m.height - 2
-	if m.config.DiffPosition == "bottom" {
-		return contentHeight/2 - 2
+	contentHeight := m.height - 2 - m.tabBarHeight()
+	if layout := m.config.layout(); !m.diffOnly() && layout.Position == "bottom" {
+		return (contentHeight - contentHeight*layout.Tree/100) - 2
 	}
 	return contentHeight - 2
 }
 
 // Commands
-func scanReposCmd(root string) tea.Cmd {
+
+// waitForReposCmd waits for the next snapshot published by the service.
+// It reports an engine whose updates ended with engineLostMsg.
+func waitForReposCmd(s Engine) tea.Cmd {
+	return func() tea.Msg {
+		repos, ok := <-s.Updates()
+		if !ok {
+			return engineLostMsg{from: s}
+		}
+		return reposScannedMsg{repos: repos, from: s}
+	}
+}
+
+func waitForConfigCmd(s Engine) tea.Cmd {
+	return func() tea.Msg {
+		if _, ok := <-s.ConfigChanges(); !ok {
+			return nil
+		}
+		return configChangedMsg{from: s}
+	}
+}
+
+// getDiff returns the diff of a file, or of the whole repo when filePath
+// is "".
+func getDiff(repoPath, filePath string, opts sidegit.DiffOptions) (sidegit.Diff, error) {
+	if filePath == "" {
+		return sidegit.GetRepoDiff(repoPath, opts)
+	}
+	return sidegit.GetDiff(repoPath, filePath, opts)
+}
+
+func loadDiffCmd(repoPath, filePath string, opts sidegit.DiffOptions, pager diffPager) tea.Cmd {
 	return func() tea.Msg {
-		repos, _ := ScanRepos(root)
-		return reposScannedMsg{repos: repos}
+		d, err := getDiff(repoPath, filePath, opts)
+		if err != nil {
+			d.Text = fmt.Sprintf("Error loading diff: %v", err)
+		} else {
+			d.Text = pager.page(d.Text)
+		}
+		msg := diffLoadedMsg{content: d.Text, files: d.Files, truncated: d.Truncated, repo: repoPath, file: filePath}
+		if info, err := os.Stat(filepath.Join(repoPath, filePath)); err == nil && !info.IsDir() {
+			msg.size = info.Size()
+		}
+		return msg
 	}
 }
 
-func loadDiffCmd(repoPath, filePath string) tea.Cmd {
+func reloadDiffCmd(repoPath, filePath string, opts sidegit.DiffOptions, pager diffPager) tea.Cmd {
 	return func() tea.Msg {
-		content, err := GetDiff(repoPath, filePath)
+		d, err := getDiff(repoPath, filePath, opts)
 		if err != nil {
-			content = fmt.Sprintf("Error loading diff: %v", err)
+			return nil
 		}
-		return diffLoadedMsg{content: content, file: filePath}
+		return diffReloadedMsg{content: pager.page(d.Text), files: d.Files, truncated: d.Truncated, repo: repoPath, file: filePath}
 	}
 }
 
-func pollTickCmd(seconds int) tea.Cmd {
-	return tea.Tick(time.Duration(seconds)*time.Second, func(t time.Time) tea.Msg {
-		return pollTickMsg(t)
+// followChanged reports whether repos, a new snapshot, changes what the
+// diff followed in follow mode shows: the open file, or any file of a
+// repo diff.
+func (m model) followChanged(repos []sidegit.Repo) bool {
+	if !m.followDiff || !m.diffOpen {
+		return false
+	}
+	files := func(repos []sidegit.Repo) []sidegit.FileStatus {
+		var files []sidegit.FileStatus
+		for _, r := range repos {
+			if r.Path != m.diffRepo {
+				continue
+			}
+			for _, f := range r.Files {
+				if m.diffFile == "" || f.Path == m.diffFile {
+					files = append(files, f)
+				}
+			}
+		}
+		return files
+	}
+	return !slices.EqualFunc(files(m.repos), files(repos), func(a, b sidegit.FileStatus) bool {
+		return a.Path == b.Path && a.Status == b.Status && a.IsStaged == b.IsStaged && a.ModTime.Equal(b.ModTime)
 	})
 }
 
-type editorFinishedMsg struct{ err error }
+func (m model) diffOptions() sidegit.DiffOptions {
+	return sidegit.DiffOptions{
+		IgnoreWhitespace: m.config.DiffIgnoreWS,
+		Context:          m.config.DiffContext,
+		Color:            m.config.DiffPager != "", // a pager expects git's colors
+		MaxLines:         m.config.DiffMaxLines * max(m.diffPages, 1),
+	}
+}
+
+// formatSize renders a byte count, like the diff panel's size warning.
+func formatSize(n int64) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1fMB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.0fKB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%dB", n)
+}
+
+// diffFlags describes non-default diff options for the diff panel title.
+func (m model) diffFlags() string {
+	var flags []string
+	if m.config.DiffIgnoreWS {
+		flags = append(flags, "-w")
+	}
+	if m.config.DiffContext != 3 {
+		flags = append(flags, fmt.Sprintf("-U%d", m.config.DiffContext))
+	}
+	return strings.Join(flags, " ")
+}
+
+// openFileDiff loads the diff of a file node into the diff panel.
+func (m *model) openFileDiff(node *TreeNode) tea.Cmd {
+	m.diffPages = 1
+	opts := m.diffOptions()
+	opts.Untracked = node.File.Status == sidegit.StatusUntracked
+	return loadDiffCmd(node.Repo.Path, node.File.Path, opts, m.diffPager())
+}
+
+// reloadOpenDiff reloads the open diff after its options changed.
+func (m model) reloadOpenDiff() tea.Cmd {
+	if !m.diffOpen {
+		return nil
+	}
+	return loadDiffCmd(m.diffRepo, m.diffFile, m.diffOptions(), m.diffPager())
+}
+
+// changedHunkLine returns the line of the hunk header in newDiff that
+// contains the first line differing from oldDiff, so follow mode can
+// scroll to the most recent edit.
+func changedHunkLine(oldDiff, newDiff string) int {
+	oldLines := strings.Split(oldDiff, "\n")
+	newLines := strings.Split(newDiff, "\n")
+	first := 0
+	for first < len(oldLines) && first < len(newLines) && oldLines[first] == newLines[first] {
+		first++
+	}
+	for i := min(first, len(newLines)-1); i >= 0; i-- {
+		if strings.Contains(newLines[i], "@@ ") {
+			return i
+		}
+	}
+	return 0
+}
+
+// editorFinishedMsg reports that an editor or shell run from sidegit is
+// done. Editors running outside the TUI set note, shown with err if they
+// failed; a terminal program's exit status is its own business.
+type editorFinishedMsg struct {
+	repo string
+	note string
+	err  error
+}
+
+// terminalGitCmd runs git with the TUI suspended, so it can prompt for
+// credentials.
+func terminalGitCmd(repoPath string, args []string) tea.Cmd {
+	c := exec.Command("git", sidegit.RepoArgs(repoPath, args...)...)
+	return tea.ExecProcess(c, func(err error) tea.Msg {
+		if err != nil {
+			return gitErrorMsg{repo: repoPath, err: fmt.Errorf("git %s: %v", args[0], err), changed: true}
+		}
+		return fileChangedMsg{repo: repoPath}
+	})
+}
 
 func checkoutBranchCmd(repoPath, branch string) tea.Cmd {
+	return gitCmd(repoPath, func() error { return sidegit.CheckoutBranch(repoPath, branch) })
+}
+
+// backupCmd copies a file aside, then runs a destructive op on it. Nothing
+// is touched if the copy fails.
+func backupCmd(repoPath, filePath string, keepDays int, op func() error) tea.Cmd {
 	return func() tea.Msg {
-		if err := CheckoutBranch(repoPath, branch); err != nil {
-			return gitErrorMsg{err: err}
+		b, err := backupFile(repoPath, filePath, keepDays)
+		if err != nil {
+			return gitErrorMsg{repo: repoPath, err: fmt.Errorf("backup of %s failed, nothing changed: %w", filePath, err)}
+		}
+		if err := op(); err != nil {
+			return gitErrorMsg{repo: repoPath, err: err, changed: true}
 		}
-		return fileChangedMsg{}
+		return backedUpMsg{backup: b, repo: repoPath}
 	}
 }
 
-func gitPullCmd(repoPath string) tea.Cmd {
+func prTickCmd(d time.Duration) tea.Cmd {
+	return tea.Tick(d, func(time.Time) tea.Msg { return prTickMsg{} })
+}
+
+// checkPRsCmd looks up the open pull request of every repo on a branch,
+// a few at a time. Repos gh can't answer for (not on GitHub, no auth)
+// simply show none.
+func checkPRsCmd(repos []sidegit.Repo, workers int) tea.Cmd {
+	repos = append([]sidegit.Repo(nil), repos...)
+	return func() tea.Msg {
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+		prs := map[string]*sidegit.PullRequest{}
+		sem := make(chan struct{}, workers)
+		for _, r := range repos {
+			if r.Branch == "" || r.Unavailable {
+				continue
+			}
+			wg.Add(1)
+			sem <- struct{}{}
+			go func(r sidegit.Repo) {
+				defer func() { <-sem; wg.Done() }()
+				ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+				defer cancel()
+				pr, err := sidegit.GetPullRequest(ctx, r.Path)
+				if err != nil && debugLog != nil {
+					debugLog.Printf("pr status %s: %v", r.Path, err)
+				}
+				if pr != nil {
+					mu.Lock()
+					prs[r.Path] = pr
+					mu.Unlock()
+				}
+			}(r)
+		}
+		wg.Wait()
+		return prStatusMsg{prs: prs}
+	}
+}
+
+// gitCmd runs a git operation on repoPath in the background and refreshes
+// that repo afterwards.
+func gitCmd(repoPath string, op func() error) tea.Cmd {
 	return func() tea.Msg {
-		if err := GitPull(repoPath); err != nil {
-			return gitErrorMsg{err: err}
+		if err := op(); err != nil {
+			return gitErrorMsg{repo: repoPath, err: err, changed: true}
 		}
-		return fileChangedMsg{}
+		return fileChangedMsg{repo: repoPath}
 	}
 }
 
-func gitPushCmd(repoPath string) tea.Cmd {
+func openMenuCmd(title string, options []menuOption) tea.Cmd {
 	return func() tea.Msg {
-		if err := GitPush(repoPath); err != nil {
-			return gitErrorMsg{err: err}
+		return openMenuMsg{title: title, options: options}
+	}
+}
+
+// discardMenuCmd loads everything discard would throw away from files,
+// untracked ones whole, and asks for confirmation with it on screen. name
+// is the file or directory the menu is for.
+func discardMenuCmd(repoPath, name string, files []sidegit.FileStatus, maxLines int, discard func() tea.Cmd) tea.Cmd {
+	return func() tea.Msg {
+		var preview string
+		truncated := false
+		for _, f := range files {
+			opts := sidegit.DiffOptions{Context: 3, MaxLines: maxLines, Head: true, Color: true}
+			opts.Untracked = f.Status == sidegit.StatusUntracked
+			if maxLines > 0 {
+				opts.MaxLines = maxLines - strings.Count(preview, "\n")
+				if opts.MaxLines <= 0 {
+					truncated = true
+					break
+				}
+			}
+			d, err := sidegit.GetDiff(repoPath, f.Path, opts)
+			if err != nil {
+				preview += fmt.Sprintf("Error loading the diff of %s: %v\n", f.Path, err)
+				continue
+			}
+			preview += d.Text
+			if d.Truncated {
+				truncated = true
+				break
+			}
+		}
+		if truncated {
+			preview += fmt.Sprintf("\n… only the first %d lines are shown", maxLines)
+		}
+		return openMenuMsg{
+			title: "Discard changes to " + name,
+			options: []menuOption{
+				{key: "x", label: "Discard all changes", action: discard},
+				{label: "Cancel"},
+			},
+			preview: preview,
 		}
-		return fileChangedMsg{}
 	}
 }
 
+func cherryPickCmd(repoPath, sourcePath, hash string) tea.Cmd {
+	return gitCmd(repoPath, func() error { return sidegit.CherryPick(repoPath, sourcePath, hash) })
+}
+
+func cherryPickOntoBranchCmd(repoPath, branch, hash string) tea.Cmd {
+	return gitCmd(repoPath, func() error { return sidegit.CherryPickOntoBranch(repoPath, branch, hash) })
+}
+
+func gitPullCmd(repoPath string) tea.Cmd {
+	return jobCmd("pull "+filepath.Base(repoPath), repoPath, "pulled "+filepath.Base(repoPath), func(ctx context.Context) error { return sidegit.GitPull(ctx, repoPath) })
+}
+
+func gitPushCmd(repoPath string) tea.Cmd {
+	return jobCmd("push "+filepath.Base(repoPath), repoPath, "pushed "+filepath.Base(repoPath), func(ctx context.Context) error { return sidegit.GitPush(ctx, repoPath) })
+}
+
+func mergetoolCmd(repoPath, filePath string) tea.Cmd {
+	c := exec.Command("git", sidegit.RepoArgs(repoPath, "mergetool", "--", filePath)...)
+	return tea.ExecProcess(c, func(err error) tea.Msg {
+		return editorFinishedMsg{repo: repoPath, err: err}
+	})
+}
+
 func openInEditorCmd(repoPath, filePath string) tea.Cmd {
 	editor := os.Getenv("EDITOR")
 	if editor == "" {
@@ -727,6 +2597,6 @@ func openInEditorCmd(repoPath, filePath string) tea.Cmd {
 	args := append(parts[1:], absPath)
 	c := exec.Command(parts[0], args...)
 	return tea.ExecProcess(c, func(err error) tea.Msg {
-		return editorFinishedMsg{err: err}
+		return editorFinishedMsg{repo: repoPath, err: err}
 	})
 }
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Notes are short reminders attached to a repo or a changed file, like
+// "revert before merge". They live in the state file, never in the repo.
+
+// noteSetMsg stores a note written in the prompt.
+type noteSetMsg struct {
+	repo, file, note string
+}
+
+// nodeNoteKey returns the repo and file a node's note is kept under, and
+// false for a node that can't hold one.
+func nodeNoteKey(node *TreeNode) (string, string, bool) {
+	switch {
+	case node == nil:
+		return "", "", false
+	case node.Kind == NodeRepo:
+		return node.Repo.Path, "", true
+	case node.Kind == NodeFile:
+		return node.Repo.Path, node.File.Path, true
+	}
+	return "", "", false
+}
+
+// editNoteCmd prompts for the note on the selected repo or file, filled in
+// with the one it has; clearing the text removes it.
+func (m model) editNoteCmd() tea.Cmd {
+	node := m.tree.SelectedNode()
+	repo, file, ok := nodeNoteKey(node)
+	if !ok {
+		return nil
+	}
+	name := node.Repo.RelPath
+	if file != "" {
+		name = file
+	}
+	return promptCmd(openPromptMsg{
+		title:       "Note on " + name,
+		value:       m.state.Note(repo, file),
+		placeholder: "e.g. revert before merge (empty to remove)",
+		optional:    true,
+		onSubmit: func(note string) tea.Cmd {
+			return func() tea.Msg {
+				return noteSetMsg{repo: repo, file: file, note: strings.TrimSpace(note)}
+			}
+		},
+	})
+}
+
+// showNote opens the selected row's note in a popup, if it has one.
+func (m *model) showNote() {
+	node := m.tree.SelectedNode()
+	repo, file, ok := nodeNoteKey(node)
+	if !ok || m.state.Note(repo, file) == "" {
+		return
+	}
+	rows := [][2]string{{"Repo", node.Repo.RelPath}}
+	if file != "" {
+		rows = append(rows, [2]string{"File", file})
+	}
+	m.openInfo("Note", append(rows, [2]string{"Note", m.state.Note(repo, file)}))
+}
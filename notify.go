@@ -0,0 +1,161 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/hermanschutte/sidegit/pkg/sidegit"
+)
+
+// maxNotices is how many notifications the message log keeps.
+const maxNotices = 200
+
+// How long a notification stays in the status bar. Errors stay longer.
+const (
+	noticeTimeout = 4 * time.Second
+	errorTimeout  = 10 * time.Second
+)
+
+// notice is one entry in the message log.
+type notice struct {
+	at   time.Time
+	text string
+	err  bool
+}
+
+// clearStatusMsg clears the status bar, unless a newer notification has
+// replaced the one that scheduled it.
+type clearStatusMsg struct{ seq int }
+
+// notify shows text in the status bar for a few seconds and adds it to
+// the message log.
+func (m *model) notify(text string) tea.Cmd {
+	return m.addNotice(text, false, noticeTimeout)
+}
+
+// notifyError is notify for failures.
+func (m *model) notifyError(text string) tea.Cmd {
+	return m.addNotice(text, true, errorTimeout)
+}
+
+func (m *model) addNotice(text string, isErr bool, timeout time.Duration) tea.Cmd {
+	m.notices = append(m.notices, notice{at: time.Now(), text: text, err: isErr})
+	if len(m.notices) > maxNotices {
+		m.notices = m.notices[len(m.notices)-maxNotices:]
+	}
+	m.statusMsg = text
+	m.statusErr = ""
+	if isErr {
+		m.statusErr = text
+	}
+	m.statusSeq++
+	seq := m.statusSeq
+	return tea.Tick(timeout, func(time.Time) tea.Msg { return clearStatusMsg{seq: seq} })
+}
+
+// fetchErrorNotices notifies about background fetches that started
+// failing since the previous snapshot.
+func (m *model) fetchErrorNotices(prev, repos []sidegit.Repo) tea.Cmd {
+	before := map[string]string{}
+	for _, r := range prev {
+		before[r.Path] = r.FetchError
+	}
+	var cmds []tea.Cmd
+	for _, r := range repos {
+		if r.FetchError != "" && r.FetchError != before[r.Path] {
+			cmds = append(cmds, m.notifyError("fetch "+r.RelPath+": "+r.FetchError))
+		}
+	}
+	return tea.Batch(cmds...)
+}
+
+// watchLimited counts the repos the system's watch limit left partly
+// unwatched.
+func watchLimited(repos []sidegit.Repo) int {
+	n := 0
+	for _, r := range repos {
+		if r.WatchLimited {
+			n++
+		}
+	}
+	return n
+}
+
+// watchLimitNotice explains how to raise the watch limit once it first
+// leaves repos unwatched, or leaves more of them.
+func (m *model) watchLimitNotice(prev, repos []sidegit.Repo) tea.Cmd {
+	n := watchLimited(repos)
+	if n == 0 || n <= watchLimited(prev) {
+		return nil
+	}
+	return m.notifyError(trf("Out of file watches: %d repo(s) are polled instead. Raise the limit with sudo sysctl fs.inotify.max_user_watches=524288", n))
+}
+
+// gitNoteCmd is gitCmd with a notification once op succeeds.
+func gitNoteCmd(repoPath, note string, op func() error) tea.Cmd {
+	return func() tea.Msg {
+		if err := op(); err != nil {
+			return gitErrorMsg{repo: repoPath, err: err, changed: true}
+		}
+		return fileChangedMsg{repo: repoPath, note: note}
+	}
+}
+
+func (m *model) openMessages() {
+	m.messagesOpen = true
+	m.messagesOffset = max(0, len(m.notices)-m.messagesVisible())
+}
+
+func (m model) messagesVisible() int {
+	return max(3, m.height-6)
+}
+
+func (m model) handleMessagesKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	last := max(0, len(m.notices)-m.messagesVisible())
+	switch msg.String() {
+	case "up", "k":
+		m.messagesOffset = max(0, m.messagesOffset-1)
+	case "down", "j":
+		m.messagesOffset = min(last, m.messagesOffset+1)
+	case "pgup":
+		m.messagesOffset = max(0, m.messagesOffset-m.messagesVisible())
+	case "pgdown":
+		m.messagesOffset = min(last, m.messagesOffset+m.messagesVisible())
+	default:
+		// Any other key closes the log, like the help overlay
+		m.messagesOpen = false
+	}
+	return m, nil
+}
+
+// renderMessages renders the message log, oldest first.
+func (m model) renderMessages() string {
+	boxWidth := m.width - 2
+	innerWidth := boxWidth - 2
+	timeStyle := lipgloss.NewStyle().Foreground(themeColor(m.config.Theme.StatusBar))
+	errStyle := lipgloss.NewStyle().Foreground(themeColor(m.config.Theme.StatusConflict))
+
+	var lines []string
+	if len(m.notices) == 0 {
+		lines = append(lines, "no messages yet")
+	}
+	end := min(len(m.notices), m.messagesOffset+m.messagesVisible())
+	for _, n := range m.notices[m.messagesOffset:end] {
+		text := truncateStr(n.text, innerWidth-9)
+		if n.err {
+			text = errStyle.Render(text)
+		}
+		line := timeStyle.Render(n.at.Format("15:04:05")) + " " + text
+		if vis := lipgloss.Width(line); vis < innerWidth {
+			line += strings.Repeat(" ", innerWidth-vis)
+		}
+		lines = append(lines, line)
+	}
+
+	box := renderBorderedPanel("Messages", strings.Join(lines, "\n"), boxWidth, len(lines)+2, m.config.Theme.BorderFocused, m.config.Theme.Title)
+	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, box,
+		lipgloss.WithWhitespaceChars(" "),
+		lipgloss.WithWhitespaceForeground(lipgloss.Color("0")))
+}
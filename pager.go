@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// pagerTimeout bounds a diff_pager run, in case the command waits for
+// input or a terminal it won't get.
+const pagerTimeout = 10 * time.Second
+
+// diffPager formats diffs with the diff_pager command, like delta or
+// diff-so-fancy. The zero value leaves diffs as git colored them.
+type diffPager struct {
+	command string
+	width   int // columns of the diff panel, passed on as $COLUMNS
+}
+
+func (m model) diffPager() diffPager {
+	return diffPager{command: m.config.DiffPager, width: m.diffWidth()}
+}
+
+// page pipes a colored git diff through the pager and returns its output,
+// ANSI codes and all. On failure the diff comes back unchanged, headed by
+// the error, so a broken pager doesn't hide the changes.
+func (p diffPager) page(diff string) string {
+	if p.command == "" || diff == "" {
+		return diff
+	}
+	out, err := p.run(diff)
+	if err != nil {
+		return fmt.Sprintf("diff_pager: %v\n\n", err) + diff
+	}
+	return out
+}
+
+func (p diffPager) run(diff string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), pagerTimeout)
+	defer cancel()
+	shell, flag := "sh", "-c"
+	if runtime.GOOS == "windows" {
+		shell, flag = "cmd", "/c"
+	}
+	cmd := exec.CommandContext(ctx, shell, flag, p.command)
+	cmd.Stdin = strings.NewReader(diff)
+	cmd.Env = append(os.Environ(), fmt.Sprintf("COLUMNS=%d", p.width))
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%s", msg)
+		}
+		return "", err
+	}
+	return string(out), nil
+}
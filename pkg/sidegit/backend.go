@@ -0,0 +1,377 @@
+package sidegit
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// GitBackend runs the git queries made on every refresh and diff view.
+// These are the hot paths, so implementations should keep the number of
+// git processes per call to a minimum.
+type GitBackend interface {
+	Status(repoPath string) (GitStatus, error)
+	Diff(repoPath, filePath string, opts DiffOptions) (Diff, error)
+	RepoDiff(repoPath string, opts DiffOptions) (Diff, error)
+	// Query runs a short read-only git command in the repo, such as
+	// rev-parse or describe, and returns its output.
+	Query(repoPath string, args ...string) (string, error)
+}
+
+// Backend is the GitBackend used by GetStatus, GetDiff, GetRepoDiff and
+// the scanner.
+var Backend GitBackend = ExecBackend{}
+
+// ErrTimeout is returned when git takes longer than the backend timeout,
+// typically because the repo lives on a stalled network filesystem.
+var ErrTimeout = errors.New("git timed out")
+
+// ExecBackend runs the git binary on PATH.
+type ExecBackend struct {
+	Timeout time.Duration // per git call, 0 for none
+	// Untracked is the --untracked-files mode of git status: "all" (the
+	// default when empty), "normal" or "no". "repo" leaves it to each
+	// repo's status.showUntrackedFiles, like a plain git status; that is
+	// always the mode for a BareRepo.
+	Untracked string
+}
+
+// Status runs a single `git status`; its porcelain v2 branch headers carry
+// the branch, head commit and upstream, so no rev-parse calls are needed.
+func (b ExecBackend) Status(repoPath string) (GitStatus, error) {
+	ctx, cancel := b.context()
+	defer cancel()
+	args := RepoArgs(repoPath, "status", "--porcelain=v2", "--branch")
+	mode := b.Untracked
+	if _, bare := lookupBareRepo(repoPath); bare {
+		// A dotfiles repo's work tree is the home directory; it relies on
+		// status.showUntrackedFiles=no to keep status usable
+		mode = "repo"
+	}
+	switch mode {
+	case "":
+		args = append(args, "--untracked-files=all")
+	case "repo":
+	default:
+		args = append(args, "--untracked-files="+b.Untracked)
+	}
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.WaitDelay = time.Second // don't wait on a killed git's stuck pipes
+	out, err := cmd.Output()
+	if ctx.Err() == context.DeadlineExceeded {
+		return GitStatus{}, ErrTimeout
+	}
+	if err != nil {
+		return GitStatus{}, fmt.Errorf("git status failed: %w", err)
+	}
+	return parseStatus(string(out)), nil
+}
+
+// context bounds one call by b.Timeout.
+func (b ExecBackend) context() (context.Context, context.CancelFunc) {
+	if b.Timeout > 0 {
+		return context.WithTimeout(context.Background(), b.Timeout)
+	}
+	return context.WithCancel(context.Background())
+}
+
+func (b ExecBackend) Query(repoPath string, args ...string) (string, error) {
+	ctx, cancel := b.context()
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "git", RepoArgs(repoPath, args...)...)
+	cmd.WaitDelay = time.Second
+	out, err := cmd.Output()
+	if ctx.Err() == context.DeadlineExceeded {
+		return "", ErrTimeout
+	}
+	return string(out), err
+}
+
+func parseStatus(out string) GitStatus {
+	var result GitStatus
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "# branch.head ") {
+			result.Branch = strings.TrimPrefix(line, "# branch.head ")
+			if result.Branch == "(detached)" {
+				result.Branch = "HEAD"
+			}
+			continue
+		}
+		if strings.HasPrefix(line, "# branch.oid ") {
+			if oid := strings.TrimPrefix(line, "# branch.oid "); oid != "(initial)" {
+				result.Head = oid
+			}
+			continue
+		}
+		if strings.HasPrefix(line, "# branch.upstream ") {
+			result.Upstream = strings.TrimPrefix(line, "# branch.upstream ")
+			continue
+		}
+		if strings.HasPrefix(line, "# branch.ab ") {
+			fmt.Sscanf(line, "# branch.ab +%d -%d", &result.Ahead, &result.Behind)
+			continue
+		}
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "1 ") || strings.HasPrefix(line, "2 ") {
+			// The sub field is "S<c><m><u>" for submodules; any flag set
+			// means the submodule itself has changes
+			if fields := strings.Fields(line); len(fields) > 2 && fields[2][0] == 'S' && fields[2] != "S..." {
+				result.DirtySubmodules++
+			}
+			fs := parseOrdinaryEntry(line)
+			if fs != nil {
+				result.Files = append(result.Files, *fs)
+			}
+		} else if strings.HasPrefix(line, "u ") {
+			// Format: u XY sub m1 m2 m3 mW h1 h2 h3 path
+			fields := strings.Fields(line)
+			if len(fields) >= 11 {
+				result.Files = append(result.Files, FileStatus{
+					Path:   fields[len(fields)-1],
+					Status: StatusConflict,
+				})
+			}
+		} else if strings.HasPrefix(line, "? ") {
+			// Without --untracked-files=all a wholly untracked directory
+			// is one entry, "dir/"
+			path := strings.TrimSuffix(line[2:], "/")
+			result.Files = append(result.Files, FileStatus{
+				Path:   path,
+				Status: StatusUntracked,
+			})
+		}
+	}
+	return result
+}
+
+// Diff tries the unstaged diff first since that is the common case, and
+// only asks git whether the file is tracked when both diffs come up empty.
+func (b ExecBackend) Diff(repoPath, filePath string, opts DiffOptions) (Diff, error) {
+	ctx, cancel := b.context()
+	defer cancel()
+	d, err := b.diff(ctx, repoPath, filePath, opts)
+	if ctx.Err() == context.DeadlineExceeded {
+		return Diff{}, ErrTimeout
+	}
+	return d, err
+}
+
+func (ExecBackend) diff(ctx context.Context, repoPath, filePath string, opts DiffOptions) (Diff, error) {
+	absFile := filepath.Join(repoPath, filePath)
+
+	diffArgs := func(extra ...string) []string {
+		args := RepoArgs(repoPath, append([]string{"diff"}, extra...)...)
+		args = append(args, opts.args()...)
+		return append(args, "--")
+	}
+	untrackedDiff := func() (Diff, error) {
+		if info, err := os.Stat(absFile); err == nil && info.IsDir() {
+			// --untracked-files=normal lists whole directories
+			return Diff{Text: "(new untracked directory)"}, nil
+		}
+		// Untracked file — diff against the null device (NUL on Windows).
+		// --no-index exits 1 when the files differ, so errors are ignored
+		d, _ := readDiff(ctx, opts, append(diffArgs("--no-index"), os.DevNull, absFile)...)
+		if d.Text == "" {
+			d.Text = "(new untracked file)"
+		}
+		return d, nil
+	}
+
+	if opts.Untracked {
+		return untrackedDiff()
+	}
+	if opts.Head {
+		// Fails before the first commit; the diffs below still work then
+		if d, err := readDiff(ctx, opts, append(diffArgs("HEAD"), filePath)...); err == nil && d.Text != "" {
+			return d, nil
+		}
+	}
+
+	// Tracked file — normal diff
+	d, err := readDiff(ctx, opts, append(diffArgs(), filePath)...)
+	if err != nil {
+		return Diff{}, fmt.Errorf("git diff failed: %w", err)
+	}
+	if d.Text != "" {
+		return d, nil
+	}
+	// Maybe staged — try diff --cached
+	d, err = readDiff(ctx, opts, append(diffArgs("--cached"), filePath)...)
+	if err != nil {
+		return Diff{}, fmt.Errorf("git diff --cached failed: %w", err)
+	}
+	if d.Text != "" {
+		return d, nil
+	}
+	cmd := exec.CommandContext(ctx, "git", RepoArgs(repoPath, "ls-files", "--error-unmatch", filePath)...)
+	if err := cmd.Run(); err != nil {
+		return untrackedDiff()
+	}
+	return Diff{Text: "(no changes)"}, nil
+}
+
+func (b ExecBackend) RepoDiff(repoPath string, opts DiffOptions) (Diff, error) {
+	ctx, cancel := b.context()
+	defer cancel()
+	d, err := b.repoDiff(ctx, repoPath, opts)
+	if ctx.Err() == context.DeadlineExceeded {
+		return Diff{}, ErrTimeout
+	}
+	return d, err
+}
+
+func (b ExecBackend) repoDiff(ctx context.Context, repoPath string, opts DiffOptions) (Diff, error) {
+	base := "HEAD"
+	if exec.CommandContext(ctx, "git", RepoArgs(repoPath, "rev-parse", "--verify", "-q", base)...).Run() != nil {
+		// No commits yet: everything is new
+		empty, err := emptyTree(repoPath)
+		if err != nil {
+			return Diff{}, err
+		}
+		base = empty
+	}
+	args := RepoArgs(repoPath, append([]string{"diff"}, opts.args()...)...)
+	d, err := readDiff(ctx, opts, append(args, base, "--")...)
+	if err != nil {
+		return Diff{}, fmt.Errorf("git diff failed: %w", err)
+	}
+	if b.showUntracked(ctx, repoPath) {
+		out, err := exec.CommandContext(ctx, "git", RepoArgs(repoPath, "ls-files", "--others", "--exclude-standard", "-z")...).Output()
+		if err != nil {
+			return Diff{}, fmt.Errorf("git ls-files failed: %v", err)
+		}
+		for _, name := range strings.Split(string(out), "\x00") {
+			if name == "" || d.Truncated {
+				continue
+			}
+			rest := opts
+			if opts.MaxLines > 0 {
+				rest.MaxLines = opts.MaxLines - strings.Count(d.Text, "\n")
+				if rest.MaxLines <= 0 {
+					d.Truncated = true
+					continue
+				}
+			}
+			// --no-index exits 1 when the files differ, so errors are ignored
+			u, _ := readDiff(ctx, rest, append(args, "--no-index", "--", os.DevNull, name)...)
+			d.Text += u.Text
+			d.Files = append(d.Files, u.Files...)
+			d.Truncated = u.Truncated
+		}
+	}
+	if d.Text == "" {
+		d = Diff{Text: "(no changes)"}
+	}
+	return d, nil
+}
+
+// showUntracked reports whether the repo's untracked files belong in its
+// diff, going by the same mode as Status. A BareRepo's never do: its work
+// tree is usually the home directory.
+func (b ExecBackend) showUntracked(ctx context.Context, repoPath string) bool {
+	if _, bare := lookupBareRepo(repoPath); bare {
+		return false
+	}
+	switch b.Untracked {
+	case "no":
+		return false
+	case "repo":
+		out, _ := exec.CommandContext(ctx, "git", RepoArgs(repoPath, "config", "status.showUntrackedFiles")...).Output()
+		return strings.TrimSpace(string(out)) != "no"
+	}
+	return true
+}
+
+// readDiff streams git's output and stops after opts.MaxLines lines, so a
+// huge generated file never has to be read (or rendered) in full.
+func readDiff(ctx context.Context, opts DiffOptions, args ...string) (Diff, error) {
+	maxLines := opts.MaxLines
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.WaitDelay = time.Second
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return Diff{}, err
+	}
+	if err := cmd.Start(); err != nil {
+		return Diff{}, err
+	}
+	var b strings.Builder
+	var d Diff
+	r := bufio.NewReader(stdout)
+	for n := 0; ; n++ {
+		if maxLines > 0 && n == maxLines {
+			if _, err := r.Peek(1); err == nil {
+				d.Truncated = true
+				_ = cmd.Process.Kill()
+			}
+			break
+		}
+		line, err := r.ReadString('\n')
+		b.WriteString(line)
+		if err != nil {
+			break
+		}
+	}
+	d.Text = b.String()
+	if !opts.Color {
+		d.Files = ParseDiff(d.Text)
+	}
+	if err := cmd.Wait(); err != nil && !d.Truncated {
+		return d, err
+	}
+	return d, nil
+}
+
+// LogBackend wraps b so every call is logged with its duration.
+func LogBackend(b GitBackend, l *log.Logger) GitBackend {
+	return logBackend{b, l}
+}
+
+type logBackend struct {
+	GitBackend
+	log *log.Logger
+}
+
+func (b logBackend) Status(repoPath string) (GitStatus, error) {
+	start := time.Now()
+	st, err := b.GitBackend.Status(repoPath)
+	b.log.Printf("git status %s: %v (err: %v)", repoPath, time.Since(start).Round(time.Microsecond), err)
+	return st, err
+}
+
+func (b logBackend) Diff(repoPath, filePath string, opts DiffOptions) (Diff, error) {
+	start := time.Now()
+	d, err := b.GitBackend.Diff(repoPath, filePath, opts)
+	b.log.Printf("git diff %s %s: %v, %d bytes, truncated %v (err: %v)", repoPath, filePath, time.Since(start).Round(time.Microsecond), len(d.Text), d.Truncated, err)
+	return d, err
+}
+
+func (b logBackend) RepoDiff(repoPath string, opts DiffOptions) (Diff, error) {
+	start := time.Now()
+	d, err := b.GitBackend.RepoDiff(repoPath, opts)
+	b.log.Printf("git diff %s: %v, %d bytes, truncated %v (err: %v)", repoPath, time.Since(start).Round(time.Microsecond), len(d.Text), d.Truncated, err)
+	return d, err
+}
+
+func (b logBackend) Query(repoPath string, args ...string) (string, error) {
+	start := time.Now()
+	out, err := b.GitBackend.Query(repoPath, args...)
+	b.log.Printf("git %s %s: %v (err: %v)", strings.Join(args, " "), repoPath, time.Since(start).Round(time.Microsecond), err)
+	return out, err
+}
@@ -0,0 +1,79 @@
+package sidegit
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// BareRepo is a repo whose git directory lives apart from its work tree,
+// like a dotfiles repo made with `git init --bare ~/.dotfiles` and used as
+// `git --git-dir=$HOME/.dotfiles --work-tree=$HOME`.
+type BareRepo struct {
+	GitDir   string
+	WorkTree string
+	Name     string // display name, defaults to the git dir's name without a leading dot
+}
+
+// bareRepos holds the BareRepos found by a scan, by work tree. Every git
+// call for a repo path looks here, so it has to be package state.
+var bareRepos sync.Map
+
+// addBareRepo makes b known to RepoArgs and GitDir. It reports false if
+// b.GitDir isn't a git directory.
+func addBareRepo(b BareRepo) (BareRepo, bool) {
+	var err error
+	if b.GitDir, err = filepath.Abs(b.GitDir); err != nil {
+		return b, false
+	}
+	if b.WorkTree, err = filepath.Abs(b.WorkTree); err != nil {
+		return b, false
+	}
+	if _, err := os.Stat(filepath.Join(b.GitDir, "HEAD")); err != nil {
+		return b, false
+	}
+	if b.Name == "" {
+		b.Name = strings.TrimPrefix(strings.TrimSuffix(filepath.Base(b.GitDir), ".git"), ".")
+	}
+	bareRepos.Store(b.WorkTree, b)
+	return b, true
+}
+
+func lookupBareRepo(repoPath string) (BareRepo, bool) {
+	v, ok := bareRepos.Load(repoPath)
+	if !ok {
+		return BareRepo{}, false
+	}
+	return v.(BareRepo), true
+}
+
+// RepoArgs prefixes a git command line with what git needs to find the
+// repo at repoPath: -C, plus --git-dir and --work-tree for a BareRepo. It
+// also turns off core.quotePath, so paths that aren't ASCII come out as
+// they are rather than as octal escapes.
+func RepoArgs(repoPath string, args ...string) []string {
+	pre := []string{"-c", "core.quotePath=false", "-C", repoPath}
+	if b, ok := lookupBareRepo(repoPath); ok {
+		pre = append(pre, "--git-dir="+b.GitDir, "--work-tree="+b.WorkTree)
+	}
+	return append(pre, args...)
+}
+
+// RepoEnv is RepoArgs for a shell command run in the repo at repoPath:
+// GIT_DIR and GIT_WORK_TREE for a BareRepo, so the git commands in it find
+// the repo, and nothing for any other.
+func RepoEnv(repoPath string) []string {
+	if b, ok := lookupBareRepo(repoPath); ok {
+		return []string{"GIT_DIR=" + b.GitDir, "GIT_WORK_TREE=" + b.WorkTree}
+	}
+	return nil
+}
+
+// GitDir returns the git directory of the repo at repoPath.
+func GitDir(repoPath string) string {
+	if b, ok := lookupBareRepo(repoPath); ok {
+		return b.GitDir
+	}
+	return filepath.Join(repoPath, ".git")
+}
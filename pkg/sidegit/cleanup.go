@@ -0,0 +1,114 @@
+package sidegit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// StaleRemoteBranches lists the remote-tracking branches of remote whose
+// branch is gone on the remote, which PruneRemote would delete.
+func StaleRemoteBranches(repoPath, remote string) ([]string, error) {
+	out, err := runRemoteOutput(context.Background(), repoPath, "remote", "prune", "--dry-run", remote)
+	if err != nil {
+		return nil, err
+	}
+	return prunedRefs(out), nil
+}
+
+// PruneRemote deletes the remote-tracking branches of remote that no
+// longer exist there, and returns their names.
+func PruneRemote(repoPath, remote string) ([]string, error) {
+	out, err := runRemoteOutput(context.Background(), repoPath, "remote", "prune", remote)
+	if err != nil {
+		return nil, err
+	}
+	return prunedRefs(out), nil
+}
+
+// prunedRefs picks the ref names out of `git remote prune` output, whose
+// lines look like " * [pruned] origin/topic" (or "[would prune]").
+func prunedRefs(out string) []string {
+	var refs []string
+	for _, line := range strings.Split(out, "\n") {
+		tag, ref, ok := strings.Cut(line, "] ")
+		if ok && strings.Contains(tag, "prune") {
+			refs = append(refs, strings.TrimSpace(ref))
+		}
+	}
+	return refs
+}
+
+// MergedBranches lists the local branches that are fully merged into
+// HEAD, other than the checked-out one.
+func MergedBranches(repoPath string) ([]string, error) {
+	cmd := exec.Command("git", RepoArgs(repoPath, "branch", "--merged", "HEAD", "--format=%(HEAD) %(refname:short)")...)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("git branch --merged: %s", out)
+	}
+	var branches []string
+	for _, line := range strings.Split(string(out), "\n") {
+		// %(HEAD) is "*" on the checked-out branch and a space elsewhere
+		if len(line) < 3 || line[0] == '*' {
+			continue
+		}
+		if name := line[2:]; !strings.HasPrefix(name, "(") {
+			branches = append(branches, name)
+		}
+	}
+	return branches, nil
+}
+
+// DeleteBranches deletes merged local branches with `git branch -d`, so
+// a branch with unmerged commits is refused rather than lost. It returns
+// the branches it deleted along with the errors of the others.
+func DeleteBranches(repoPath string, branches []string) ([]string, error) {
+	var deleted []string
+	var errs []error
+	for _, b := range branches {
+		out, err := exec.Command("git", RepoArgs(repoPath, "branch", "-d", b)...).CombinedOutput()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("git branch -d %s: %s", b, strings.TrimSpace(string(out))))
+			continue
+		}
+		deleted = append(deleted, b)
+	}
+	return deleted, errors.Join(errs...)
+}
+
+// ObjectsSize returns the disk space taken by the repo's objects, loose
+// and packed, in bytes.
+func ObjectsSize(repoPath string) (int64, error) {
+	out, err := exec.Command("git", RepoArgs(repoPath, "count-objects", "-v")...).Output()
+	if err != nil {
+		return 0, fmt.Errorf("git count-objects: %v", err)
+	}
+	var kib int64
+	for _, line := range strings.Split(string(out), "\n") {
+		key, value, ok := strings.Cut(line, ": ")
+		if !ok || (key != "size" && key != "size-pack" && key != "size-garbage") {
+			continue
+		}
+		n, _ := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		kib += n
+	}
+	return kib * 1024, nil
+}
+
+// GarbageCollect runs `git gc` and returns the object sizes before and
+// after it.
+func GarbageCollect(repoPath string) (before, after int64, err error) {
+	before, err = ObjectsSize(repoPath)
+	if err != nil {
+		return 0, 0, err
+	}
+	if out, err := exec.Command("git", RepoArgs(repoPath, "gc", "--quiet")...).CombinedOutput(); err != nil {
+		return before, 0, fmt.Errorf("git gc: %s", strings.TrimSpace(string(out)))
+	}
+	after, err = ObjectsSize(repoPath)
+	return before, after, err
+}
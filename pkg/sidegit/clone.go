@@ -0,0 +1,83 @@
+package sidegit
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// readCloneLayout fills in whether the repo is shallow, sparse or a
+// partial clone. Like CheckHealth it only reads files under .git.
+func readCloneLayout(r *Repo) {
+	gitDir := GitDir(r.Path)
+	if _, err := os.Stat(filepath.Join(gitDir, "shallow")); err == nil {
+		r.Shallow = true
+	}
+	// git sparse-checkout writes its settings to config.worktree
+	for _, name := range []string{"config", "config.worktree"} {
+		f, err := os.Open(filepath.Join(gitDir, name))
+		if err != nil {
+			continue
+		}
+		sc := bufio.NewScanner(f)
+		for sc.Scan() {
+			// Keys are case-insensitive and may have spaces around the "="
+			line := strings.ToLower(strings.Join(strings.Fields(sc.Text()), ""))
+			switch {
+			case line == "sparsecheckout=true":
+				r.Sparse = true
+			case line == "promisor=true", strings.HasPrefix(line, "partialclonefilter="):
+				r.Partial = true
+			}
+		}
+		f.Close()
+	}
+}
+
+// SparsePatterns returns the patterns (or, in cone mode, directories) a
+// sparse checkout is limited to.
+func SparsePatterns(repoPath string) []string {
+	out, err := exec.Command("git", RepoArgs(repoPath, "sparse-checkout", "list")...).Output()
+	if err != nil {
+		return nil
+	}
+	var patterns []string
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		if line != "" {
+			patterns = append(patterns, line)
+		}
+	}
+	return patterns
+}
+
+// PartialCloneFilter returns the object filter a partial clone was made
+// with, like "blob:none", or "" if none is recorded.
+func PartialCloneFilter(repoPath string) string {
+	out, _ := exec.Command("git", RepoArgs(repoPath, "config", "--get-regexp", `^remote\..*\.partialclonefilter$`)...).Output()
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		if _, filter, ok := strings.Cut(line, " "); ok {
+			return filter
+		}
+	}
+	return ""
+}
+
+// HistoryDepth counts the commits reachable from HEAD, which in a shallow
+// clone is how much history was fetched. It returns 0 on failure.
+func HistoryDepth(repoPath string) int {
+	out, err := exec.Command("git", RepoArgs(repoPath, "rev-list", "--count", "HEAD")...).Output()
+	if err != nil {
+		return 0
+	}
+	n, _ := strconv.Atoi(strings.TrimSpace(string(out)))
+	return n
+}
+
+// GitUnshallow fetches the rest of a shallow clone's history.
+func GitUnshallow(ctx context.Context, repoPath string) error {
+	return runRemote(ctx, repoPath, "fetch", "--unshallow")
+}
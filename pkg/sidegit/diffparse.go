@@ -0,0 +1,160 @@
+package sidegit
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// LineKind says what a line of a hunk is.
+type LineKind int
+
+const (
+	LineContext   LineKind = iota // unchanged
+	LineAdded                     // "+"
+	LineRemoved                   // "-"
+	LineNoNewline                 // "\ No newline at end of file"
+)
+
+// DiffLine is a line of a hunk, without its "+", "-" or " " prefix.
+type DiffLine struct {
+	Kind     LineKind
+	Text     string
+	Old, New int // line numbers in the old and new file, 0 on a side the line isn't on
+}
+
+// Hunk is a hunk header and the lines under it.
+type Hunk struct {
+	Header             string // "@@ -12,5 +12,7 @@ func main() {"
+	OldStart, NewStart int
+	Lines              []DiffLine
+}
+
+// FileDiff is one file's part of a diff.
+type FileDiff struct {
+	Header  []string // "diff --git …", "index …", "--- a/…", "+++ b/…" and the like
+	OldPath string   // "" for a new file
+	NewPath string   // "" for a deleted file
+	Hunks   []Hunk
+}
+
+// Path returns the file's path: NewPath, or OldPath for a deleted file.
+func (f FileDiff) Path() string {
+	if f.NewPath == "" {
+		return f.OldPath
+	}
+	return f.NewPath
+}
+
+// Len returns the number of lines f takes in the diff it came from.
+func (f FileDiff) Len() int {
+	n := len(f.Header)
+	for _, h := range f.Hunks {
+		n += 1 + len(h.Lines)
+	}
+	return n
+}
+
+// hunkHeader matches "@@ -12,5 +12,7 @@", capturing where the hunk starts
+// in the old and the new file.
+var hunkHeader = regexp.MustCompile(`^@@ -(\d+)(?:,\d+)? \+(\d+)(?:,\d+)? @@`)
+
+// ParseDiff reads the output of git diff, without colors, into files,
+// hunks and lines. Every line of text ends up in exactly one place, so
+// the parts add up to the text line by line. Lines a hunk can't hold, as
+// in the combined diff of a conflict, go in the file header.
+func ParseDiff(text string) []FileDiff {
+	text = strings.TrimSuffix(text, "\n")
+	if text == "" {
+		return nil
+	}
+	var files []FileDiff
+	var f *FileDiff
+	var h *Hunk
+	o, n := 0, 0
+	for _, line := range strings.Split(text, "\n") {
+		if strings.HasPrefix(line, "diff ") || f == nil {
+			files = append(files, FileDiff{})
+			f, h = &files[len(files)-1], nil
+		}
+		if m := hunkHeader.FindStringSubmatch(line); m != nil {
+			o, _ = strconv.Atoi(m[1])
+			n, _ = strconv.Atoi(m[2])
+			f.Hunks = append(f.Hunks, Hunk{Header: line, OldStart: o, NewStart: n})
+			h = &f.Hunks[len(f.Hunks)-1]
+			continue
+		}
+		if h != nil {
+			l := DiffLine{}
+			switch {
+			case strings.HasPrefix(line, "+"):
+				l.Kind, l.New = LineAdded, n
+				n++
+			case strings.HasPrefix(line, "-"):
+				l.Kind, l.Old = LineRemoved, o
+				o++
+			case strings.HasPrefix(line, " "), line == "":
+				l.Kind, l.Old, l.New = LineContext, o, n
+				o++
+				n++
+			case strings.HasPrefix(line, `\`):
+				l.Kind = LineNoNewline
+			default:
+				h = nil
+			}
+			if h != nil {
+				if l.Kind != LineNoNewline && line != "" {
+					line = line[1:]
+				}
+				l.Text = line
+				h.Lines = append(h.Lines, l)
+				continue
+			}
+		}
+		if len(f.Hunks) > 0 {
+			// Header lines come before the hunks; anything else after
+			// them starts a part git didn't mark with "diff "
+			files = append(files, FileDiff{})
+			f = &files[len(files)-1]
+		}
+		f.Header = append(f.Header, line)
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			// Only a guess, for a file without "---" and "+++" lines like
+			// an empty or binary one; they come later and override it
+			f.OldPath, f.NewPath = gitDiffPath(line[len("diff --git "):])
+		case strings.HasPrefix(line, "new file mode "):
+			f.OldPath = ""
+		case strings.HasPrefix(line, "deleted file mode "):
+			f.NewPath = ""
+		case strings.HasPrefix(line, "--- "):
+			f.OldPath = diffPath(line[4:], "a/")
+		case strings.HasPrefix(line, "+++ "):
+			f.NewPath = diffPath(line[4:], "b/")
+		}
+	}
+	return files
+}
+
+// diffPath strips the "a/" or "b/" prefix git puts on the paths in "---"
+// and "+++" lines; /dev/null, for a file missing on that side, is "".
+func diffPath(p, prefix string) string {
+	if p == "/dev/null" {
+		return ""
+	}
+	return strings.TrimPrefix(p, prefix)
+}
+
+// gitDiffPath reads the path from the "a/… b/…" of a "diff --git" line.
+// Paths can hold spaces, so only the same path on both sides is certain.
+func gitDiffPath(names string) (string, string) {
+	n := (len(names) - len("a/ b/")) / 2
+	if n <= 0 || !strings.HasPrefix(names, "a/") {
+		return "", ""
+	}
+	p := names[2 : 2+n]
+	if names[2+n:] != " b/"+p {
+		return "", ""
+	}
+	return p, p
+}
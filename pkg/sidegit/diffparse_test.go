@@ -0,0 +1,185 @@
+package sidegit
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseDiff(t *testing.T) {
+	type hunk struct {
+		oldStart, newStart int
+		kinds              string // one letter per line: c, a, r or n
+	}
+	type file struct {
+		oldPath, newPath string
+		hunks            []hunk
+	}
+	tests := []struct {
+		name string
+		diff string
+		want []file
+	}{
+		{
+			name: "empty",
+			diff: "",
+		},
+		{
+			name: "modified",
+			diff: `diff --git a/f.txt b/f.txt
+index 1111111..2222222 100644
+--- a/f.txt
++++ b/f.txt
+@@ -1,3 +1,3 @@
+ one
+-two
++TWO
+ three
+@@ -10,2 +10,3 @@ func main() {
+ ten
++ten and a half
+
+`,
+			want: []file{{"f.txt", "f.txt", []hunk{{1, 1, "crac"}, {10, 10, "cac"}}}},
+		},
+		{
+			name: "new and deleted",
+			diff: `diff --git a/new.txt b/new.txt
+new file mode 100644
+index 0000000..1111111
+--- /dev/null
++++ b/new.txt
+@@ -0,0 +1 @@
++hello
+\ No newline at end of file
+diff --git a/old.txt b/old.txt
+deleted file mode 100644
+index 1111111..0000000
+--- a/old.txt
++++ /dev/null
+@@ -1 +0,0 @@
+-bye
+`,
+			want: []file{
+				{"", "new.txt", []hunk{{0, 1, "an"}}},
+				{"old.txt", "", []hunk{{1, 0, "r"}}},
+			},
+		},
+		{
+			name: "empty and binary files",
+			diff: `diff --git a/with space.txt b/with space.txt
+new file mode 100644
+index 0000000..e69de29
+diff --git a/img.png b/img.png
+index 1111111..2222222 100644
+Binary files a/img.png and b/img.png differ
+`,
+			want: []file{{"", "with space.txt", nil}, {"img.png", "img.png", nil}},
+		},
+		{
+			name: "renamed",
+			diff: `diff --git a/a.go b/b.go
+similarity index 90%
+rename from a.go
+rename to b.go
+index 1111111..2222222 100644
+--- a/a.go
++++ b/b.go
+@@ -3 +3 @@
+-x
++y
+`,
+			want: []file{{"a.go", "b.go", []hunk{{3, 3, "ra"}}}},
+		},
+		{
+			name: "untracked file diffed with --no-index",
+			diff: `diff --git a/u.go b/u.go
+new file mode 100644
+index 0000000..1111111
+--- /dev/null
++++ b/u.go
+@@ -0,0 +1,2 @@
++package u
++
+`,
+			want: []file{{"", "u.go", []hunk{{0, 1, "aa"}}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files := ParseDiff(tt.diff)
+			if len(files) != len(tt.want) {
+				t.Fatalf("got %d files, want %d", len(files), len(tt.want))
+			}
+			lines := 0
+			for i, f := range files {
+				w := tt.want[i]
+				lines += f.Len()
+				if f.OldPath != w.oldPath || f.NewPath != w.newPath {
+					t.Errorf("file %d: paths %q, %q, want %q, %q", i, f.OldPath, f.NewPath, w.oldPath, w.newPath)
+				}
+				if len(f.Hunks) != len(w.hunks) {
+					t.Errorf("file %d: got %d hunks, want %d", i, len(f.Hunks), len(w.hunks))
+					continue
+				}
+				for j, h := range f.Hunks {
+					var kinds strings.Builder
+					for _, l := range h.Lines {
+						kinds.WriteByte("carn"[l.Kind])
+					}
+					if h.OldStart != w.hunks[j].oldStart || h.NewStart != w.hunks[j].newStart || kinds.String() != w.hunks[j].kinds {
+						t.Errorf("file %d hunk %d: -%d +%d %s, want -%d +%d %s", i, j,
+							h.OldStart, h.NewStart, kinds.String(), w.hunks[j].oldStart, w.hunks[j].newStart, w.hunks[j].kinds)
+					}
+				}
+			}
+			// Every line of the text ends up somewhere
+			if want := len(strings.Split(strings.TrimSuffix(tt.diff, "\n"), "\n")); tt.diff != "" && lines != want {
+				t.Errorf("parts add up to %d lines, want %d", lines, want)
+			}
+		})
+	}
+}
+
+func TestParseDiffLineNumbers(t *testing.T) {
+	files := ParseDiff(`diff --git a/f b/f
+--- a/f
++++ b/f
+@@ -5,3 +5,3 @@
+ a
+-b
++B
+ c
+`)
+	want := []DiffLine{
+		{LineContext, "a", 5, 5},
+		{LineRemoved, "b", 6, 0},
+		{LineAdded, "B", 0, 6},
+		{LineContext, "c", 7, 7},
+	}
+	got := files[0].Hunks[0].Lines
+	if len(got) != len(want) {
+		t.Fatalf("got %d lines, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("line %d: %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestGitDiffPath(t *testing.T) {
+	tests := []struct {
+		names, want string
+	}{
+		{"a/f.txt b/f.txt", "f.txt"},
+		{"a/with space b/with space", "with space"},
+		{"a/x b/y", ""},
+		{"a/ b/", ""},
+		{"x/f y/f", ""},
+	}
+	for _, tt := range tests {
+		if old, new := gitDiffPath(tt.names); old != tt.want || new != tt.want {
+			t.Errorf("gitDiffPath(%q) = %q, %q, want %q", tt.names, old, new, tt.want)
+		}
+	}
+}
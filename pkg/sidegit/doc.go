@@ -0,0 +1,17 @@
+// Package sidegit is the multi-repo status engine behind the sidegit TUI.
+//
+// ScanRepos finds the git repos below a directory and reports their branch,
+// ahead/behind counts and changed files. Watch keeps that state current in
+// the background, driven by a file watcher and polling, and publishes a
+// fresh snapshot on Service.Updates after every scan:
+//
+//	s := sidegit.Watch(root, sidegit.Options{PollInterval: 10 * time.Second})
+//	defer s.Stop()
+//	for repos := range s.Updates() {
+//		...
+//	}
+//
+// The git helpers (GetStatus, GetDiff, CherryPick and friends) shell out to
+// the git binary on PATH. The per-refresh queries go through Backend, which
+// can be swapped for another GitBackend implementation.
+package sidegit
@@ -0,0 +1,783 @@
+package sidegit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type StatusCode string
+
+const (
+	StatusModified  StatusCode = "M"
+	StatusAdded     StatusCode = "A"
+	StatusDeleted   StatusCode = "D"
+	StatusRenamed   StatusCode = "R"
+	StatusCopied    StatusCode = "C"
+	StatusUntracked StatusCode = "?"
+	StatusConflict  StatusCode = "U"
+)
+
+type FileStatus struct {
+	Path     string
+	Status   StatusCode
+	IsStaged bool
+	ModTime  time.Time // last modified on disk; zero for a deleted file
+}
+
+func FindBranch(repoPath string) string {
+	out, err := Backend.Query(repoPath, "rev-parse", "--abbrev-ref", "HEAD")
+	if err == nil {
+		return strings.TrimSpace(out)
+	}
+
+	// Fallback for repos with no commits: read .git/HEAD directly
+	data, err := os.ReadFile(filepath.Join(GitDir(repoPath), "HEAD"))
+	if err != nil {
+		return "unknown"
+	}
+	ref := strings.TrimSpace(string(data))
+	if strings.HasPrefix(ref, "ref: refs/heads/") {
+		return strings.TrimPrefix(ref, "ref: refs/heads/")
+	}
+	return ref
+}
+
+type GitStatus struct {
+	Branch          string // "HEAD" when detached
+	Head            string // commit hash, empty before the first commit
+	Files           []FileStatus
+	Upstream        string
+	Ahead           int
+	Behind          int
+	DirtySubmodules int
+}
+
+// GetStatus reads the branch and working tree status of a repo.
+func GetStatus(repoPath string) (GitStatus, error) {
+	return Backend.Status(repoPath)
+}
+
+func parseOrdinaryEntry(line string) *FileStatus {
+	// Format: 1 XY sub mH mI mW hH hI path
+	// or:     2 XY sub mH mI mW hH hI X### origPath\tpath
+	fields := strings.Fields(line)
+	if len(fields) < 9 {
+		return nil
+	}
+
+	xy := fields[1]
+	stagedCode := xy[0]
+	unstagedCode := xy[1]
+
+	var path string
+	if fields[0] == "2" {
+		// Rename entry — path is in the last field after tab
+		lastField := fields[len(fields)-1]
+		parts := strings.SplitN(lastField, "\t", 2)
+		if len(parts) == 2 {
+			path = parts[1]
+		} else {
+			path = lastField
+		}
+	} else {
+		path = fields[len(fields)-1]
+	}
+
+	// Prefer showing unstaged changes; if none, show staged
+	if unstagedCode != '.' {
+		return &FileStatus{
+			Path:   path,
+			Status: mapStatusByte(unstagedCode),
+		}
+	}
+	if stagedCode != '.' {
+		return &FileStatus{
+			Path:     path,
+			Status:   mapStatusByte(stagedCode),
+			IsStaged: true,
+		}
+	}
+
+	return nil
+}
+
+func mapStatusByte(b byte) StatusCode {
+	switch b {
+	case 'M':
+		return StatusModified
+	case 'A':
+		return StatusAdded
+	case 'D':
+		return StatusDeleted
+	case 'R':
+		return StatusRenamed
+	case 'C':
+		return StatusCopied
+	default:
+		return StatusModified
+	}
+}
+
+func DiscardAllChanges(repoPath, filePath string, isUntracked bool) error {
+	if isUntracked {
+		return os.Remove(filepath.Join(repoPath, filePath))
+	}
+	// Unstage first (ignore error — file may not be staged)
+	unstage := exec.Command("git", RepoArgs(repoPath, "reset", "HEAD", "--", filePath)...)
+	_ = unstage.Run()
+	// Discard working tree changes
+	cmd := exec.Command("git", RepoArgs(repoPath, "checkout", "--", filePath)...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("git checkout failed: %s", out)
+	}
+	return nil
+}
+
+// StagePaths stages every change under paths, new and deleted files
+// included (git add -A).
+func StagePaths(repoPath string, paths ...string) error {
+	args := RepoArgs(repoPath, append([]string{"add", "-A", "--"}, paths...)...)
+	if out, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("git add: %s", strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// UnstageAll empties the index of changes, leaving the worktree alone.
+// Before the first commit there's no HEAD to reset to, so everything is
+// removed from the index instead.
+func UnstageAll(repoPath string) error {
+	cmd := exec.Command("git", RepoArgs(repoPath, "reset", "-q")...)
+	if exec.Command("git", RepoArgs(repoPath, "rev-parse", "--verify", "-q", "HEAD")...).Run() != nil {
+		cmd = exec.Command("git", RepoArgs(repoPath, "rm", "-r", "-q", "--cached", "--ignore-unmatch", ".")...)
+	}
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("git %s: %s", cmd.Args[3], strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// DeleteFile removes a file from the worktree and, with git rm, from the
+// index. Untracked files (and directories) are just removed.
+func DeleteFile(repoPath, filePath string, isUntracked bool) error {
+	if isUntracked {
+		return os.RemoveAll(filepath.Join(repoPath, filePath))
+	}
+	cmd := exec.Command("git", RepoArgs(repoPath, "rm", "-f", "--", filePath)...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("git rm: %s", strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// RenameFile moves a file within repoPath, with git mv for tracked files
+// so the index follows, or a plain rename for untracked ones. Missing
+// parent directories of the destination are created.
+func RenameFile(repoPath, from, to string, isUntracked bool) error {
+	if !filepath.IsLocal(to) {
+		return fmt.Errorf("%s is outside the repo", to)
+	}
+	if err := os.MkdirAll(filepath.Dir(filepath.Join(repoPath, to)), 0755); err != nil {
+		return err
+	}
+	if isUntracked {
+		return os.Rename(filepath.Join(repoPath, from), filepath.Join(repoPath, to))
+	}
+	cmd := exec.Command("git", RepoArgs(repoPath, "mv", "--", from, to)...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("git mv: %s", strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// CommitStaged records the staged changes with message. With noVerify the
+// pre-commit and commit-msg hooks don't run; a commit one of them stopped
+// is reported as a *HookError.
+func CommitStaged(repoPath, message string, noVerify bool) error {
+	args := []string{"commit", "-F", "-"}
+	if noVerify {
+		args = append(args, "--no-verify")
+	}
+	cmd := exec.Command("git", RepoArgs(repoPath, args...)...)
+	cmd.Stdin = strings.NewReader(message)
+	trace := TraceHooks(cmd)
+	out, err := cmd.CombinedOutput()
+	hooks := trace()
+	if err != nil {
+		if hookErr := CommitHookError(string(out), hooks); hookErr != nil && !noVerify {
+			return hookErr
+		}
+		return fmt.Errorf("git commit: %s", strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// HookError is returned by CommitStaged when the repo's pre-commit or
+// commit-msg hook stopped the commit. Output is what the hook printed, for
+// showing in full; git commit --no-verify skips the hook.
+type HookError struct {
+	Hook   string
+	Output string
+}
+
+func (e *HookError) Error() string {
+	return fmt.Sprintf("git commit: the %s hook failed: %s", e.Hook, lastLine(e.Output))
+}
+
+// TraceHooks has git record the hooks cmd runs, in a temporary file, for
+// CommitHookError. The func it returns reads the record once cmd is done,
+// and removes the file.
+func TraceHooks(cmd *exec.Cmd) func() string {
+	f, err := os.CreateTemp("", "sidegit-trace-")
+	if err != nil {
+		return func() string { return "" }
+	}
+	f.Close()
+	cmd.Env = append(cmd.Environ(), "GIT_TRACE2_EVENT="+f.Name())
+	return func() string {
+		defer os.Remove(f.Name())
+		data, _ := os.ReadFile(f.Name())
+		return string(data)
+	}
+}
+
+// CommitHookError makes a *HookError of the output of a failed git commit
+// when its trace, from TraceHooks, shows a hook that --no-verify skips
+// exiting non-zero. It returns nil when git failed on its own, as with
+// nothing to commit or no user identity.
+func CommitHookError(output, trace string) *HookError {
+	type event struct {
+		Event    string `json:"event"`
+		Sid      string `json:"sid"`
+		ChildID  int    `json:"child_id"`
+		Class    string `json:"child_class"`
+		HookName string `json:"hook_name"`
+		Code     int    `json:"code"`
+	}
+	hooks := map[int]string{}
+	for _, line := range strings.Split(trace, "\n") {
+		var ev event
+		// Git commands a hook runs log here too, under a sid of their own
+		// below git commit's
+		if json.Unmarshal([]byte(line), &ev) != nil || strings.Contains(ev.Sid, "/") {
+			continue
+		}
+		switch {
+		case ev.Event == "child_start" && ev.Class == "hook" && (ev.HookName == "pre-commit" || ev.HookName == "commit-msg"):
+			hooks[ev.ChildID] = ev.HookName
+		case ev.Event == "child_exit" && ev.Code != 0 && hooks[ev.ChildID] != "":
+			return &HookError{Hook: hooks[ev.ChildID], Output: strings.TrimSpace(output)}
+		}
+	}
+	return nil
+}
+
+// SigningEnabled reports whether commits in repoPath get signed
+// (commit.gpgsign), which may need a passphrase prompt on the terminal.
+func SigningEnabled(repoPath string) bool {
+	out, err := exec.Command("git", RepoArgs(repoPath, "config", "--bool", "commit.gpgsign")...).Output()
+	return err == nil && strings.TrimSpace(string(out)) == "true"
+}
+
+// CommitTemplate returns the repo's commit.template with comment lines
+// dropped, or "" when none is set.
+func CommitTemplate(repoPath string) (string, error) {
+	out, err := exec.Command("git", RepoArgs(repoPath, "config", "--path", "commit.template")...).Output()
+	if err != nil {
+		return "", nil // unset
+	}
+	path := strings.TrimSpace(string(out))
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(repoPath, path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("commit.template: %w", err)
+	}
+	var lines []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if !strings.HasPrefix(line, "#") {
+			lines = append(lines, line)
+		}
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n")), nil
+}
+
+// ResolveConflict checks out one side of a conflicted file ("ours" or
+// "theirs") and marks it resolved.
+func ResolveConflict(repoPath, filePath, side string) error {
+	cmd := exec.Command("git", RepoArgs(repoPath, "checkout", "--"+side, "--", filePath)...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("git checkout --%s: %s", side, out)
+	}
+	return MarkResolved(repoPath, filePath)
+}
+
+func MarkResolved(repoPath, filePath string) error {
+	cmd := exec.Command("git", RepoArgs(repoPath, "add", "--", filePath)...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("git add: %s", out)
+	}
+	return nil
+}
+
+func ListBranches(repoPath string) ([]string, string, error) {
+	current := FindBranch(repoPath)
+	cmd := exec.Command("git", RepoArgs(repoPath, "branch", "--format=%(refname:short)")...)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return nil, current, fmt.Errorf("git branch: %s", out)
+	}
+	var branches []string
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		line = strings.TrimSpace(line)
+		// Skip the "(HEAD detached at …)" pseudo-entry
+		if line != "" && !strings.HasPrefix(line, "(") {
+			branches = append(branches, line)
+		}
+	}
+	return branches, current, nil
+}
+
+// DescribeDetached names a detached HEAD at commit head: the tag pointing
+// at it if there is one, otherwise its abbreviated hash.
+func DescribeDetached(repoPath, head string) (name string, isTag bool) {
+	if out, err := Backend.Query(repoPath, "describe", "--tags", "--exact-match", "HEAD"); err == nil {
+		return strings.TrimSpace(out), true
+	}
+	if len(head) > 7 {
+		head = head[:7]
+	}
+	if head == "" {
+		return "?", false
+	}
+	return head, false
+}
+
+// DescribeHead names HEAD relative to the nearest tag, like
+// "v1.2.0-3-ga1b2c3d", or returns "" when no tag is reachable.
+func DescribeHead(repoPath string) string {
+	out, err := exec.Command("git", RepoArgs(repoPath, "describe", "--tags", "HEAD")...).Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// StashCount returns how many entries the repo's stash list has.
+func StashCount(repoPath string) int {
+	out, err := exec.Command("git", RepoArgs(repoPath, "stash", "list", "--format=%gd")...).Output()
+	if err != nil || len(strings.TrimSpace(string(out))) == 0 {
+		return 0
+	}
+	return len(strings.Split(strings.TrimSpace(string(out)), "\n"))
+}
+
+// SwitchNewBranch creates branch at HEAD and switches to it. Uncommitted
+// changes come along, which makes it the way out of committing on the
+// wrong branch.
+func SwitchNewBranch(repoPath, branch string) error {
+	if err := CheckNewBranchName(repoPath, branch); err != nil {
+		return err
+	}
+	cmd := exec.Command("git", RepoArgs(repoPath, "switch", "-c", branch)...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("git switch -c: %s", out)
+	}
+	return nil
+}
+
+// CheckNewBranchName reports why branch can't be created in repoPath: an
+// invalid ref name or an existing branch.
+func CheckNewBranchName(repoPath, branch string) error {
+	if err := exec.Command("git", "check-ref-format", "--branch", branch).Run(); err != nil || strings.HasPrefix(branch, "-") {
+		return fmt.Errorf("%q is not a valid branch name", branch)
+	}
+	if err := exec.Command("git", RepoArgs(repoPath, "rev-parse", "--verify", "--quiet", "refs/heads/"+branch)...).Run(); err == nil {
+		return fmt.Errorf("branch %s already exists", branch)
+	}
+	return nil
+}
+
+func CheckoutBranch(repoPath, branch string) error {
+	cmd := exec.Command("git", RepoArgs(repoPath, "checkout", branch)...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("git checkout: %s", out)
+	}
+	return nil
+}
+
+type Remote struct {
+	Name string
+	URL  string
+}
+
+func ListRemotes(repoPath string) ([]Remote, error) {
+	cmd := exec.Command("git", RepoArgs(repoPath, "remote", "-v")...)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("git remote: %s", out)
+	}
+	var remotes []Remote
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 3 && fields[2] == "(fetch)" {
+			remotes = append(remotes, Remote{Name: fields[0], URL: fields[1]})
+		}
+	}
+	return remotes, nil
+}
+
+func SetUpstream(repoPath, upstream string) error {
+	cmd := exec.Command("git", RepoArgs(repoPath, "branch", "--set-upstream-to="+upstream)...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("git branch --set-upstream-to: %s", out)
+	}
+	return nil
+}
+
+func AddRemote(repoPath, name, url string) error {
+	cmd := exec.Command("git", RepoArgs(repoPath, "remote", "add", name, url)...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("git remote add: %s", out)
+	}
+	return nil
+}
+
+func RemoveRemote(repoPath, name string) error {
+	cmd := exec.Command("git", RepoArgs(repoPath, "remote", "remove", name)...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("git remote remove: %s", out)
+	}
+	return nil
+}
+
+func SetRemoteURL(repoPath, name, url string) error {
+	cmd := exec.Command("git", RepoArgs(repoPath, "remote", "set-url", name, url)...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("git remote set-url: %s", out)
+	}
+	return nil
+}
+
+// CredentialsError is returned by fetch, pull and push when git stopped
+// at a username, password or passphrase prompt. Args re-run the operation
+// on a terminal, where the prompt can be answered.
+type CredentialsError struct {
+	Args   []string
+	Output string
+}
+
+func (e *CredentialsError) Error() string {
+	return fmt.Sprintf("git %s needs credentials: %s", e.Args[0], lastLine(e.Output))
+}
+
+// credentialHints are git and ssh messages that mean a prompt was needed.
+var credentialHints = []string{
+	"terminal prompts disabled",
+	"could not read Username",
+	"could not read Password",
+	"Authentication failed",
+	"Permission denied (publickey",
+	"Host key verification failed",
+	"passphrase",
+}
+
+// runRemote runs a git command that talks to a remote without letting it
+// prompt: a prompt would hang a background fetch, or scribble over the
+// TUI. A prompt it needed is reported as a *CredentialsError. Canceling
+// ctx kills git, and the error is ctx's.
+func runRemote(ctx context.Context, repoPath string, args ...string) error {
+	_, err := runRemoteOutput(ctx, repoPath, args...)
+	return err
+}
+
+// NoPromptEnv is what to add to the environment of git commands run in
+// the repo at repoPath with nobody to answer them: credential and ssh
+// prompts fail instead of waiting, and an editor leaves the message as
+// it is.
+func NoPromptEnv(repoPath string) []string {
+	env := []string{"GIT_TERMINAL_PROMPT=0", "GIT_EDITOR=true"}
+	if os.Getenv("GIT_SSH_COMMAND") == "" && exec.Command("git", RepoArgs(repoPath, "config", "core.sshCommand")...).Run() != nil {
+		env = append(env, "GIT_SSH_COMMAND=ssh -o BatchMode=yes")
+	}
+	return env
+}
+
+// runRemoteOutput is runRemote for commands whose output is needed.
+func runRemoteOutput(ctx context.Context, repoPath string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", RepoArgs(repoPath, args...)...)
+	cmd.WaitDelay = time.Second // ssh may hold the pipes of a killed git
+	cmd.Env = append(os.Environ(), NoPromptEnv(repoPath)...)
+	out, err := cmd.CombinedOutput()
+	if err == nil {
+		return string(out), nil
+	}
+	if ctx.Err() != nil {
+		return "", ctx.Err()
+	}
+	for _, hint := range credentialHints {
+		if strings.Contains(string(out), hint) {
+			return "", &CredentialsError{Args: args, Output: string(out)}
+		}
+	}
+	return "", fmt.Errorf("git %s: %s", args[0], out)
+}
+
+func lastLine(s string) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
+
+func GitFetch(ctx context.Context, repoPath string) error {
+	return runRemote(ctx, repoPath, "fetch", "--quiet")
+}
+
+// LastFetch returns when the repo was last fetched, based on FETCH_HEAD.
+func LastFetch(repoPath string) time.Time {
+	info, err := os.Stat(filepath.Join(GitDir(repoPath), "FETCH_HEAD"))
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
+
+// commitTimes caches CommitTime for the commit last asked about in each
+// repo, HEAD on every scan, so it stays one entry per repo.
+var (
+	commitTimesMu sync.Mutex
+	commitTimes   = map[string]commitStamp{}
+)
+
+type commitStamp struct {
+	hash string
+	when time.Time
+}
+
+// CommitTime returns when commit hash was committed, asking git only when
+// it isn't the one last asked about in the repo, or the zero time for an
+// empty hash.
+func CommitTime(repoPath, hash string) time.Time {
+	if hash == "" {
+		return time.Time{}
+	}
+	commitTimesMu.Lock()
+	c, ok := commitTimes[repoPath]
+	commitTimesMu.Unlock()
+	if ok && c.hash == hash {
+		return c.when
+	}
+	t := commitTime(repoPath, hash)
+	if !t.IsZero() {
+		commitTimesMu.Lock()
+		commitTimes[repoPath] = commitStamp{hash, t}
+		commitTimesMu.Unlock()
+	}
+	return t
+}
+
+func commitTime(repoPath, rev string) time.Time {
+	out, err := Backend.Query(repoPath, "log", "-1", "--format=%ct", rev, "--")
+	if err != nil {
+		return time.Time{}
+	}
+	secs, err := strconv.ParseInt(strings.TrimSpace(out), 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.Unix(secs, 0)
+}
+
+func GitPull(ctx context.Context, repoPath string) error {
+	return runRemote(ctx, repoPath, "pull")
+}
+
+// GitPullFFOnly pulls only when the branch can fast-forward, so it never
+// starts a merge.
+func GitPullFFOnly(ctx context.Context, repoPath string) error {
+	return runRemote(ctx, repoPath, "pull", "--ff-only")
+}
+
+func GitPush(ctx context.Context, repoPath string) error {
+	return runRemote(ctx, repoPath, "push")
+}
+
+// GitPushSetUpstream pushes the current branch like GitPush. A branch
+// without an upstream is pushed to origin (or the only remote) and gets
+// it set there.
+func GitPushSetUpstream(ctx context.Context, repoPath string) error {
+	if exec.Command("git", RepoArgs(repoPath, "rev-parse", "--verify", "-q", "@{upstream}")...).Run() == nil {
+		return GitPush(ctx, repoPath)
+	}
+	remotes, err := ListRemotes(repoPath)
+	if err != nil {
+		return err
+	}
+	remote := ""
+	for _, r := range remotes {
+		if r.Name == "origin" || remote == "" {
+			remote = r.Name
+		}
+	}
+	if remote == "" {
+		return fmt.Errorf("no remote to push to")
+	}
+	return runRemote(ctx, repoPath, "push", "-u", remote, "HEAD")
+}
+
+// GitStash stashes every change in repoPath, untracked files included.
+// An empty message leaves git's default ("WIP on <branch>").
+func GitStash(repoPath, message string) error {
+	args := RepoArgs(repoPath, "stash", "push", "--include-untracked")
+	if message != "" {
+		args = append(args, "-m", message)
+	}
+	if out, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("git stash: %s", strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+type Commit struct {
+	Hash    string
+	Short   string
+	Subject string
+	Author  string
+	When    string // relative date, e.g. "2 days ago"
+}
+
+func GetLog(repoPath string, limit int) ([]Commit, error) {
+	cmd := exec.Command("git", RepoArgs(repoPath, "log", fmt.Sprintf("-n%d", limit),
+		"--format=%H%x1f%h%x1f%s%x1f%an%x1f%ar")...)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("git log: %s", out)
+	}
+	var commits []Commit
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		parts := strings.Split(line, "\x1f")
+		if len(parts) != 5 {
+			continue
+		}
+		commits = append(commits, Commit{
+			Hash:    parts[0],
+			Short:   parts[1],
+			Subject: parts[2],
+			Author:  parts[3],
+			When:    parts[4],
+		})
+	}
+	return commits, nil
+}
+
+// CherryPick applies commit hash from sourcePath onto the current branch of
+// repoPath. When the repos differ the commit is fetched first.
+func CherryPick(repoPath, sourcePath, hash string) error {
+	if repoPath != sourcePath {
+		fetch := exec.Command("git", RepoArgs(repoPath, "fetch", "--no-tags", sourcePath, hash)...)
+		if out, err := fetch.CombinedOutput(); err != nil {
+			return fmt.Errorf("git fetch: %s", out)
+		}
+	}
+	cmd := exec.Command("git", RepoArgs(repoPath, "cherry-pick", hash)...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("git cherry-pick: %s", out)
+	}
+	return nil
+}
+
+// CherryPickOntoBranch cherry-picks hash onto branch in a temporary
+// worktree, so the repo stays on the branch it was on. A cherry-pick that
+// conflicts is undone, since there'd be nowhere to resolve it.
+func CherryPickOntoBranch(repoPath, branch, hash string) error {
+	dir, err := os.MkdirTemp("", "sidegit-pick-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	add := exec.Command("git", RepoArgs(repoPath, "worktree", "add", "--quiet", dir, branch)...)
+	if out, err := add.CombinedOutput(); err != nil {
+		return fmt.Errorf("git worktree add: %s", lastLine(string(out)))
+	}
+	defer exec.Command("git", RepoArgs(repoPath, "worktree", "remove", "--force", dir)...).Run()
+	if out, err := exec.Command("git", "-C", dir, "cherry-pick", hash).CombinedOutput(); err != nil {
+		if strings.Contains(string(out), "CONFLICT") {
+			exec.Command("git", "-C", dir, "cherry-pick", "--abort").Run()
+			return fmt.Errorf("cherry-picking onto %s conflicts; check out %s and cherry-pick %s there to resolve it", branch, branch, hash)
+		}
+		return fmt.Errorf("git cherry-pick: %s", lastLine(string(out)))
+	}
+	return nil
+}
+
+// IsMerge reports whether commit hash has more than one parent.
+func IsMerge(repoPath, hash string) bool {
+	return exec.Command("git", RepoArgs(repoPath, "rev-parse", "--verify", "-q", hash+"^2")...).Run() == nil
+}
+
+// Revert commits the inverse of hash with git's default message. A merge
+// is reverted against its first parent. When the revert conflicts, the
+// conflicts are left in the worktree and an error is returned.
+func Revert(repoPath, hash string) error {
+	args := RepoArgs(repoPath, "revert", "--no-edit")
+	if IsMerge(repoPath, hash) {
+		args = append(args, "-m", "1")
+	}
+	if out, err := exec.Command("git", append(args, hash)...).CombinedOutput(); err != nil {
+		if strings.Contains(string(out), "CONFLICT") {
+			return fmt.Errorf("git revert stopped on conflicts; resolve them and commit")
+		}
+		return fmt.Errorf("git revert: %s", lastLine(string(out)))
+	}
+	return nil
+}
+
+// DiffOptions tweak how GetDiff renders a diff. Untracked is a hint from
+// callers that already know the file's status; it saves probing git for it.
+type DiffOptions struct {
+	IgnoreWhitespace bool
+	Context          int // lines of context (-U<n>)
+	Untracked        bool
+	MaxLines         int  // stop reading after this many lines, 0 for all
+	Head             bool // staged and unstaged changes together (git diff HEAD)
+	Color            bool // git's colors in Text, for a pager, instead of Files
+}
+
+func (o DiffOptions) args() []string {
+	args := []string{fmt.Sprintf("-U%d", o.Context)}
+	if o.IgnoreWhitespace {
+		args = append(args, "--ignore-all-space")
+	}
+	if o.Color {
+		return append(args, "--color=always")
+	}
+	return append(args, "--no-color")
+}
+
+// Diff is the diff of one file: git's output, and the same parsed.
+type Diff struct {
+	Text      string
+	Files     []FileDiff // nil with DiffOptions.Color, or when Text is a note like "(no changes)"
+	Truncated bool       // cut off at DiffOptions.MaxLines
+}
+
+// GetDiff returns the diff of one file: unstaged changes, else staged
+// ones, else the whole file when it is untracked.
+func GetDiff(repoPath, filePath string, opts DiffOptions) (Diff, error) {
+	return Backend.Diff(repoPath, filePath, opts)
+}
+
+// GetRepoDiff returns the diff of every change in a repo at once: staged
+// and unstaged changes against HEAD, then each untracked file whole.
+// opts.MaxLines counts the lines of all of them together.
+func GetRepoDiff(repoPath string, opts DiffOptions) (Diff, error) {
+	return Backend.RepoDiff(repoPath, opts)
+}
@@ -0,0 +1,51 @@
+package sidegit
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCommitHookError(t *testing.T) {
+	const sid = `"sid":"20260101T000000.000000Z-H1-P1"`
+	start := func(id, hook string) string {
+		return `{"event":"child_start",` + sid + `,"child_id":` + id + `,"child_class":"hook","hook_name":"` + hook + `"}`
+	}
+	exit := func(id, code string) string {
+		return `{"event":"child_exit",` + sid + `,"child_id":` + id + `,"code":` + code + `}`
+	}
+	trace := func(lines ...string) string { return strings.Join(lines, "\n") + "\n" }
+	tests := []struct {
+		name  string
+		trace string
+		want  string // the hook named, "" for no *HookError
+	}{
+		{"no trace", "", ""},
+		{"pre-commit failed", trace(start("0", "pre-commit"), exit("0", "1")), "pre-commit"},
+		{"commit-msg failed", trace(start("0", "pre-commit"), exit("0", "0"), start("1", "commit-msg"), exit("1", "1")), "commit-msg"},
+		{"nothing to commit after pre-commit passed", trace(start("0", "pre-commit"), exit("0", "0")), ""},
+		{"hook --no-verify doesn't skip", trace(start("0", "prepare-commit-msg"), exit("0", "1")), ""},
+		{"other child failed", trace(`{"event":"child_start",`+sid+`,"child_id":0,"child_class":"?"}`, exit("0", "1")), ""},
+		{
+			"git run by the hook failed",
+			trace(start("0", "pre-commit"),
+				`{"event":"child_start","sid":"20260101T000000.000000Z-H1-P1/20260101T000000.000001Z-H1-P2","child_id":0,"child_class":"hook","hook_name":"pre-commit"}`,
+				`{"event":"child_exit","sid":"20260101T000000.000000Z-H1-P1/20260101T000000.000001Z-H1-P2","child_id":0,"code":1}`,
+				exit("0", "0")),
+			"",
+		},
+		{"garbled lines", trace("not json", start("0", "pre-commit"), "{", exit("0", "1")), "pre-commit"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CommitHookError("  lint failed\n", tt.trace)
+			switch {
+			case tt.want == "" && err != nil:
+				t.Errorf("got a %s hook error, want none", err.Hook)
+			case tt.want != "" && err == nil:
+				t.Errorf("got no hook error, want %s", tt.want)
+			case err != nil && (err.Hook != tt.want || err.Output != "lint failed"):
+				t.Errorf("got %+v, want the %s hook with its output", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package sidegit
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ignoreRule is a pattern from a .gitignore file, or from .git/info/exclude.
+type ignoreRule struct {
+	file     string   // the file it's from
+	base     string   // directory of the .gitignore, relative to the repo, "" for the root
+	segs     []string // pattern split on "/", for an anchored pattern
+	name     string   // the pattern, for one that matches a name at any depth
+	negate   bool     // "!pattern" re-includes
+	dirOnly  bool     // "pattern/" only matches directories
+	anchored bool
+}
+
+// gitignore holds the rules of a repo's .gitignore files, shallowest first
+// so that deeper files override them, as with git. Global excludes
+// (core.excludesFile) aren't read.
+type gitignore []ignoreRule
+
+// parseIgnoreFile reads the rules of the .gitignore file at file, whose
+// patterns apply below base.
+func parseIgnoreFile(file, base string) []ignoreRule {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	var rules []ignoreRule
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimRight(sc.Text(), " \t\r")
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		r := ignoreRule{file: file, base: base}
+		if line[0] == '!' {
+			r.negate, line = true, line[1:]
+		} else if line[0] == '\\' {
+			line = line[1:] // "\#" and "\!" start a literal pattern
+		}
+		if strings.HasSuffix(line, "/") {
+			r.dirOnly, line = true, strings.TrimRight(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+		if strings.Contains(line, "/") {
+			r.anchored, r.segs = true, strings.Split(strings.TrimPrefix(line, "/"), "/")
+		} else {
+			r.name = line
+		}
+		rules = append(rules, r)
+	}
+	return rules
+}
+
+// setFile replaces the rules read from file, the .gitignore of directory
+// base, with what it has now, keeping the rules ordered by depth.
+func (g gitignore) setFile(file, base string) gitignore {
+	var kept gitignore
+	for _, r := range g {
+		if r.file != file {
+			kept = append(kept, r)
+		}
+	}
+	kept = append(kept, parseIgnoreFile(file, base)...)
+	sort.SliceStable(kept, func(i, j int) bool { return ignoreDepth(kept[i].base) < ignoreDepth(kept[j].base) })
+	return kept
+}
+
+func ignoreDepth(base string) int {
+	if base == "" {
+		return 0
+	}
+	return strings.Count(base, "/") + 1
+}
+
+// ignored reports whether the slash-separated path rel, relative to the
+// repo, is ignored. As with git, nothing below an ignored directory can be
+// re-included.
+func (g gitignore) ignored(rel string, isDir bool) bool {
+	if len(g) == 0 {
+		return false
+	}
+	segs := strings.Split(rel, "/")
+	for i := 1; i < len(segs); i++ {
+		if g.match(strings.Join(segs[:i], "/"), true) {
+			return true
+		}
+	}
+	return g.match(rel, isDir)
+}
+
+// match applies the rules to rel alone, the last one matching deciding.
+func (g gitignore) match(rel string, isDir bool) bool {
+	ignored := false
+	for _, r := range g {
+		if r.dirOnly && !isDir {
+			continue
+		}
+		sub := rel
+		if r.base != "" {
+			if !strings.HasPrefix(rel, r.base+"/") {
+				continue
+			}
+			sub = rel[len(r.base)+1:]
+		}
+		var ok bool
+		if r.anchored {
+			ok = globMatch(r.segs, strings.Split(sub, "/"))
+		} else {
+			ok, _ = path.Match(r.name, path.Base(sub))
+		}
+		if ok {
+			ignored = !r.negate
+		}
+	}
+	return ignored
+}
+
+// loadInfoExclude returns the rules of the repo's .git/info/exclude.
+func loadInfoExclude(repoPath string) gitignore {
+	return parseIgnoreFile(filepath.Join(GitDir(repoPath), "info", "exclude"), "")
+}
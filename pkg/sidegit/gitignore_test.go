@@ -0,0 +1,101 @@
+package sidegit
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGitignore(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, lines ...string) string {
+		file := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(strings.Join(lines, "\n")+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return file
+	}
+	var g gitignore
+	// Deeper first, to check setFile orders them by depth
+	g = g.setFile(write("sub/.gitignore",
+		"!keep.log",
+		"local",
+		"/only-here",
+	), "sub")
+	g = g.setFile(write(".gitignore",
+		"# a comment",
+		"",
+		"*.log",
+		"build/",
+		"/root-only",
+		"docs/*.html",
+		"**/cache",
+		"vendor/**",
+		"a/**/z",
+		"\\#hash",
+		"trailing   ",
+		"!important.log",
+		"out/",
+		"!out/keep",
+	), "")
+
+	tests := []struct {
+		rel   string
+		isDir bool
+		want  bool
+	}{
+		{"x.log", false, true},
+		{"deep/down/x.log", false, true},
+		{"important.log", false, false},
+		{"sub/keep.log", false, false}, // the deeper file overrides
+		{"sub/other.log", false, true},
+		{"build", true, true},
+		{"build", false, false}, // "build/" only matches directories
+		{"src/build", true, true},
+		{"src/build/x.go", false, true}, // below an ignored directory
+		{"root-only", false, true},
+		{"src/root-only", false, false},
+		{"docs/a.html", false, true},
+		{"docs/sub/a.html", false, false},
+		{"cache", true, true},
+		{"x/y/cache", true, true},
+		{"vendor/pkg/a.go", false, true},
+		{"vendor", true, true},
+		{"a/z", false, true},
+		{"a/b/c/z", false, true},
+		{"b/z", false, false},
+		{"#hash", false, true},
+		{"trailing", false, true},
+		{"sub/local", false, true},
+		{"local", false, false}, // sub's rules apply below sub only
+		{"sub/only-here", false, true},
+		{"sub/x/only-here", false, false},
+		{"out/keep", false, true}, // nothing below an ignored directory comes back
+		{"src/main.go", false, false},
+	}
+	for _, tt := range tests {
+		if got := g.ignored(tt.rel, tt.isDir); got != tt.want {
+			t.Errorf("ignored(%q, %v) = %v, want %v", tt.rel, tt.isDir, got, tt.want)
+		}
+	}
+
+	// Rewriting a file replaces its rules
+	g = g.setFile(write(".gitignore", "*.tmp"), "")
+	if g.ignored("x.log", false) || !g.ignored("x.tmp", false) || !g.ignored("sub/local", false) {
+		t.Errorf("rules after rewriting .gitignore: %+v", g)
+	}
+}
+
+func TestGitignoreEmpty(t *testing.T) {
+	var g gitignore
+	if g.ignored("anything", false) {
+		t.Error("no rules ignored a path")
+	}
+	if rules := parseIgnoreFile(filepath.Join(t.TempDir(), "missing"), ""); rules != nil {
+		t.Errorf("missing file gave rules %+v", rules)
+	}
+}
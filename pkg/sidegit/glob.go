@@ -0,0 +1,62 @@
+package sidegit
+
+import (
+	"path"
+	"strings"
+)
+
+// MatchGlob reports whether the slash-separated path p matches any of
+// patterns. Patterns without a slash match the last element at any depth,
+// like .gitignore; the rest match from the start of p, so a leading slash
+// anchors a single name there. "**" matches any number of directories.
+func MatchGlob(patterns []string, p string) bool {
+	p = strings.TrimSuffix(p, "/")
+	for _, pat := range patterns {
+		if !strings.Contains(pat, "/") {
+			if ok, _ := path.Match(pat, path.Base(p)); ok {
+				return true
+			}
+			continue
+		}
+		if globMatch(strings.Split(strings.TrimPrefix(pat, "/"), "/"), strings.Split(p, "/")) {
+			return true
+		}
+	}
+	return false
+}
+
+// globMatch matches path segments against pattern segments, where a "**"
+// segment stands for zero or more path segments.
+func globMatch(pattern, segs []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(segs); i++ {
+				if globMatch(pattern[1:], segs[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(segs) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], segs[0]); !ok {
+			return false
+		}
+		pattern, segs = pattern[1:], segs[1:]
+	}
+	return len(segs) == 0
+}
+
+// QuoteGlob escapes the glob metacharacters in p, so MatchGlob matches it
+// literally.
+func QuoteGlob(p string) string {
+	var b strings.Builder
+	for _, r := range p {
+		if strings.ContainsRune(`*?[\`, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
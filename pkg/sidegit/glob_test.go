@@ -0,0 +1,57 @@
+package sidegit
+
+import "testing"
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		patterns []string
+		path     string
+		want     bool
+	}{
+		{[]string{"d"}, "d", true},
+		{[]string{"d"}, "x/d", true},
+		{[]string{"/d"}, "d", true},
+		{[]string{"/d"}, "x/d", false},
+		{[]string{"x/d"}, "x/d", true},
+		{[]string{"x/d"}, "y/x/d", false},
+		{[]string{"*-deprecated"}, "a/old-deprecated", true},
+		{[]string{"**/archive/**"}, "archive/a", true},
+		{[]string{"**/archive/**"}, "x/archive/a/b", true},
+		{[]string{"**/archive/**"}, "x/archived/a", false},
+		{[]string{"x/**"}, "x", true},
+		{[]string{"d"}, "d/", true},
+		{[]string{"a", "b"}, "x/b", true},
+		{nil, "d", false},
+	}
+	for _, tt := range tests {
+		if got := MatchGlob(tt.patterns, tt.path); got != tt.want {
+			t.Errorf("MatchGlob(%q, %q) = %v, want %v", tt.patterns, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestQuoteGlob(t *testing.T) {
+	tests := []struct {
+		path  string
+		other string // a path the quoted pattern must not match
+	}{
+		{"plain", "plainer"},
+		{"a*b", "axb"},
+		{"what?", "whats"},
+		{"[ab]", "a"},
+		{`back\slash`, "backslash"},
+		{"x/[y]", "x/y"},
+	}
+	for _, tt := range tests {
+		pattern := "/" + QuoteGlob(tt.path)
+		if !MatchGlob([]string{pattern}, tt.path) {
+			t.Errorf("%q doesn't match %q", pattern, tt.path)
+		}
+		if MatchGlob([]string{pattern}, tt.other) {
+			t.Errorf("%q matches %q", pattern, tt.other)
+		}
+		if MatchGlob([]string{pattern}, "deeper/"+tt.path) {
+			t.Errorf("%q matches %q", pattern, "deeper/"+tt.path)
+		}
+	}
+}
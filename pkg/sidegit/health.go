@@ -0,0 +1,75 @@
+package sidegit
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// untrackedWarning is the untracked file count that triggers a warning.
+const untrackedWarning = 200
+
+// inProgressMarkers maps files in .git to the operation they indicate.
+var inProgressMarkers = []struct {
+	file string
+	op   string
+}{
+	{"MERGE_HEAD", "merge"},
+	{"rebase-merge", "rebase"},
+	{"rebase-apply", "rebase"},
+	{"CHERRY_PICK_HEAD", "cherry-pick"},
+	{"REVERT_HEAD", "revert"},
+	{"BISECT_LOG", "bisect"},
+}
+
+// CheckHealth returns human-readable warnings about a repo's state. It only
+// inspects files under .git plus what r already holds, so it adds no git
+// process spawns to a scan.
+func CheckHealth(r Repo, status GitStatus) []string {
+	gitDir := GitDir(r.Path)
+	exists := func(name string) bool {
+		_, err := os.Stat(filepath.Join(gitDir, name))
+		return err == nil
+	}
+
+	var warnings []string
+	detached := r.Branch == "HEAD"
+	if detached {
+		warnings = append(warnings, "detached HEAD")
+	}
+	for _, m := range inProgressMarkers {
+		if exists(m.file) {
+			warnings = append(warnings, m.op+" in progress")
+			break
+		}
+	}
+	if status.DirtySubmodules > 0 {
+		warnings = append(warnings, fmt.Sprintf("%d dirty submodule(s)", status.DirtySubmodules))
+	}
+	if !detached && r.Upstream == "" && hasRemotes(gitDir) {
+		warnings = append(warnings, "no upstream for "+r.Branch)
+	}
+	if r.Ahead > 0 && r.Behind > 0 {
+		warnings = append(warnings, fmt.Sprintf("diverged from upstream (↑%d ↓%d)", r.Ahead, r.Behind))
+	}
+	untracked := 0
+	for _, f := range r.Files {
+		if f.Status == StatusUntracked {
+			untracked++
+		}
+	}
+	if untracked >= untrackedWarning {
+		warnings = append(warnings, fmt.Sprintf("%d untracked files", untracked))
+	}
+	return warnings
+}
+
+// hasRemotes reports whether .git/config defines any remote.
+func hasRemotes(gitDir string) bool {
+	data, err := os.ReadFile(filepath.Join(gitDir, "config"))
+	if err != nil {
+		return false
+	}
+	return strings.Contains(string(data), "[remote \"")
+}
@@ -0,0 +1,76 @@
+package sidegit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// PullRequest is the open pull request for a repo's branch, as reported by
+// the GitHub CLI.
+type PullRequest struct {
+	Number int
+	URL    string
+	Checks string // "pass", "fail", "pending", or "" when there are none
+	Branch string // the PR's head branch
+}
+
+// GetPullRequest asks gh for the open pull request of the checked-out
+// branch. It returns nil without an error when there isn't one. gh brings
+// its own auth, so this needs `gh auth login` once and network access.
+func GetPullRequest(ctx context.Context, repoPath string) (*PullRequest, error) {
+	cmd := exec.CommandContext(ctx, "gh", "pr", "view", "--json", "number,state,url,headRefName,statusCheckRollup")
+	cmd.Dir = repoPath
+	out, err := cmd.Output()
+	if err != nil {
+		if ee, ok := err.(*exec.ExitError); ok {
+			if strings.Contains(string(ee.Stderr), "no pull requests found") {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("gh pr view: %s", strings.TrimSpace(string(ee.Stderr)))
+		}
+		return nil, err
+	}
+	var v struct {
+		Number      int
+		State       string
+		URL         string
+		HeadRefName string
+		Checks      []struct {
+			Status     string // check runs: QUEUED, IN_PROGRESS, COMPLETED
+			Conclusion string // check runs, once completed
+			State      string // commit statuses: PENDING, SUCCESS, FAILURE, ERROR
+		} `json:"statusCheckRollup"`
+	}
+	if err := json.Unmarshal(out, &v); err != nil {
+		return nil, fmt.Errorf("gh pr view: %v", err)
+	}
+	if v.State != "OPEN" {
+		return nil, nil
+	}
+	pr := &PullRequest{Number: v.Number, URL: v.URL, Branch: v.HeadRefName}
+	for _, c := range v.Checks {
+		result := c.State
+		if result == "" {
+			result = c.Conclusion
+			if c.Status != "COMPLETED" {
+				result = "PENDING"
+			}
+		}
+		switch result {
+		case "FAILURE", "ERROR", "CANCELLED", "TIMED_OUT", "ACTION_REQUIRED", "STARTUP_FAILURE":
+			pr.Checks = "fail"
+		case "PENDING", "EXPECTED", "QUEUED", "IN_PROGRESS":
+			if pr.Checks != "fail" {
+				pr.Checks = "pending"
+			}
+		default:
+			if pr.Checks == "" {
+				pr.Checks = "pass"
+			}
+		}
+	}
+	return pr, nil
+}
@@ -0,0 +1,78 @@
+package sidegit
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// ReflogEntry is one line of HEAD's reflog.
+type ReflogEntry struct {
+	Hash     string
+	Short    string
+	Selector string // "HEAD@{3}"
+	Subject  string // what moved HEAD, e.g. "rebase (finish): returning to refs/heads/main"
+	When     string // when HEAD moved, e.g. "2 hours ago"
+}
+
+// GetReflog returns HEAD's most recent reflog entries, newest first.
+func GetReflog(repoPath string, limit int) ([]ReflogEntry, error) {
+	// With --date, %gd is "HEAD@{2 hours ago}" rather than "HEAD@{3}"
+	cmd := exec.Command("git", RepoArgs(repoPath, "reflog", "show", fmt.Sprintf("-n%d", limit),
+		"--date=relative", "--format=%H%x1f%h%x1f%gd%x1f%gs", "HEAD")...)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("git reflog: %s", strings.TrimSpace(string(out)))
+	}
+	var entries []ReflogEntry
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		parts := strings.Split(line, "\x1f")
+		if len(parts) != 4 {
+			continue
+		}
+		when := strings.TrimSuffix(strings.TrimPrefix(parts[2], "HEAD@{"), "}")
+		entries = append(entries, ReflogEntry{
+			Hash:     parts[0],
+			Short:    parts[1],
+			Selector: fmt.Sprintf("HEAD@{%d}", len(entries)),
+			Subject:  parts[3],
+			When:     when,
+		})
+	}
+	return entries, nil
+}
+
+// ResetTo moves the current branch to ref with `git reset --<mode>`,
+// mode being soft, mixed or hard.
+func ResetTo(repoPath, ref, mode string) error {
+	cmd := exec.Command("git", RepoArgs(repoPath, "reset", "--"+mode, ref)...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("git reset --%s: %s", mode, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// CommitsNotIn returns the colored one-line log of the commits on HEAD
+// that ref doesn't have, with their count.
+func CommitsNotIn(repoPath, ref string) (string, int, error) {
+	out, err := exec.Command("git", RepoArgs(repoPath, "log", "--color=always", "--format=%C(yellow)%h%C(reset) %s %C(dim)(%an, %ar)%C(reset)", ref+"..HEAD")...).Output()
+	if err != nil {
+		return "", 0, fmt.Errorf("git log %s..HEAD: %v", ref, err)
+	}
+	text := strings.TrimRight(string(out), "\n")
+	if text == "" {
+		return "", 0, nil
+	}
+	return text, strings.Count(text, "\n") + 1, nil
+}
+
+// ResetKeep moves the current branch to hash with `git reset --keep`,
+// which refuses rather than lose uncommitted changes to files that
+// differ between the two commits.
+func ResetKeep(repoPath, hash string) error {
+	cmd := exec.Command("git", RepoArgs(repoPath, "reset", "--keep", hash)...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("git reset --keep: %s", strings.TrimSpace(string(out)))
+	}
+	return nil
+}
@@ -0,0 +1,336 @@
+package sidegit
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+type Repo struct {
+	Path        string
+	RelPath     string // display name with "/" separators; the root repo shows its basename
+	IsRoot      bool
+	Branch      string
+	Detached    string // for a detached HEAD: "(tag v1.2)" or "(detached @ a1b2c3d)"
+	Upstream    string
+	Files       []FileStatus
+	Ahead       int
+	Behind      int
+	Fetched     time.Time    // zero if never fetched
+	LastCommit  time.Time    // when HEAD was committed, zero before the first commit
+	Accent      string       // accent color, empty for none
+	BranchColor string       // color for Branch, empty for the theme's
+	Warnings    []string     // health warnings, see CheckHealth
+	PR          *PullRequest // open pull request for Branch; only set by callers that look it up
+	FetchError  string       // why the last background fetch failed, empty if it worked
+	AutoCommit  bool         // committed automatically after a quiet period; only set by callers that do that
+	// WatchLimited is set by a Service when the system's limit on file
+	// watches left some of the repo unwatched, so its changes show on polls
+	WatchLimited bool
+
+	// How much of the repo is local: a shallow clone lacks older history,
+	// a partial clone fetches objects on demand and a sparse checkout
+	// leaves files out of the worktree
+	Shallow bool
+	Partial bool
+	Sparse  bool
+
+	// Unavailable is set when git timed out on the repo; nothing else but
+	// the path fields is filled in.
+	Unavailable bool
+}
+
+// ScanOptions controls repo discovery.
+type ScanOptions struct {
+	Depth   int        // directory levels searched below the root's children
+	Ignore  []string   // directory names never descended into
+	Hidden  []string   // repos left out, by display name or folder name
+	Exclude []string   // globs of repos left out and not descended into, see MatchGlob
+	Bare    []BareRepo // repos kept apart from their work tree, listed wherever it is
+	Nested  bool       // also find repos at any depth inside the repos found
+
+	// nested, when set, carries the nested repos between scans: a scan
+	// walks for them only while it isn't known, and builds the ones the
+	// last walk found otherwise.
+	nested *nestedRepos
+}
+
+// nestedRepos holds the nested repos found by the last walk for them.
+type nestedRepos struct {
+	known bool
+	paths []string
+}
+
+func ScanRepos(root string, opts ScanOptions) ([]Repo, error) {
+	return scanReposWith(root, opts, buildRepo)
+}
+
+// scanReposWith discovers repos below root and builds each one with build,
+// letting callers substitute a cached builder.
+func scanReposWith(root string, opts ScanOptions, build func(root, repoPath string) Repo) ([]Repo, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+
+	var repos []Repo
+
+	// Check if root itself is a git repo
+	if isGitRepo(root) {
+		repos = append(repos, build(root, root))
+	}
+
+	// Scan subdirectories: the root's children plus opts.Depth more levels.
+	// Symlinked directories are followed, keeping their path under root;
+	// visited holds real paths, so a link back up the tree or a second
+	// link to the same repo is skipped.
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		realRoot = root
+	}
+	visited := map[string]bool{realRoot: true}
+	var walk func(dir, real string, level int)
+	walk = func(dir, real string, level int) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return
+		}
+		for _, entry := range entries {
+			if entry.Name()[0] == '.' || containsString(opts.Ignore, entry.Name()) {
+				continue
+			}
+			sub, subReal := filepath.Join(dir, entry.Name()), filepath.Join(real, entry.Name())
+			switch {
+			case entry.IsDir():
+			case entry.Type()&fs.ModeSymlink != 0:
+				if subReal, err = filepath.EvalSymlinks(sub); err != nil {
+					continue
+				}
+				if info, err := os.Stat(subReal); err != nil || !info.IsDir() {
+					continue
+				}
+			default:
+				continue
+			}
+			if visited[subReal] || strings.HasPrefix(realRoot, subReal+string(filepath.Separator)) {
+				// Already scanned, or an ancestor of root
+				continue
+			}
+			visited[subReal] = true
+			if excluded(root, sub, opts.Exclude) {
+				continue
+			}
+			if isGitRepo(sub) {
+				repos = append(repos, build(root, sub))
+			}
+			if level < opts.Depth {
+				walk(sub, subReal, level+1)
+			}
+		}
+	}
+	walk(root, realRoot, 0)
+
+	if opts.Nested {
+		// Vendored forks and tools checked out inside a repo. Submodules
+		// have a .git file rather than a directory, so they stay out
+		found := map[string]bool{}
+		for _, r := range repos {
+			found[r.Path] = true
+		}
+		if opts.nested != nil && opts.nested.known {
+			for _, p := range opts.nested.paths {
+				if !found[p] && isGitRepo(p) && !excluded(root, p, opts.Exclude) {
+					repos = append(repos, build(root, p))
+				}
+			}
+		} else {
+			var paths []string
+			for _, r := range append([]Repo(nil), repos...) {
+				_ = filepath.WalkDir(r.Path, func(p string, d fs.DirEntry, err error) error {
+					if err != nil || !d.IsDir() || p == r.Path {
+						return nil
+					}
+					if d.Name()[0] == '.' || containsString(opts.Ignore, d.Name()) || found[p] || excluded(root, p, opts.Exclude) {
+						// Repos found already get a walk of their own
+						return filepath.SkipDir
+					}
+					if isGitRepo(p) {
+						repos = append(repos, build(root, p))
+						paths = append(paths, p)
+					}
+					return nil
+				})
+			}
+			if opts.nested != nil {
+				*opts.nested = nestedRepos{known: true, paths: paths}
+			}
+		}
+	}
+
+	for _, b := range opts.Bare {
+		if b, ok := addBareRepo(b); ok {
+			repos = append(repos, build(root, b.WorkTree))
+		}
+	}
+
+	if len(opts.Hidden) > 0 || len(opts.Exclude) > 0 {
+		// The walk already skipped excluded directories; this catches the
+		// root repo and bare repos
+		visible := repos[:0]
+		for _, r := range repos {
+			if !containsString(opts.Hidden, r.RelPath) && !containsString(opts.Hidden, filepath.Base(r.Path)) && !MatchGlob(opts.Exclude, r.RelPath) {
+				visible = append(visible, r)
+			}
+		}
+		repos = visible
+	}
+
+	SortReposByPath(repos)
+
+	return repos, nil
+}
+
+// excluded reports whether dir, below root, matches one of the exclude
+// globs.
+func excluded(root, dir string, exclude []string) bool {
+	if len(exclude) == 0 {
+		return false
+	}
+	rel, err := filepath.Rel(root, dir)
+	return err == nil && MatchGlob(exclude, filepath.ToSlash(rel))
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// SortReposByPath sorts by relative path, but keeps the root repo first.
+func SortReposByPath(repos []Repo) {
+	sort.Slice(repos, func(i, j int) bool {
+		if repos[i].IsRoot != repos[j].IsRoot {
+			return repos[i].IsRoot
+		}
+		return repos[i].RelPath < repos[j].RelPath
+	})
+}
+
+func isGitRepo(path string) bool {
+	info, err := os.Stat(filepath.Join(path, ".git"))
+	if err != nil {
+		return false
+	}
+	return info.IsDir()
+}
+
+// FindRepos discovers the repos below root like ScanRepos, but only fills
+// in their paths and names, without running git.
+func FindRepos(root string, opts ScanOptions) ([]Repo, error) {
+	return scanReposWith(root, opts, func(root, repoPath string) Repo {
+		rel, isRoot := repoName(root, repoPath)
+		return Repo{Path: repoPath, RelPath: rel, IsRoot: isRoot}
+	})
+}
+
+// repoName returns the display name of the repo at repoPath and whether
+// it is root itself.
+func repoName(root, repoPath string) (string, bool) {
+	if b, ok := lookupBareRepo(repoPath); ok {
+		// Its work tree is likely outside root, or root itself
+		return b.Name, false
+	}
+	rel, err := filepath.Rel(root, repoPath)
+	if err != nil {
+		rel = repoPath
+	}
+	isRoot := rel == "" || rel == "."
+	if isRoot {
+		// The absolute path eats the sidebar; show the folder name instead
+		rel = filepath.Base(repoPath)
+	}
+	// Display names use "/" everywhere, like git paths
+	return filepath.ToSlash(rel), isRoot
+}
+
+func buildRepo(root, repoPath string) Repo {
+	rel, isRoot := repoName(root, repoPath)
+
+	status, err := GetStatus(repoPath)
+	if errors.Is(err, ErrTimeout) {
+		// Every further git call would stall too, and so would the reads
+		// of .git below. The git calls run under the same timeout anyway
+		return Repo{
+			Path:        repoPath,
+			RelPath:     rel,
+			IsRoot:      isRoot,
+			Unavailable: true,
+			Warnings:    []string{"unavailable: git timed out"},
+		}
+	}
+	branch := status.Branch
+	if err != nil {
+		branch = FindBranch(repoPath)
+	}
+
+	stampModTimes(repoPath, status.Files)
+	repo := Repo{
+		Path:     repoPath,
+		RelPath:  rel,
+		IsRoot:   isRoot,
+		Branch:   branch,
+		Upstream: status.Upstream,
+		Files:    status.Files,
+		Ahead:    status.Ahead,
+		Behind:   status.Behind,
+		Fetched:  LastFetch(repoPath),
+	}
+	repo.LastCommit = CommitTime(repoPath, status.Head)
+	if branch == "HEAD" {
+		if name, isTag := DescribeDetached(repoPath, status.Head); isTag {
+			repo.Detached = "(tag " + name + ")"
+		} else {
+			repo.Detached = "(detached @ " + name + ")"
+		}
+	}
+	readCloneLayout(&repo)
+	repo.Warnings = CheckHealth(repo, status)
+	return repo
+}
+
+// stampModTimes sets the ModTime of every file that still exists.
+func stampModTimes(repoPath string, files []FileStatus) {
+	for i := range files {
+		if info, err := os.Lstat(filepath.Join(repoPath, filepath.FromSlash(files[i].Path))); err == nil {
+			files[i].ModTime = info.ModTime()
+		}
+	}
+}
+
+// LastActive returns when r last saw work: its newest changed file or its
+// last commit, whichever is later.
+func LastActive(r Repo) time.Time {
+	if t := NewestChange(r); t.After(r.LastCommit) {
+		return t
+	}
+	return r.LastCommit
+}
+
+// NewestChange returns when the most recently modified of r's changed
+// files was modified, or the zero time if none exists on disk.
+func NewestChange(r Repo) time.Time {
+	var newest time.Time
+	for _, f := range r.Files {
+		if f.ModTime.After(newest) {
+			newest = f.ModTime
+		}
+	}
+	return newest
+}
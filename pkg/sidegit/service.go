@@ -0,0 +1,584 @@
+package sidegit
+
+import (
+	"context"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Service owns repo state in a background goroutine. Refresh requests and
+// periodic polls are coalesced into scans, at most one per
+// Options.MinScanInterval, and every finished scan is published as a
+// snapshot on Updates. The TUI and non-interactive modes share this engine
+// instead of running ad-hoc scans.
+type Service struct {
+	root string
+	opts Options
+
+	// Requests pile up here until the run loop scans them; wake tells it
+	// there are some. Each request takes the next generation, so a scan
+	// knows which requests came in after it started.
+	pendingMu   sync.Mutex
+	pendingFull uint64            // generation of the last full rescan request, 0 for none
+	pending     map[string]uint64 // generation of the last request by repo path
+	generation  uint64
+	wake        chan struct{}
+
+	updates chan []Repo
+	configs chan struct{}
+	done    chan struct{}
+
+	mu        sync.RWMutex
+	repos     []Repo
+	fetchErrs map[string]string // last background fetch error by repo path
+	// fetchCancel stops the background fetch running, nil while none is
+	fetchCancel context.CancelFunc
+
+	publishMu sync.Mutex // guards sends on updates and configs, and stopped
+	stopped   bool
+	fetching  atomic.Bool
+
+	watcher *Watcher
+	// cache, lastPublish and nested are only touched from the run loop
+	// goroutine
+	cache       map[string]cacheEntry
+	lastPublish time.Time
+	nested      nestedRepos // nested repos found by the last full scan
+	cacheHits   atomic.Uint64
+	cacheMisses atomic.Uint64
+}
+
+// repoSignature captures everything that can change a repo's status:
+// the mtimes of the index, HEAD, the branch and its upstream's refs,
+// packed-refs and FETCH_HEAD, plus the watcher's worktree generation.
+type repoSignature struct {
+	index     time.Time
+	head      time.Time
+	branch    time.Time
+	upstream  time.Time
+	packed    time.Time
+	fetchHead time.Time
+	gen       uint64
+}
+
+type cacheEntry struct {
+	sig  repoSignature
+	repo Repo
+}
+
+// Options configures a Service.
+type Options struct {
+	Scan            ScanOptions
+	PollInterval    time.Duration // rescan period, 0 disables polling
+	MinScanInterval time.Duration // least time between scans; requests meanwhile wait and coalesce
+	FetchInterval   time.Duration // background fetch period, 0 disables it
+	FetchWorkers    int           // concurrent fetches
+	RootName        string        // display name for a repo at the root
+	NoWatch         bool          // rely on polling, no file watcher
+	WatchIgnore     []string      // globs of paths whose changes wait for the next poll, see MatchGlob
+	Offline         bool          // never fetch
+	ConfigFiles     []string      // files reported on ConfigChanges
+	Logger          *log.Logger   // debug output, nil for none
+}
+
+func NewService(root string, opts Options) *Service {
+	if opts.FetchWorkers < 1 {
+		opts.FetchWorkers = 1
+	}
+	return &Service{
+		root:      root,
+		opts:      opts,
+		pending:   map[string]uint64{},
+		wake:      make(chan struct{}, 1),
+		updates:   make(chan []Repo, 1),
+		configs:   make(chan struct{}, 1),
+		done:      make(chan struct{}),
+		cache:     map[string]cacheEntry{},
+		fetchErrs: map[string]string{},
+	}
+}
+
+// Watch starts a service for root and returns it. Snapshots arrive on
+// Updates until Stop is called.
+func Watch(root string, opts Options) *Service {
+	s := NewService(root, opts)
+	s.Start()
+	s.Refresh()
+	return s
+}
+
+// Start runs the service loop until Stop is called. Without a working
+// file watcher the service still works, relying on polling alone.
+func (s *Service) Start() {
+	if !s.opts.NoWatch {
+		if w, err := NewWatcher(s.RefreshRepo); err == nil {
+			w.logger = s.opts.Logger
+			w.ignore = s.opts.WatchIgnore
+			s.watcher = w
+			for _, path := range s.opts.ConfigFiles {
+				_ = w.WatchFile(path, s.configChanged)
+			}
+		}
+	}
+	go s.run()
+}
+
+// Stop ends the service. Updates and ConfigChanges are closed, so their
+// consumers see the end; nothing is published after this.
+func (s *Service) Stop() {
+	close(s.done)
+	if s.watcher != nil {
+		_ = s.watcher.Close()
+	}
+	s.publishMu.Lock()
+	defer s.publishMu.Unlock()
+	s.stopped = true
+	close(s.updates)
+	close(s.configs)
+}
+
+// Refresh requests a full rescan of the scan root.
+func (s *Service) Refresh() {
+	s.request("")
+}
+
+// RefreshRepo requests a rescan of a single repo, or of everything when
+// repoPath is empty.
+func (s *Service) RefreshRepo(repoPath string) {
+	s.request(repoPath)
+}
+
+// request records a rescan for the run loop. It never blocks, however
+// many come in: repeated requests for a repo collapse into one.
+func (s *Service) request(repoPath string) {
+	s.pendingMu.Lock()
+	s.generation++
+	if repoPath == "" {
+		s.pendingFull = s.generation
+	} else {
+		s.pending[repoPath] = s.generation
+	}
+	s.pendingMu.Unlock()
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// takePending hands the requests made so far to a scan, along with the
+// generation they go up to.
+func (s *Service) takePending() (full bool, paths map[string]bool, gen uint64) {
+	s.pendingMu.Lock()
+	defer s.pendingMu.Unlock()
+	full = s.pendingFull != 0
+	paths = make(map[string]bool, len(s.pending))
+	for path := range s.pending {
+		paths[path] = true
+	}
+	s.pendingFull, s.pending = 0, map[string]uint64{}
+	return full, paths, s.generation
+}
+
+// superseded reports whether every repo a scan covered was asked for again
+// after generation gen, so another scan of them is already due.
+func (s *Service) superseded(full bool, paths map[string]bool, gen uint64) bool {
+	s.pendingMu.Lock()
+	defer s.pendingMu.Unlock()
+	if s.pendingFull > gen {
+		return true
+	}
+	if full || len(paths) == 0 {
+		return false
+	}
+	for path := range paths {
+		if s.pending[path] <= gen {
+			return false
+		}
+	}
+	return true
+}
+
+// Updates delivers a snapshot after every scan. Only the latest snapshot
+// is kept if the consumer falls behind.
+func (s *Service) Updates() <-chan []Repo {
+	return s.updates
+}
+
+// ConfigChanges signals whenever one of Options.ConfigFiles is saved.
+// Bursts of writes collapse into a single pending signal.
+func (s *Service) ConfigChanges() <-chan struct{} {
+	return s.configs
+}
+
+func (s *Service) configChanged() {
+	s.publishMu.Lock()
+	defer s.publishMu.Unlock()
+	if s.stopped {
+		return
+	}
+	select {
+	case s.configs <- struct{}{}:
+	default:
+	}
+}
+
+// ScanOnce scans every repo synchronously, for the non-interactive modes
+// that don't run the service loop.
+func (s *Service) ScanOnce() []Repo {
+	start := time.Now()
+	repos, _ := scanReposWith(s.root, s.opts.Scan, buildRepo)
+	s.logf("scan: %d repos in %v", len(repos), time.Since(start).Round(time.Millisecond))
+	s.publish(repos)
+	return s.Snapshot()
+}
+
+// limitPollInterval is how often repos the watch limit left partly
+// unwatched are rescanned when polling is off.
+const limitPollInterval = 10 * time.Second
+
+// maxStale is how long results can be dropped for newer requests before
+// one is published anyway, so a long burst of changes (an npm install)
+// doesn't freeze the snapshot.
+const maxStale = 2 * time.Second
+
+// CacheStats returns how many poll-time repo builds were served from the
+// status cache, and how many had to run git.
+func (s *Service) CacheStats() (hits, misses uint64) {
+	return s.cacheHits.Load(), s.cacheMisses.Load()
+}
+
+func hitRate(hits, misses uint64) float64 {
+	if hits+misses == 0 {
+		return 0
+	}
+	return 100 * float64(hits) / float64(hits+misses)
+}
+
+func (s *Service) logf(format string, args ...any) {
+	if s.opts.Logger != nil {
+		s.opts.Logger.Printf(format, args...)
+	}
+}
+
+// Snapshot returns a copy of the current repo state.
+func (s *Service) Snapshot() []Repo {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]Repo(nil), s.repos...)
+}
+
+// Fetching reports whether a background fetch is running.
+func (s *Service) Fetching() bool {
+	return s.fetching.Load()
+}
+
+// FetchAll starts a background fetch of every repo unless one is running.
+func (s *Service) FetchAll() {
+	if s.opts.Offline || !s.fetching.CompareAndSwap(false, true) {
+		return
+	}
+	go s.fetchAll()
+}
+
+// CancelFetch stops the background fetch running, if any. Repos it
+// hadn't fetched yet keep their last fetch error.
+func (s *Service) CancelFetch() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fetchCancel != nil {
+		s.fetchCancel()
+	}
+}
+
+// fetchAll runs git fetch across all repos with a bounded worker pool,
+// then requests a rescan so ahead/behind and fetch times update. Stop and
+// CancelFetch kill the fetches still running.
+func (s *Service) fetchAll() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s.mu.Lock()
+	s.fetchCancel = cancel
+	s.mu.Unlock()
+	go func() {
+		select {
+		case <-s.done:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	repos := s.Snapshot()
+	// Publish so the UI shows the fetch indicator right away
+	s.publish(repos)
+
+	jobs := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < s.opts.FetchWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for path := range jobs {
+				err := GitFetch(ctx, path)
+				if ctx.Err() != nil {
+					continue
+				}
+				s.mu.Lock()
+				if err != nil {
+					s.fetchErrs[path] = err.Error()
+				} else {
+					delete(s.fetchErrs, path)
+				}
+				s.mu.Unlock()
+			}
+		}()
+	}
+	for _, r := range repos {
+		if ctx.Err() != nil {
+			break
+		}
+		jobs <- r.Path
+	}
+	close(jobs)
+	wg.Wait()
+
+	s.mu.Lock()
+	s.fetchCancel = nil
+	s.mu.Unlock()
+	s.fetching.Store(false)
+	s.Refresh()
+}
+
+func (s *Service) run() {
+	var tick, fetchTick <-chan time.Time
+	if s.opts.PollInterval > 0 {
+		ticker := time.NewTicker(s.opts.PollInterval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	if s.opts.FetchInterval > 0 && !s.opts.Offline {
+		ticker := time.NewTicker(s.opts.FetchInterval)
+		defer ticker.Stop()
+		fetchTick = ticker.C
+	}
+
+	// Repos the watch limit left partly unwatched are polled even with
+	// polling off
+	var limitTick <-chan time.Time
+	if s.opts.PollInterval == 0 && s.watcher != nil {
+		ticker := time.NewTicker(limitPollInterval)
+		defer ticker.Stop()
+		limitTick = ticker.C
+	}
+	// due fires once the requests waiting may be scanned, nil while none
+	// are waiting
+	var due <-chan time.Time
+	var lastScan time.Time
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-fetchTick:
+			s.FetchAll()
+		case <-tick:
+			s.publish(s.scanAll(true))
+			lastScan, s.lastPublish = time.Now(), time.Now()
+		case <-limitTick:
+			for _, path := range s.watcher.LimitedRepos() {
+				s.request(path)
+			}
+		case <-s.wake:
+			if due == nil {
+				due = time.After(s.opts.MinScanInterval - time.Since(lastScan))
+			}
+		case <-due:
+			due = nil
+			s.scanPending()
+			lastScan = time.Now()
+		}
+	}
+}
+
+// scanPending runs one scan for every request made so far. Its result is
+// dropped when all of it was asked for again while it ran, since the scan
+// those requests are waiting for supersedes it.
+func (s *Service) scanPending() {
+	full, paths, gen := s.takePending()
+	var repos []Repo
+	if full {
+		repos = s.scanAll(false)
+	} else {
+		repos = s.scanRepos(paths)
+	}
+	if s.superseded(full, paths, gen) && time.Since(s.lastPublish) < maxStale {
+		s.logf("scan: dropped, %d repos changed again meanwhile", len(paths))
+		return
+	}
+	s.publish(repos)
+	s.lastPublish = time.Now()
+}
+
+// scanAll rescans every repo. Poll ticks pass cached=true so repos whose
+// signature is unchanged skip git entirely; explicit refreshes always run
+// git since they may follow changes the signature doesn't cover (config,
+// remotes added or removed). Only explicit refreshes walk the repos for
+// nested ones; polls reuse what the last walk found, and the watcher asks
+// for a full rescan when a repo turns up inside a watched one.
+func (s *Service) scanAll(cached bool) []Repo {
+	build := s.buildFresh
+	if cached {
+		build = s.buildCached
+	} else {
+		s.nested.known = false
+	}
+	start := time.Now()
+	hits, misses := s.CacheStats()
+	opts := s.opts.Scan
+	opts.nested = &s.nested
+	repos, _ := scanReposWith(s.root, opts, build)
+	if cached {
+		h, m := s.CacheStats()
+		s.logf("poll scan: %d repos in %v, cache %d hit / %d miss (%.0f%% overall)",
+			len(repos), time.Since(start).Round(time.Millisecond), h-hits, m-misses, hitRate(h, m))
+	} else {
+		s.logf("full scan: %d repos in %v", len(repos), time.Since(start).Round(time.Millisecond))
+	}
+	if s.watcher != nil {
+		if !cached {
+			s.watcher.RetryLimited()
+		}
+		paths := make([]string, len(repos))
+		for i, r := range repos {
+			paths[i] = r.Path
+		}
+		s.watcher.Sync(paths)
+	}
+	return repos
+}
+
+// signature returns the repo's current signature, and false when it can't
+// be trusted because the worktree isn't fully watched. The branch and
+// upstream refs are the ones known, the repo as last built.
+func (s *Service) signature(repoPath string, known Repo) (repoSignature, bool) {
+	if s.watcher == nil {
+		return repoSignature{}, false
+	}
+	gen, watched := s.watcher.Generation(repoPath)
+	if !watched {
+		return repoSignature{}, false
+	}
+	mtime := func(path ...string) time.Time {
+		if info, err := os.Stat(filepath.Join(path...)); err == nil {
+			return info.ModTime()
+		}
+		return time.Time{}
+	}
+	gitDir := GitDir(repoPath)
+	common := commonDir(gitDir)
+	sig := repoSignature{
+		gen:       gen,
+		index:     mtime(gitDir, "index"),
+		head:      mtime(gitDir, "HEAD"),
+		packed:    mtime(common, "packed-refs"),
+		fetchHead: mtime(common, "FETCH_HEAD"),
+	}
+	if known.Branch != "" && known.Detached == "" {
+		sig.branch = mtime(common, "refs", "heads", filepath.FromSlash(known.Branch))
+	}
+	if known.Upstream != "" {
+		sig.upstream = mtime(common, "refs", "remotes", filepath.FromSlash(known.Upstream))
+	}
+	return sig, true
+}
+
+// commonDir returns the directory a worktree's git dir shares refs with:
+// the main repo's for a linked worktree, otherwise gitDir itself.
+func commonDir(gitDir string) string {
+	data, err := os.ReadFile(filepath.Join(gitDir, "commondir"))
+	if err != nil {
+		return gitDir
+	}
+	dir := strings.TrimSpace(string(data))
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(gitDir, dir)
+	}
+	return dir
+}
+
+func (s *Service) buildCached(root, repoPath string) Repo {
+	e, hit := s.cache[repoPath]
+	sig, ok := s.signature(repoPath, e.repo)
+	if ok {
+		if hit && e.sig == sig {
+			s.cacheHits.Add(1)
+			return e.repo
+		}
+	}
+	s.cacheMisses.Add(1)
+	repo := buildRepo(root, repoPath)
+	if ok && !repo.Unavailable {
+		s.cache[repoPath] = cacheEntry{sig: sig, repo: repo}
+	}
+	return repo
+}
+
+func (s *Service) buildFresh(root, repoPath string) Repo {
+	// Take the signature before running git so changes made meanwhile
+	// invalidate the entry on the next poll
+	sig, ok := s.signature(repoPath, s.cache[repoPath].repo)
+	repo := buildRepo(root, repoPath)
+	if ok && !repo.Unavailable {
+		s.cache[repoPath] = cacheEntry{sig: sig, repo: repo}
+	} else {
+		delete(s.cache, repoPath)
+	}
+	return repo
+}
+
+// scanRepos rebuilds only the given repos in the current snapshot.
+func (s *Service) scanRepos(paths map[string]bool) []Repo {
+	root, err := filepath.Abs(s.root)
+	if err != nil {
+		return s.scanAll(false)
+	}
+	repos := s.Snapshot()
+	for i := range repos {
+		if paths[repos[i].Path] {
+			repos[i] = s.buildFresh(root, repos[i].Path)
+		}
+	}
+	return repos
+}
+
+func (s *Service) publish(repos []Repo) {
+	if s.opts.RootName != "" {
+		for i := range repos {
+			if repos[i].IsRoot {
+				repos[i].RelPath = s.opts.RootName
+			}
+		}
+	}
+
+	s.mu.Lock()
+	for i := range repos {
+		repos[i].FetchError = s.fetchErrs[repos[i].Path]
+		repos[i].WatchLimited = s.watcher != nil && s.watcher.Limited(repos[i].Path)
+	}
+	s.repos = repos
+	s.mu.Unlock()
+
+	snapshot := append([]Repo(nil), repos...)
+	s.publishMu.Lock()
+	defer s.publishMu.Unlock()
+	if s.stopped {
+		return
+	}
+	select {
+	case <-s.updates: // drop a stale snapshot nobody read yet
+	default:
+	}
+	s.updates <- snapshot
+}
@@ -0,0 +1,113 @@
+package sidegit
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// CommitDetail is a commit with everything the commit view shows. Its
+// diffs are against the first parent, or against nothing for a root
+// commit.
+type CommitDetail struct {
+	Hash    string
+	Short   string
+	Author  string // "name <email>"
+	Date    string
+	Message string
+	Stat    string // colored diffstat
+	Files   []string
+	parent  string
+}
+
+// GetCommit loads hash's message, author, diffstat and changed files.
+func GetCommit(repoPath, hash string) (CommitDetail, error) {
+	out, err := exec.Command("git", RepoArgs(repoPath, "show", "-s", "--date=format:%Y-%m-%d %H:%M",
+		"--format=%H%x1f%h%x1f%an <%ae>%x1f%ad%x1f%B", hash)...).Output()
+	if err != nil {
+		return CommitDetail{}, fmt.Errorf("git show %s: %v", hash, err)
+	}
+	parts := strings.SplitN(string(out), "\x1f", 5)
+	if len(parts) != 5 {
+		return CommitDetail{}, fmt.Errorf("git show %s: unexpected output", hash)
+	}
+	c := CommitDetail{
+		Hash:    parts[0],
+		Short:   parts[1],
+		Author:  parts[2],
+		Date:    parts[3],
+		Message: strings.TrimRight(parts[4], "\n"),
+	}
+
+	c.parent = c.Hash + "^"
+	if exec.Command("git", RepoArgs(repoPath, "rev-parse", "--verify", "-q", c.parent)...).Run() != nil {
+		// A root commit: diff against the empty tree
+		empty, err := emptyTree(repoPath)
+		if err != nil {
+			return c, err
+		}
+		c.parent = empty
+	}
+
+	stat, err := exec.Command("git", RepoArgs(repoPath, "diff", "--stat", "--color=always", c.parent, c.Hash)...).Output()
+	if err != nil {
+		return c, fmt.Errorf("git diff --stat: %v", err)
+	}
+	c.Stat = strings.TrimRight(string(stat), "\n")
+
+	names, err := exec.Command("git", RepoArgs(repoPath, "diff", "--name-only", "-z", c.parent, c.Hash)...).Output()
+	if err != nil {
+		return c, fmt.Errorf("git diff --name-only: %v", err)
+	}
+	for _, name := range strings.Split(string(names), "\x00") {
+		if name != "" {
+			c.Files = append(c.Files, name)
+		}
+	}
+	return c, nil
+}
+
+// emptyTree returns the hash of the empty tree, to diff a root commit or
+// a repo without commits against.
+func emptyTree(repoPath string) (string, error) {
+	empty, err := exec.Command("git", RepoArgs(repoPath, "hash-object", "-t", "tree", os.DevNull)...).Output()
+	if err != nil {
+		return "", fmt.Errorf("git hash-object: %v", err)
+	}
+	return strings.TrimSpace(string(empty)), nil
+}
+
+// CommitFileDiff returns the diff of one file in commit c.
+func CommitFileDiff(repoPath string, c CommitDetail, filePath string, opts DiffOptions) (Diff, error) {
+	args := RepoArgs(repoPath, append([]string{"diff"}, opts.args()...)...)
+	args = append(args, c.parent, c.Hash, "--", filePath)
+	d, err := readDiff(context.Background(), opts, args...)
+	if err != nil {
+		return Diff{}, fmt.Errorf("git diff failed: %w", err)
+	}
+	return d, nil
+}
+
+// CheckoutFileAt writes filePath as it was at commit hash to a temporary
+// directory, leaving the worktree alone, and returns where it went.
+func CheckoutFileAt(repoPath, hash, filePath string) (string, error) {
+	out, err := exec.Command("git", RepoArgs(repoPath, "show", hash+":"+filePath)...).Output()
+	if err != nil {
+		return "", fmt.Errorf("%s doesn't exist at %s", filePath, hash)
+	}
+	dir, err := os.MkdirTemp("", "sidegit-"+hash+"-")
+	if err != nil {
+		return "", err
+	}
+	dest := filepath.Join(dir, filepath.FromSlash(filePath))
+	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(dest, out, 0o644); err != nil {
+		return "", err
+	}
+	return dest, nil
+}
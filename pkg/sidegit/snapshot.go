@@ -0,0 +1,212 @@
+package sidegit
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// snapshotRefs is where snapshots are kept: refs outside refs/stash, so
+// they never show up in (or get dropped from) the user's stash list.
+const snapshotRefs = "refs/sidegit/snapshots/"
+
+// Snapshot is a stash commit recorded by TakeSnapshot. Like one made by
+// `git stash -u` it holds the staged and unstaged changes to tracked files
+// at that time, and the untracked files in a third parent.
+type Snapshot struct {
+	Ref  string
+	Hash string
+	When time.Time
+}
+
+// TakeSnapshot records the repo's current changes without touching the
+// worktree, the index or the stash list. It returns false when there are
+// no changes, or none since the last snapshot.
+func TakeSnapshot(repoPath string) (bool, error) {
+	out, err := exec.Command("git", RepoArgs(repoPath, "stash", "create", "sidegit snapshot")...).Output()
+	if err != nil {
+		return false, fmt.Errorf("git stash create: %v", err)
+	}
+	hash := strings.TrimSpace(string(out))
+	untracked, err := untrackedCommit(repoPath)
+	if err != nil {
+		return false, err
+	}
+	if untracked != "" {
+		if hash, err = addUntracked(repoPath, hash, untracked); err != nil {
+			return false, err
+		}
+	}
+	if hash == "" {
+		return false, nil
+	}
+	snaps, err := ListSnapshots(repoPath)
+	if err != nil {
+		return false, err
+	}
+	if len(snaps) > 0 && snapshotTrees(repoPath, snaps[0].Hash) == snapshotTrees(repoPath, hash) {
+		return false, nil
+	}
+	now := time.Now()
+	ref := snapshotRefs + strconv.FormatInt(now.UnixNano(), 10)
+	cmd := exec.Command("git", RepoArgs(repoPath, "update-ref", "-m", "sidegit snapshot", ref, hash)...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return false, fmt.Errorf("git update-ref: %s", strings.TrimSpace(string(out)))
+	}
+	return true, nil
+}
+
+// untrackedCommit commits the repo's untracked files, those git status
+// lists, to a root commit of their own without touching the index. It
+// returns "" when there are none, and always for a BareRepo.
+func untrackedCommit(repoPath string) (string, error) {
+	if _, bare := lookupBareRepo(repoPath); bare {
+		// Everything in a dotfiles repo's work tree is untracked
+		return "", nil
+	}
+	files, err := exec.Command("git", RepoArgs(repoPath, "ls-files", "--others", "--exclude-standard", "-z")...).Output()
+	if err != nil {
+		return "", fmt.Errorf("git ls-files: %v", err)
+	}
+	if len(files) == 0 {
+		return "", nil
+	}
+	// A scratch index; git creates the file itself
+	f, err := os.CreateTemp("", "sidegit-index-")
+	if err != nil {
+		return "", err
+	}
+	index := f.Name()
+	f.Close()
+	os.Remove(index)
+	defer os.Remove(index)
+	env := append(os.Environ(), "GIT_INDEX_FILE="+index)
+
+	add := exec.Command("git", RepoArgs(repoPath, "update-index", "--add", "-z", "--stdin")...)
+	add.Env = env
+	add.Stdin = strings.NewReader(string(files))
+	if out, err := add.CombinedOutput(); err != nil {
+		return "", fmt.Errorf("git update-index: %s", strings.TrimSpace(string(out)))
+	}
+	write := exec.Command("git", RepoArgs(repoPath, "write-tree")...)
+	write.Env = env
+	tree, err := write.Output()
+	if err != nil {
+		return "", fmt.Errorf("git write-tree: %v", err)
+	}
+	return commitTree(repoPath, strings.TrimSpace(string(tree)), "untracked files")
+}
+
+// addUntracked returns stash, a `git stash create` commit or "" when only
+// untracked files changed, with untracked as its third parent, the way
+// `git stash -u` records them.
+func addUntracked(repoPath, stash, untracked string) (string, error) {
+	if stash == "" {
+		// The worktree and the index both match HEAD
+		index, err := commitTree(repoPath, "HEAD^{tree}", "index", "-p", "HEAD")
+		if err != nil {
+			return "", err
+		}
+		return commitTree(repoPath, "HEAD^{tree}", "sidegit snapshot", "-p", "HEAD", "-p", index, "-p", untracked)
+	}
+	return commitTree(repoPath, stash+"^{tree}", "sidegit snapshot", "-p", stash+"^1", "-p", stash+"^2", "-p", untracked)
+}
+
+func commitTree(repoPath, tree, message string, parents ...string) (string, error) {
+	args := append([]string{"commit-tree", tree, "-m", message}, parents...)
+	cmd := exec.Command("git", RepoArgs(repoPath, args...)...)
+	if exec.Command("git", RepoArgs(repoPath, "var", "GIT_COMMITTER_IDENT")...).Run() != nil {
+		// Like git stash, work without a configured identity
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME=sidegit", "GIT_AUTHOR_EMAIL=sidegit@localhost",
+			"GIT_COMMITTER_NAME=sidegit", "GIT_COMMITTER_EMAIL=sidegit@localhost")
+	}
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git commit-tree: %v", err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// snapshotTrees identifies a snapshot's content: its worktree tree and the
+// tree of its untracked files, if it has any.
+func snapshotTrees(repoPath, commit string) string {
+	return treeOf(repoPath, commit) + " " + treeOf(repoPath, commit+"^3")
+}
+
+func treeOf(repoPath, commit string) string {
+	out, err := exec.Command("git", RepoArgs(repoPath, "rev-parse", "--verify", "-q", commit+"^{tree}")...).Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// ListSnapshots returns the repo's snapshots, newest first.
+func ListSnapshots(repoPath string) ([]Snapshot, error) {
+	// The ref names are timestamps of the same length, so sorting them
+	// sorts by age
+	cmd := exec.Command("git", RepoArgs(repoPath, "for-each-ref", "--sort=-refname", "--format=%(refname) %(objectname)", snapshotRefs)...)
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git for-each-ref: %v", err)
+	}
+	var snaps []Snapshot
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		ref, hash, ok := strings.Cut(line, " ")
+		if !ok {
+			continue
+		}
+		nanos, err := strconv.ParseInt(strings.TrimPrefix(ref, snapshotRefs), 10, 64)
+		if err != nil {
+			continue
+		}
+		snaps = append(snaps, Snapshot{Ref: ref, Hash: hash, When: time.Unix(0, nanos)})
+	}
+	return snaps, nil
+}
+
+// PruneSnapshots deletes all but the newest keep snapshots.
+func PruneSnapshots(repoPath string, keep int) error {
+	snaps, err := ListSnapshots(repoPath)
+	if err != nil || len(snaps) <= keep {
+		return err
+	}
+	for _, s := range snaps[keep:] {
+		if err := DeleteSnapshot(repoPath, s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func DeleteSnapshot(repoPath string, s Snapshot) error {
+	if out, err := exec.Command("git", RepoArgs(repoPath, "update-ref", "-d", s.Ref)...).CombinedOutput(); err != nil {
+		return fmt.Errorf("git update-ref -d: %s", strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// ApplySnapshot applies a snapshot's changes to the worktree, like
+// `git stash apply`, bringing back its untracked files too. Conflicting
+// changes are left as conflicts; git refuses to overwrite an untracked
+// file that exists again.
+func ApplySnapshot(repoPath string, s Snapshot) error {
+	if out, err := exec.Command("git", RepoArgs(repoPath, "stash", "apply", s.Hash)...).CombinedOutput(); err != nil {
+		return fmt.Errorf("git stash apply: %s", strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// SnapshotDiff returns the colored patch of a snapshot against the commit
+// it was taken on, untracked files included.
+func SnapshotDiff(repoPath string, s Snapshot) (string, error) {
+	out, err := exec.Command("git", RepoArgs(repoPath, "stash", "show", "-p", "--stat", "--include-untracked", "--color=always", s.Hash)...).Output()
+	if err != nil {
+		return "", fmt.Errorf("git stash show: %v", err)
+	}
+	return string(out), nil
+}
@@ -0,0 +1,44 @@
+package sidegit
+
+import (
+	"errors"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// WatchBackend delivers the file system events a Watcher works from.
+// Every platform can watch single directories; where the system offers it
+// (FSEvents on macOS, ReadDirectoryChangesW on Windows) a backend also
+// watches whole trees, which needs no registration per directory and
+// misses no directory created deep in a tree.
+type WatchBackend interface {
+	// Add watches a single directory, or a file.
+	Add(path string) error
+	// AddTree watches dir and everything below it, or returns
+	// ErrNotRecursive.
+	AddTree(dir string) error
+	Events() <-chan fsnotify.Event
+	Errors() <-chan error
+	Close() error
+}
+
+// ErrNotRecursive is returned by AddTree when a backend can only watch
+// directories one by one.
+var ErrNotRecursive = errors.New("recursive watching not supported")
+
+// fsnotifyBackend watches directories one by one with fsnotify: inotify on
+// Linux, kqueue on macOS and the BSDs.
+type fsnotifyBackend struct {
+	w *fsnotify.Watcher
+}
+
+func newFSNotifyBackend() (fsnotifyBackend, error) {
+	w, err := fsnotify.NewWatcher()
+	return fsnotifyBackend{w}, err
+}
+
+func (b fsnotifyBackend) Add(path string) error         { return b.w.Add(path) }
+func (b fsnotifyBackend) AddTree(string) error          { return ErrNotRecursive }
+func (b fsnotifyBackend) Events() <-chan fsnotify.Event { return b.w.Events }
+func (b fsnotifyBackend) Errors() <-chan error          { return b.w.Errors }
+func (b fsnotifyBackend) Close() error                  { return b.w.Close() }
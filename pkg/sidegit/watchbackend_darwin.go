@@ -0,0 +1,115 @@
+//go:build darwin && cgo
+
+package sidegit
+
+/*
+#cgo LDFLAGS: -framework CoreServices
+#include <stdlib.h>
+#include <CoreServices/CoreServices.h>
+#include <dispatch/dispatch.h>
+
+extern void sidegitFSEvents(ConstFSEventStreamRef stream, void* info, size_t n, void* paths, FSEventStreamEventFlags* flags, FSEventStreamEventId* ids);
+*/
+import "C"
+
+import (
+	"errors"
+	"os"
+	"sync"
+	"unsafe"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// fsEventsLatency is how long, in seconds, FSEvents gathers changes before
+// reporting them.
+const fsEventsLatency = 0.05
+
+// fsEventsSinceNow is kFSEventStreamEventIdSinceNow, which cgo reads as -1.
+const fsEventsSinceNow = C.FSEventStreamEventId(1<<64 - 1)
+
+var (
+	fsEventsMu      sync.Mutex
+	fsEventsStreams = map[uintptr]func(fsnotify.Event){} // stream -> where its events go
+	fsEventsQueue   C.dispatch_queue_t
+	fsEventsOnce    sync.Once
+)
+
+// watchTree watches dir and everything below it with an FSEvents stream,
+// passing its changes to send until stop is called.
+func watchTree(dir string, send func(fsnotify.Event), sendError func(error)) (stop func(), err error) {
+	fsEventsOnce.Do(func() {
+		name := C.CString("sidegit.fsevents")
+		defer C.free(unsafe.Pointer(name))
+		fsEventsQueue = C.dispatch_queue_create(name, nil)
+	})
+
+	cdir := C.CString(dir)
+	defer C.free(unsafe.Pointer(cdir))
+	path := C.CFStringCreateWithCString(C.kCFAllocatorDefault, cdir, C.kCFStringEncodingUTF8)
+	defer C.CFRelease(C.CFTypeRef(path))
+	paths := C.CFArrayCreate(C.kCFAllocatorDefault, (*unsafe.Pointer)(unsafe.Pointer(&path)), 1, &C.kCFTypeArrayCallBacks)
+	defer C.CFRelease(C.CFTypeRef(paths))
+
+	stream := C.FSEventStreamCreate(C.kCFAllocatorDefault, C.FSEventStreamCallback(C.sidegitFSEvents), nil, paths,
+		fsEventsSinceNow, C.CFTimeInterval(fsEventsLatency),
+		C.kFSEventStreamCreateFlagFileEvents|C.kFSEventStreamCreateFlagNoDefer|C.kFSEventStreamCreateFlagWatchRoot)
+	if stream == nil {
+		return nil, errors.New("can't watch " + dir + " with FSEvents")
+	}
+	key := uintptr(unsafe.Pointer(stream))
+	fsEventsMu.Lock()
+	fsEventsStreams[key] = send
+	fsEventsMu.Unlock()
+
+	C.FSEventStreamSetDispatchQueue(stream, fsEventsQueue)
+	if C.FSEventStreamStart(stream) == 0 {
+		fsEventsMu.Lock()
+		delete(fsEventsStreams, key)
+		fsEventsMu.Unlock()
+		C.FSEventStreamInvalidate(stream)
+		C.FSEventStreamRelease(stream)
+		return nil, errors.New("can't start watching " + dir + " with FSEvents")
+	}
+	return func() {
+		C.FSEventStreamStop(stream)
+		C.FSEventStreamInvalidate(stream)
+		fsEventsMu.Lock()
+		delete(fsEventsStreams, key)
+		fsEventsMu.Unlock()
+		C.FSEventStreamRelease(stream)
+	}, nil
+}
+
+//export sidegitFSEvents
+func sidegitFSEvents(stream C.ConstFSEventStreamRef, info unsafe.Pointer, n C.size_t, paths unsafe.Pointer, flags *C.FSEventStreamEventFlags, ids *C.FSEventStreamEventId) {
+	fsEventsMu.Lock()
+	send := fsEventsStreams[uintptr(unsafe.Pointer(stream))]
+	fsEventsMu.Unlock()
+	if send == nil {
+		return // stopped meanwhile
+	}
+	names := unsafe.Slice((**C.char)(paths), int(n))
+	for i, f := range unsafe.Slice(flags, int(n)) {
+		name := C.GoString(names[i])
+		send(fsnotify.Event{Name: name, Op: fsEventsOp(name, uint32(f))})
+	}
+}
+
+// fsEventsOp maps an FSEvents event's flags to the fsnotify operation it
+// stands for.
+func fsEventsOp(name string, flags uint32) fsnotify.Op {
+	switch {
+	case flags&C.kFSEventStreamEventFlagItemRemoved != 0:
+		return fsnotify.Remove
+	case flags&C.kFSEventStreamEventFlagItemRenamed != 0:
+		// Both ends of a rename are reported as renamed
+		if _, err := os.Lstat(name); err == nil {
+			return fsnotify.Create
+		}
+		return fsnotify.Rename
+	case flags&C.kFSEventStreamEventFlagItemCreated != 0:
+		return fsnotify.Create
+	}
+	return fsnotify.Write
+}
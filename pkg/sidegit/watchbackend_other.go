@@ -0,0 +1,9 @@
+//go:build !windows && !(darwin && cgo)
+
+package sidegit
+
+// newWatchBackend returns the fsnotify backend; this platform has no
+// recursive watching to use.
+func newWatchBackend() (WatchBackend, error) {
+	return newFSNotifyBackend()
+}
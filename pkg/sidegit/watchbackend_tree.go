@@ -0,0 +1,101 @@
+//go:build windows || (darwin && cgo)
+
+package sidegit
+
+import (
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// treeBackend watches whole trees with the system's recursive API (see
+// watchTree, per platform), and single directories and files with
+// fsnotify. Their events arrive merged on one channel.
+type treeBackend struct {
+	single fsnotifyBackend
+	events chan fsnotify.Event
+	errors chan error
+	done   chan struct{}
+
+	mu    sync.Mutex
+	trees map[string]func() // watched tree -> its stop function
+}
+
+func newWatchBackend() (WatchBackend, error) {
+	single, err := newFSNotifyBackend()
+	if err != nil {
+		return nil, err
+	}
+	b := &treeBackend{
+		single: single,
+		events: make(chan fsnotify.Event),
+		errors: make(chan error),
+		done:   make(chan struct{}),
+		trees:  map[string]func(){},
+	}
+	go b.forward()
+	return b, nil
+}
+
+// forward passes fsnotify's events on until it's closed.
+func (b *treeBackend) forward() {
+	defer close(b.events)
+	defer close(b.errors)
+	for {
+		select {
+		case ev, open := <-b.single.Events():
+			if !open {
+				return
+			}
+			b.send(ev)
+		case err, open := <-b.single.Errors():
+			if !open {
+				return
+			}
+			b.sendError(err)
+		}
+	}
+}
+
+func (b *treeBackend) send(ev fsnotify.Event) {
+	select {
+	case b.events <- ev:
+	case <-b.done:
+	}
+}
+
+func (b *treeBackend) sendError(err error) {
+	select {
+	case b.errors <- err:
+	case <-b.done:
+	}
+}
+
+func (b *treeBackend) Add(path string) error         { return b.single.Add(path) }
+func (b *treeBackend) Events() <-chan fsnotify.Event { return b.events }
+func (b *treeBackend) Errors() <-chan error          { return b.errors }
+
+func (b *treeBackend) AddTree(dir string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.trees[dir]; ok {
+		return nil
+	}
+	stop, err := watchTree(dir, b.send, b.sendError)
+	if err != nil {
+		return err
+	}
+	b.trees[dir] = stop
+	return nil
+}
+
+func (b *treeBackend) Close() error {
+	close(b.done)
+	b.mu.Lock()
+	for dir, stop := range b.trees {
+		stop()
+		delete(b.trees, dir)
+	}
+	b.mu.Unlock()
+	return b.single.Close()
+}
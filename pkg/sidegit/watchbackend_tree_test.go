@@ -0,0 +1,61 @@
+//go:build windows || (darwin && cgo)
+
+package sidegit
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTreeBackend(t *testing.T) {
+	dir, err := filepath.EvalSymlinks(t.TempDir()) // FSEvents reports /private/var for /var
+	if err != nil {
+		t.Fatal(err)
+	}
+	sub := filepath.Join(dir, "a", "b")
+	if err := os.MkdirAll(sub, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	b, err := newWatchBackend()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := b.AddTree(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	// A change below the tree arrives without a watch of its own
+	file := filepath.Join(sub, "f.txt")
+	if err := os.WriteFile(file, []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	timeout := time.After(5 * time.Second)
+	for seen := false; !seen; {
+		select {
+		case ev := <-b.Events():
+			seen = ev.Name == file
+		case err := <-b.Errors():
+			t.Fatal(err)
+		case <-timeout:
+			t.Fatalf("no event for %s", file)
+		}
+	}
+
+	// Close ends the pending read rather than waiting for a change
+	closed := make(chan error)
+	go func() { closed <- b.Close() }()
+	go func() {
+		for range b.Events() {
+		}
+	}()
+	select {
+	case err := <-closed:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("Close hung")
+	}
+}
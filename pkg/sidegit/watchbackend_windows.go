@@ -0,0 +1,107 @@
+package sidegit
+
+import (
+	"errors"
+	"path/filepath"
+	"time"
+	"unsafe"
+
+	"github.com/fsnotify/fsnotify"
+	"golang.org/x/sys/windows"
+)
+
+// treeChanges is what ReadDirectoryChangesW reports for a tree.
+const treeChanges = windows.FILE_NOTIFY_CHANGE_FILE_NAME | windows.FILE_NOTIFY_CHANGE_DIR_NAME |
+	windows.FILE_NOTIFY_CHANGE_ATTRIBUTES | windows.FILE_NOTIFY_CHANGE_SIZE |
+	windows.FILE_NOTIFY_CHANGE_LAST_WRITE | windows.FILE_NOTIFY_CHANGE_CREATION
+
+// watchTree watches dir and everything below it with ReadDirectoryChangesW,
+// passing its changes to send until stop is called. Reads are overlapped,
+// so stop can cancel the one pending with CancelIoEx; closing the handle
+// under a synchronous read can block until the next change.
+func watchTree(dir string, send func(fsnotify.Event), sendError func(error)) (stop func(), err error) {
+	name, err := windows.UTF16PtrFromString(dir)
+	if err != nil {
+		return nil, err
+	}
+	h, err := windows.CreateFile(name, windows.FILE_LIST_DIRECTORY,
+		windows.FILE_SHARE_READ|windows.FILE_SHARE_WRITE|windows.FILE_SHARE_DELETE,
+		nil, windows.OPEN_EXISTING, windows.FILE_FLAG_BACKUP_SEMANTICS|windows.FILE_FLAG_OVERLAPPED, 0)
+	if err != nil {
+		return nil, err
+	}
+	ev, err := windows.CreateEvent(nil, 1, 0, nil)
+	if err != nil {
+		windows.CloseHandle(h)
+		return nil, err
+	}
+	closed := make(chan struct{})
+	ended := make(chan struct{})
+	go func() {
+		defer close(ended)
+		buf := make([]byte, 64*1024)
+		for {
+			ov := windows.Overlapped{HEvent: ev}
+			err := windows.ReadDirectoryChanges(h, &buf[0], uint32(len(buf)), true, treeChanges, nil, &ov, 0)
+			if err != nil && !errors.Is(err, windows.ERROR_IO_PENDING) {
+				sendError(err)
+				return
+			}
+			select {
+			case <-closed:
+				// stop may have run its CancelIoEx before this read began
+				_ = windows.CancelIoEx(h, &ov)
+			default:
+			}
+			var n uint32
+			err = windows.GetOverlappedResult(h, &ov, &n, true)
+			select {
+			case <-closed:
+				return
+			default:
+			}
+			if err != nil {
+				sendError(err)
+				return
+			}
+			if n == 0 {
+				// More changed than fit the buffer: say the whole tree did
+				send(fsnotify.Event{Name: dir, Op: fsnotify.Write})
+				continue
+			}
+			for off := uint32(0); ; {
+				info := (*windows.FileNotifyInformation)(unsafe.Pointer(&buf[off]))
+				file := windows.UTF16ToString(unsafe.Slice(&info.FileName, info.FileNameLength/2))
+				send(fsnotify.Event{Name: filepath.Join(dir, file), Op: treeOp(info.Action)})
+				if info.NextEntryOffset == 0 {
+					break
+				}
+				off += info.NextEntryOffset
+			}
+		}
+	}()
+	return func() {
+		close(closed)
+		_ = windows.CancelIoEx(h, nil)
+		// Don't hang on a read that doesn't end
+		select {
+		case <-ended:
+		case <-time.After(time.Second):
+		}
+		windows.CloseHandle(ev)
+		windows.CloseHandle(h)
+	}, nil
+}
+
+// treeOp maps a FILE_ACTION to the fsnotify operation it stands for.
+func treeOp(action uint32) fsnotify.Op {
+	switch action {
+	case windows.FILE_ACTION_ADDED, windows.FILE_ACTION_RENAMED_NEW_NAME:
+		return fsnotify.Create
+	case windows.FILE_ACTION_REMOVED:
+		return fsnotify.Remove
+	case windows.FILE_ACTION_RENAMED_OLD_NAME:
+		return fsnotify.Rename
+	}
+	return fsnotify.Write
+}
@@ -0,0 +1,361 @@
+package sidegit
+
+import (
+	"errors"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Watcher watches repo worktrees and their index/HEAD for changes. Each
+// repo has a generation counter that increases on every relevant event,
+// which callers use as a cheap signature of "something changed".
+//
+// Changes to paths the repo's .gitignore files ignore, or that match one of
+// the ignore globs, only move the generation: they don't call onChange, so
+// builds and package installs don't set off a rescan each, and polling
+// still picks up anything they do to the repo's status. A repo created
+// inside a watched one calls onChange with an empty path, asking for a
+// full rescan.
+//
+// Where the system can watch a whole tree at once (see WatchBackend), a
+// repo's worktree takes one registration; elsewhere each directory needs
+// its own.
+type Watcher struct {
+	fs       WatchBackend
+	onChange func(repoPath string)
+	logger   *log.Logger // nil for none
+	ignore   []string    // globs of paths whose changes don't call onChange, see MatchGlob
+
+	mu         sync.Mutex
+	repos      map[string]bool      // repo path -> fully registered
+	gens       map[string]uint64    // repo path -> change generation
+	files      map[string]func()    // watched file -> callback
+	reals      map[string]string    // real path -> repo path, for repos reached through a symlink
+	gitignores map[string]gitignore // repo path -> its ignore rules
+	limited    map[string]bool      // repos with directories left unwatched by the system's watch limit
+	trees      map[string]bool      // repos watched as a whole tree
+}
+
+func NewWatcher(onChange func(repoPath string)) (*Watcher, error) {
+	b, err := newWatchBackend()
+	if err != nil {
+		return nil, err
+	}
+	w := &Watcher{
+		fs:         b,
+		onChange:   onChange,
+		repos:      map[string]bool{},
+		gens:       map[string]uint64{},
+		files:      map[string]func(){},
+		reals:      map[string]string{},
+		gitignores: map[string]gitignore{},
+		limited:    map[string]bool{},
+		trees:      map[string]bool{},
+	}
+	go w.run()
+	return w, nil
+}
+
+func (w *Watcher) Close() error {
+	return w.fs.Close()
+}
+
+// Sync starts watching any repo in repoPaths that isn't watched yet.
+func (w *Watcher) Sync(repoPaths []string) {
+	for _, p := range repoPaths {
+		w.mu.Lock()
+		_, known := w.repos[p]
+		w.mu.Unlock()
+		if known {
+			continue
+		}
+		ok := w.addWatchPaths(p)
+		w.mu.Lock()
+		w.repos[p] = ok
+		w.mu.Unlock()
+	}
+}
+
+// RetryLimited watches the repos Limited reports again, for when watches
+// were freed or the limit raised. A repo that gets all of them is no
+// longer Limited.
+func (w *Watcher) RetryLimited() {
+	for _, p := range w.LimitedRepos() {
+		w.mu.Lock()
+		delete(w.limited, p) // add marks it again if it runs out
+		w.mu.Unlock()
+		ok := w.addWatchPaths(p)
+		w.mu.Lock()
+		w.repos[p] = ok
+		w.mu.Unlock()
+	}
+}
+
+// WatchFile calls onChange whenever path is written or replaced. The
+// parent directory is watched rather than the file itself, since editors
+// often save by writing a new file and renaming it over the old one.
+func (w *Watcher) WatchFile(path string, onChange func()) error {
+	if err := w.fs.Add(filepath.Dir(path)); err != nil {
+		return err
+	}
+	w.mu.Lock()
+	w.files[path] = onChange
+	w.mu.Unlock()
+	return nil
+}
+
+// Generation returns the change generation for a repo, and whether every
+// directory of the repo is being watched. A repo that isn't fully watched
+// can change without the generation moving.
+func (w *Watcher) Generation(repoPath string) (uint64, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.gens[repoPath], w.repos[repoPath]
+}
+
+// Limited reports whether some of the repo's directories went unwatched
+// because the system ran out of watches (on Linux, inotify's
+// max_user_watches). Polling has to pick up their changes.
+func (w *Watcher) Limited(repoPath string) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.limited[repoPath]
+}
+
+// LimitedRepos returns the repos Limited reports.
+func (w *Watcher) LimitedRepos() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var paths []string
+	for p := range w.limited {
+		paths = append(paths, p)
+	}
+	return paths
+}
+
+// add watches dir for repoPath, noting when the watch limit stops it.
+func (w *Watcher) add(repoPath, dir string) error {
+	err := w.fs.Add(dir)
+	if errors.Is(err, syscall.ENOSPC) {
+		w.mu.Lock()
+		if !w.limited[repoPath] && w.logger != nil {
+			w.logger.Printf("watch: out of watches at %s, polling %s instead", dir, repoPath)
+		}
+		w.limited[repoPath] = true
+		w.mu.Unlock()
+	}
+	return err
+}
+
+// addWatchPaths registers the repo's worktree as a tree, or else its .git
+// directory and every worktree directory, reading the .gitignore files on
+// the way. Ignored directories aren't watched one by one. It reports
+// whether all registrations succeeded, and whether every directory whose
+// changes show in git status got one.
+func (w *Watcher) addWatchPaths(repoPath string) bool {
+	if _, bare := lookupBareRepo(repoPath); bare {
+		// The work tree of a dotfiles repo is usually the home directory;
+		// watching all of it would exhaust the watch limit, so polling
+		// picks up its changes instead
+		_ = w.add(repoPath, GitDir(repoPath))
+		return false
+	}
+	// WalkDir doesn't descend into a symlink, so walk (and watch) the
+	// real directory of a repo that is linked into the workspace, and
+	// remember where its events belong
+	real, err := filepath.EvalSymlinks(repoPath)
+	if err != nil {
+		_ = w.add(repoPath, GitDir(repoPath))
+		return false
+	}
+	if real != repoPath {
+		w.mu.Lock()
+		w.reals[real] = repoPath
+		w.mu.Unlock()
+	}
+	// A tree takes in .git too
+	tree := w.fs.AddTree(real) == nil
+	w.mu.Lock()
+	w.trees[repoPath] = tree
+	w.mu.Unlock()
+	ok := tree || w.add(repoPath, GitDir(repoPath)) == nil
+
+	// The walk still reads the .gitignore files
+	rules := loadInfoExclude(repoPath)
+	_ = filepath.WalkDir(real, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if path != real && (d.Name() == ".git" || isGitRepo(path)) {
+			// Nested repos are watched on their own
+			return filepath.SkipDir
+		}
+		rel := relSlash(real, path)
+		if path != real {
+			if rules.ignored(rel, true) {
+				return filepath.SkipDir
+			}
+			if MatchGlob(w.ignore, rel) {
+				// Unlike an ignored directory, this one's changes can
+				// still show in git status, so only polling sees them
+				// unless the tree watch does
+				ok = ok && tree
+				return filepath.SkipDir
+			}
+		}
+		rules = rules.setFile(filepath.Join(path, ".gitignore"), rel)
+		if !tree && w.add(repoPath, path) != nil {
+			ok = false
+		}
+		return nil
+	})
+	w.mu.Lock()
+	w.gitignores[repoPath] = rules
+	w.mu.Unlock()
+	return ok
+}
+
+// relSlash returns path relative to dir, slash-separated, "" for dir itself.
+func relSlash(dir, path string) string {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil || rel == "." {
+		return ""
+	}
+	return filepath.ToSlash(rel)
+}
+
+// quiet reports whether a change to rel in repo shouldn't call onChange,
+// and whether that's because the repo ignores it rather than a glob. An
+// edited .gitignore is read again first.
+func (w *Watcher) quiet(repo, dir, path, rel string, isDir bool) (quiet, gitignored bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if filepath.Base(path) == ".gitignore" {
+		w.gitignores[repo] = w.gitignores[repo].setFile(path, relSlash(dir, filepath.Dir(path)))
+		// Directories it no longer ignores have no watch of their own
+		if !w.trees[repo] {
+			w.repos[repo] = false
+		}
+	}
+	if w.gitignores[repo].ignored(rel, isDir) {
+		return true, true
+	}
+	return MatchGlob(w.ignore, rel), false
+}
+
+func (w *Watcher) run() {
+	for {
+		select {
+		case ev, open := <-w.fs.Events():
+			if !open {
+				return
+			}
+			w.handle(ev)
+		case _, open := <-w.fs.Errors():
+			if !open {
+				return
+			}
+		}
+	}
+}
+
+func (w *Watcher) handle(ev fsnotify.Event) {
+	w.mu.Lock()
+	onFile := w.files[ev.Name]
+	w.mu.Unlock()
+	if onFile != nil {
+		if !ev.Has(fsnotify.Remove) {
+			onFile()
+		}
+		return
+	}
+
+	repo, dir := w.repoFor(ev.Name)
+	if repo == "" {
+		return
+	}
+
+	// Inside .git only the index and HEAD matter; lock files and object
+	// writes churn constantly during normal git operations
+	gitDir := GitDir(repo)
+	if dir != repo {
+		gitDir = filepath.Join(dir, ".git")
+	}
+	if filepath.Dir(ev.Name) == gitDir {
+		base := filepath.Base(ev.Name)
+		if base != "index" && base != "HEAD" {
+			return
+		}
+	} else if strings.HasPrefix(ev.Name, gitDir+string(filepath.Separator)) {
+		return // deeper in .git, which only a tree watch sees
+	}
+
+	info, err := os.Stat(ev.Name)
+	isDir := err == nil && info.IsDir()
+	if ev.Has(fsnotify.Create) && isDir && ev.Name != gitDir &&
+		(filepath.Base(ev.Name) == ".git" || isGitRepo(ev.Name)) && w.onChange != nil {
+		// A repo cloned or moved in, often somewhere gitignored: only a
+		// full rescan finds it
+		w.onChange("")
+	}
+	quiet, gitignored := w.quiet(repo, dir, ev.Name, relSlash(dir, ev.Name), isDir)
+
+	// Newly created directories need their own watch, unless ignored or
+	// in a watched tree
+	w.mu.Lock()
+	tree := w.trees[repo]
+	w.mu.Unlock()
+	if ev.Has(fsnotify.Create) && isDir && !gitignored && !tree {
+		if quiet || w.add(repo, ev.Name) != nil {
+			w.mu.Lock()
+			w.repos[repo] = false
+			w.mu.Unlock()
+		}
+	}
+
+	w.mu.Lock()
+	w.gens[repo]++
+	w.mu.Unlock()
+	if quiet {
+		return
+	}
+	if w.logger != nil {
+		w.logger.Printf("watch: %s %s", ev.Op, ev.Name)
+	}
+	if w.onChange != nil {
+		w.onChange(repo)
+	}
+}
+
+// repoFor returns the watched repo containing path (longest match wins so
+// nested repos resolve to the innermost one), and the directory it matched:
+// the repo itself, or its real path if the repo was reached through a
+// symlink.
+func (w *Watcher) repoFor(path string) (repo, dir string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	match := func(r, d string) {
+		if (path == d || strings.HasPrefix(path, d+string(filepath.Separator))) && len(d) > len(dir) {
+			repo, dir = r, d
+		}
+	}
+	for r := range w.repos {
+		match(r, r)
+	}
+	for real, r := range w.reals {
+		if _, ok := w.repos[r]; ok {
+			match(r, real)
+		}
+	}
+	return repo, dir
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/bubbles/cursor"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// openPromptMsg opens a single-line text prompt. Menu actions return it
+// (usually through openPromptCmd) to ask for a name, a path or a message.
+type openPromptMsg struct {
+	title       string
+	value       string
+	placeholder string
+	optional    bool               // submitting an empty value is allowed
+	validate    func(string) error // checked on enter; the prompt stays open with the error
+	history     string             // key of the history offered on ↑/↓, empty for none
+	onSubmit    func(string) tea.Cmd
+}
+
+func openPromptCmd(title, value string, onSubmit func(string) tea.Cmd) tea.Cmd {
+	return func() tea.Msg {
+		return openPromptMsg{title: title, value: value, onSubmit: onSubmit}
+	}
+}
+
+// openOptionalPromptCmd is openPromptCmd for a value that may be left
+// empty, like a commit scope.
+func openOptionalPromptCmd(title, value string, onSubmit func(string) tea.Cmd) tea.Cmd {
+	return func() tea.Msg {
+		return openPromptMsg{title: title, value: value, optional: true, onSubmit: onSubmit}
+	}
+}
+
+// promptCmd opens p as it is, for prompts that need a placeholder,
+// validation or history.
+func promptCmd(p openPromptMsg) tea.Cmd {
+	return func() tea.Msg { return p }
+}
+
+func (m *model) openPrompt(p openPromptMsg) tea.Cmd {
+	ti := textinput.New()
+	ti.Prompt = "> "
+	ti.Placeholder = p.placeholder
+	ti.SetValue(p.value)
+	ti.CursorEnd()
+	m.promptInput = ti
+	m.prompt = p
+	m.promptErr = ""
+	m.promptHist = len(m.promptHistory())
+	m.promptOpen = true
+	if m.config.ReducedMotion {
+		m.promptInput.Cursor.SetMode(cursor.CursorStatic)
+	}
+	return m.promptInput.Focus()
+}
+
+func (m *model) closePrompt() {
+	m.promptOpen = false
+	m.prompt = openPromptMsg{}
+	m.promptErr = ""
+	m.promptInput.Blur()
+}
+
+// promptHistory returns the open prompt's history, oldest first.
+func (m model) promptHistory() []string {
+	if m.prompt.history == "" {
+		return nil
+	}
+	return m.state.History[m.prompt.history]
+}
+
+func (m model) handlePromptKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.closePrompt()
+		return m, nil
+	case "enter":
+		value := strings.TrimSpace(m.promptInput.Value())
+		p := m.prompt
+		if value == "" && !p.optional {
+			m.closePrompt()
+			return m, nil
+		}
+		if p.validate != nil {
+			if err := p.validate(value); err != nil {
+				m.promptErr = err.Error()
+				return m, nil
+			}
+		}
+		if p.history != "" && value != "" {
+			m.state.AddHistory(p.history, value)
+		}
+		m.closePrompt()
+		if p.onSubmit == nil {
+			return m, nil
+		}
+		return m, p.onSubmit(value)
+	case "up", "down":
+		h := m.promptHistory()
+		if len(h) == 0 {
+			return m, nil
+		}
+		if msg.String() == "up" {
+			m.promptHist = max(0, m.promptHist-1)
+		} else {
+			m.promptHist = min(len(h), m.promptHist+1)
+		}
+		value := m.prompt.value // back past the newest entry: what it opened with
+		if m.promptHist < len(h) {
+			value = h[m.promptHist]
+		}
+		m.promptInput.SetValue(value)
+		m.promptInput.CursorEnd()
+		return m, nil
+	}
+
+	m.promptErr = ""
+	var cmd tea.Cmd
+	m.promptInput, cmd = m.promptInput.Update(msg)
+	return m, cmd
+}
+
+func (m model) renderPrompt() string {
+	boxWidth := m.width - 2
+	innerWidth := boxWidth - 2
+	m.promptInput.Width = innerWidth - lipgloss.Width(m.promptInput.Prompt) - 1
+
+	content := m.promptInput.View()
+	height := 3
+	if m.promptErr != "" {
+		errLine := lipgloss.NewStyle().Foreground(themeColor(m.config.Theme.StatusConflict)).Render(truncateStr(m.promptErr, innerWidth))
+		content += "\n" + errLine
+		height++
+	}
+	box := renderBorderedPanel(m.prompt.title, content, boxWidth, height, m.config.Theme.BorderFocused, m.config.Theme.Title)
+
+	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, box,
+		lipgloss.WithWhitespaceChars(" "),
+		lipgloss.WithWhitespaceForeground(lipgloss.Color("0")))
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/hermanschutte/sidegit/pkg/sidegit"
+)
+
+// quit exits, unless confirm_quit_when_dirty is on and some repo in any
+// tab still has uncommitted or unpushed changes: then it lists them and
+// waits for a second q.
+func (m model) quit() (tea.Model, tea.Cmd) {
+	if !m.config.ConfirmQuitWhenDirty {
+		return m.exit()
+	}
+	m.quitRepos = m.dirtyRepos()
+	if len(m.quitRepos) == 0 {
+		return m.exit()
+	}
+	m.quitOpen = true
+	return m, nil
+}
+
+// exit quits, canceling the jobs still running. The last frame is blank
+// so an inline UI leaves nothing in the scrollback.
+func (m model) exit() (tea.Model, tea.Cmd) {
+	m.stopJobs()
+	m.quitting = true
+	return m, tea.Quit
+}
+
+// dirtyRepos returns the repos of every tab with uncommitted changes or
+// commits their upstream doesn't have.
+func (m model) dirtyRepos() []sidegit.Repo {
+	var dirty []sidegit.Repo
+	for i, w := range m.tabs {
+		repos := w.repos
+		if i == m.activeTab {
+			repos = m.repos
+		}
+		for _, r := range repos {
+			if !r.Unavailable && (len(r.Files) > 0 || r.Ahead > 0) {
+				dirty = append(dirty, r)
+			}
+		}
+	}
+	return dirty
+}
+
+func (m model) handleQuitKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "q", "y", "ctrl+c":
+		return m.exit()
+	}
+	// Anything else stays
+	m.quitOpen = false
+	m.quitRepos = nil
+	m.cdPath = ""
+	return m, nil
+}
+
+func (m model) renderQuit() string {
+	boxWidth := m.width - 2
+	innerWidth := boxWidth - 2
+	warn := lipgloss.NewStyle().Foreground(themeColor(m.config.Theme.StatusModified))
+	dim := lipgloss.NewStyle().Foreground(themeColor(m.config.Theme.StatusBar))
+
+	nameWidth := 0
+	for _, r := range m.quitRepos {
+		nameWidth = max(nameWidth, lipgloss.Width(r.RelPath))
+	}
+	nameWidth = min(nameWidth+2, innerWidth/2)
+
+	var lines []string
+	for _, r := range m.quitRepos {
+		var what []string
+		if n := len(r.Files); n > 0 {
+			what = append(what, plural(n, "changed file"))
+		}
+		if r.Ahead > 0 {
+			what = append(what, plural(r.Ahead, "unpushed commit"))
+		}
+		name := lipgloss.NewStyle().Width(nameWidth).Render(truncateStr(r.RelPath, nameWidth-1))
+		line := name + warn.Render(truncateStr(strings.Join(what, ", "), innerWidth-nameWidth))
+		if vis := lipgloss.Width(line); vis < innerWidth {
+			line += strings.Repeat(" ", innerWidth-vis)
+		}
+		lines = append(lines, line)
+	}
+	if visible := m.height - 6; len(lines) > visible {
+		more := len(lines) - visible + 1
+		lines = append(lines[:visible-1], dim.Render(fmt.Sprintf("… and %d more", more)))
+	}
+	lines = append(lines, "", dim.Render("q again to quit, any other key to stay"))
+
+	title := fmt.Sprintf("Quit? %s not committed or pushed", plural(len(m.quitRepos), "repo"))
+	box := renderBorderedPanel(title, strings.Join(lines, "\n"), boxWidth, len(lines)+2, m.config.Theme.BorderFocused, m.config.Theme.Title)
+	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, box,
+		lipgloss.WithWhitespaceChars(" "),
+		lipgloss.WithWhitespaceForeground(lipgloss.Color("0")))
+}
+
+// plural formats a count with a noun, made plural when it's not one.
+func plural(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("1 %s", noun)
+	}
+	if strings.HasSuffix(noun, "ch") || strings.HasSuffix(noun, "s") {
+		return fmt.Sprintf("%d %ses", n, noun)
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/hermanschutte/sidegit/pkg/sidegit"
+)
+
+// reflogLimit is how many reflog entries the reflog menu lists.
+const reflogLimit = 100
+
+// reflogMenuCmd lists where HEAD has been, to get back to a state from
+// before a botched rebase or reset.
+func reflogMenuCmd(repo sidegit.Repo) tea.Cmd {
+	return func() tea.Msg {
+		entries, err := sidegit.GetReflog(repo.Path, reflogLimit)
+		if err != nil {
+			return gitErrorMsg{repo: repo.Path, err: err}
+		}
+		var opts []menuOption
+		for _, e := range entries {
+			opts = append(opts, menuOption{
+				label:  fmt.Sprintf("%s %s %s (%s)", e.Short, e.Selector, e.Subject, e.When),
+				action: func() tea.Cmd { return reflogEntryMenuCmd(repo, e) },
+			})
+		}
+		opts = append(opts, menuOption{label: "Cancel"})
+		return openMenuMsg{title: "Reflog: " + repo.RelPath, options: opts}
+	}
+}
+
+// reflogEntryMenuCmd shows the commit of a reflog entry and asks what to
+// do with it.
+func reflogEntryMenuCmd(repo sidegit.Repo, e sidegit.ReflogEntry) tea.Cmd {
+	return func() tea.Msg {
+		preview := ""
+		if c, err := sidegit.GetCommit(repo.Path, e.Hash); err != nil {
+			preview = err.Error()
+		} else {
+			preview = commitSummary(c)
+		}
+		branch := repo.Branch
+		opts := []menuOption{
+			{key: "o", label: "Check out " + e.Short + " (detached HEAD)", action: func() tea.Cmd {
+				return gitNoteCmd(repo.Path, "checked out "+e.Short+" in "+repo.RelPath, func() error { return sidegit.CheckoutBranch(repo.Path, e.Hash) })
+			}},
+		}
+		if repo.Detached == "" {
+			opts = append(opts, menuOption{key: "r", label: "Reset " + branch + " to " + e.Short + " (--keep)", action: func() tea.Cmd {
+				return gitNoteCmd(repo.Path, "reset "+branch+" to "+e.Short, func() error { return sidegit.ResetKeep(repo.Path, e.Hash) })
+			}})
+		}
+		return openMenuMsg{
+			title:   fmt.Sprintf("%s %s: %s", e.Selector, e.Short, e.Subject),
+			options: append(opts, menuOption{label: "Cancel"}),
+			preview: preview,
+		}
+	}
+}
+
+// resetUpstreamMenuCmd offers to reset the branch to its upstream, with
+// the local commits that would be dropped on screen.
+func resetUpstreamMenuCmd(repo sidegit.Repo, snapshotKeep int) tea.Cmd {
+	return func() tea.Msg {
+		log, n, err := sidegit.CommitsNotIn(repo.Path, repo.Upstream)
+		if err != nil {
+			return gitErrorMsg{repo: repo.Path, err: err}
+		}
+		preview := "No local commits are dropped: " + repo.Upstream + " has all of them."
+		if n > 0 {
+			preview = fmt.Sprintf("Commits on %s that %s doesn't have, dropped from the branch:\n\n%s", repo.Branch, repo.Upstream, log)
+		}
+		reset := func(mode string) tea.Cmd {
+			return gitNoteCmd(repo.Path, "reset "+repo.Branch+" to "+repo.Upstream, func() error {
+				return sidegit.ResetTo(repo.Path, repo.Upstream, mode)
+			})
+		}
+		hard := "Hard: discard the commits and every uncommitted change"
+		if len(repo.Files) > 0 {
+			hard += " (snapshot first)"
+		}
+		return openMenuMsg{
+			title: fmt.Sprintf("Reset %s to %s, dropping %s", repo.Branch, repo.Upstream, plural(n, "local commit")),
+			options: []menuOption{
+				{key: "s", label: "Soft: keep their changes staged", action: func() tea.Cmd { return reset("soft") }},
+				{key: "m", label: "Mixed: keep their changes, unstaged", action: func() tea.Cmd { return reset("mixed") }},
+				{key: "x", label: hard, action: func() tea.Cmd {
+					return gitNoteCmd(repo.Path, "reset "+repo.Branch+" to "+repo.Upstream, func() error {
+						// Uncommitted changes can be got back from the snapshots panel
+						if len(repo.Files) > 0 {
+							if _, err := sidegit.TakeSnapshot(repo.Path); err != nil {
+								return err
+							}
+							if err := sidegit.PruneSnapshots(repo.Path, snapshotKeep); err != nil {
+								return err
+							}
+						}
+						return sidegit.ResetTo(repo.Path, repo.Upstream, "hard")
+					})
+				}},
+				{label: "Cancel"},
+			},
+			preview: preview,
+		}
+	}
+}
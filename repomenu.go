@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/hermanschutte/sidegit/pkg/sidegit"
+)
+
+// repoMenuOptions builds the actions menu opened with enter or m on a repo.
+func (m model) repoMenuOptions(repo sidegit.Repo) []menuOption {
+	repoPath := repo.Path
+	webURLs := m.config.WebURLs
+	snapshotKeep := m.config.SnapshotKeep
+	configFile := m.config.Overrides.configFile()
+	opts := []menuOption{
+		{key: "f", label: "Fetch", action: func() tea.Cmd {
+			return jobCmd("fetch "+repo.RelPath, repoPath, "fetched "+repo.RelPath, func(ctx context.Context) error { return sidegit.GitFetch(ctx, repoPath) })
+		}},
+		{key: "l", label: "Pull", action: func() tea.Cmd { return gitPullCmd(repoPath) }},
+		{key: "p", label: "Push", action: func() tea.Cmd { return gitPushCmd(repoPath) }},
+		{key: "d", label: "Diff all changes", action: func() tea.Cmd {
+			return func() tea.Msg { return repoDiffMsg{repo: repoPath} }
+		}},
+		{key: "a", label: "Stage all changes", action: func() tea.Cmd {
+			return stageAllCmd(repoPath, true)
+		}},
+		{key: "u", label: "Unstage all", action: func() tea.Cmd {
+			return stageAllCmd(repoPath, false)
+		}},
+		{key: "s", label: "Stash all changes…", action: func() tea.Cmd {
+			return promptCmd(openPromptMsg{
+				title:       "Stash message (optional)",
+				placeholder: "WIP on " + repo.Branch,
+				optional:    true,
+				history:     "stash",
+				onSubmit: func(msg string) tea.Cmd {
+					return gitNoteCmd(repoPath, "stashed changes in "+repo.RelPath, func() error { return sidegit.GitStash(repoPath, msg) })
+				},
+			})
+		}},
+		{key: "v", label: "Snapshots…", action: func() tea.Cmd { return loadSnapshotsCmd(repo) }},
+		{key: "g", label: "Reflog…", action: func() tea.Cmd { return reflogMenuCmd(repo) }},
+		{key: "c", label: "Clean up…", action: func() tea.Cmd { return openMenuCmd("Clean up "+repo.RelPath, cleanupMenuOptions(repo)) }},
+		{key: "t", label: "Open a shell here", action: func() tea.Cmd { return shellCmd(repoPath) }},
+		{key: "w", label: "Open remote in browser", action: func() tea.Cmd {
+			return openWebCmd(webURLs, repo, "tree", "")
+		}},
+		{key: "y", label: "Copy path", action: func() tea.Cmd {
+			return func() tea.Msg {
+				if err := copyToClipboard(repoPath); err != nil {
+					return gitErrorMsg{repo: repoPath, err: err}
+				}
+				return fileChangedMsg{repo: repoPath, note: "copied " + repoPath}
+			}
+		}},
+		{key: "r", label: "Refresh this repo", action: func() tea.Cmd {
+			return func() tea.Msg { return fileChangedMsg{repo: repoPath} }
+		}},
+		{key: "e", label: "Hide (add to exclude_repos)", action: func() tea.Cmd { return excludeRepoCmd(configFile, repo) }},
+	}
+	if repo.Upstream != "" && repo.Detached == "" {
+		opts = append(opts, menuOption{key: "o", label: "Reset to " + repo.Upstream + "…", action: func() tea.Cmd {
+			return resetUpstreamMenuCmd(repo, snapshotKeep)
+		}})
+	}
+	if len(m.config.Commands) > 0 {
+		tasks := m.taskMenuOptions(repo)
+		opts = append(opts, menuOption{key: "x", label: "Run a command…", action: func() tea.Cmd {
+			return openMenuCmd("Run in "+repo.RelPath, tasks)
+		}})
+	}
+	if repo.Shallow {
+		opts = append(opts, menuOption{key: "h", label: "Fetch the full history (unshallow)", action: func() tea.Cmd {
+			return jobCmd("unshallow "+repo.RelPath, repoPath, "fetched the full history of "+repo.RelPath, func(ctx context.Context) error { return sidegit.GitUnshallow(ctx, repoPath) })
+		}})
+	}
+	return append(opts, menuOption{label: "Cancel"})
+}
+
+// excludeRepoCmd adds repo to exclude_repos in the config file.
+func excludeRepoCmd(configFile string, repo sidegit.Repo) tea.Cmd {
+	return func() tea.Msg {
+		if configFile == "" {
+			return gitErrorMsg{repo: repo.Path, err: fmt.Errorf("no config file to add %s to", repo.RelPath)}
+		}
+		// Anchored, so a repo of the same name deeper down stays visible
+		pattern := "/" + sidegit.QuoteGlob(repo.RelPath)
+		if err := appendConfigList(configFile, "exclude_repos", pattern); err != nil {
+			return gitErrorMsg{repo: repo.Path, err: err}
+		}
+		return repoExcludedMsg{pattern: pattern}
+	}
+}
+
+// dirMenuOptions builds the actions menu for a directory node. They apply
+// to every file shown beneath it.
+func (m *model) dirMenuOptions(node *TreeNode) []menuOption {
+	repoPath := node.Repo.Path
+	dir := node.dirFull
+	files := m.tree.SelectedDirFiles()
+	maxLines := m.config.DiffMaxLines
+	keepDays := m.config.BackupDays
+	discard := func() tea.Cmd {
+		return backupCmd(repoPath, dir, keepDays, func() error {
+			var errs []error
+			for _, f := range files {
+				if f.Status == sidegit.StatusUntracked {
+					errs = append(errs, sidegit.DeleteFile(repoPath, f.Path, true))
+				} else {
+					errs = append(errs, sidegit.DiscardAllChanges(repoPath, f.Path, false))
+				}
+			}
+			return errors.Join(errs...)
+		})
+	}
+	return []menuOption{
+		{key: "a", label: fmt.Sprintf("Stage all (%d files)", len(files)), action: func() tea.Cmd {
+			return gitCmd(repoPath, func() error { return sidegit.StagePaths(repoPath, dir) })
+		}},
+		{key: "x", label: fmt.Sprintf("Discard all changes (%d files)…", len(files)), action: func() tea.Cmd {
+			return discardMenuCmd(repoPath, dir+"/", files, maxLines, discard)
+		}},
+		{key: "o", label: "Collapse other directories", action: func() tea.Cmd {
+			return func() tea.Msg { return collapseOthersMsg{} }
+		}},
+		{label: "Cancel"},
+	}
+}
+
+// stagedAllMsg reports that every change in repo was staged (or
+// unstaged), so the tree can show it before the rescan lands.
+type stagedAllMsg struct {
+	repo   string
+	staged bool
+}
+
+func stageAllCmd(repoPath string, stage bool) tea.Cmd {
+	return func() tea.Msg {
+		var err error
+		if stage {
+			err = sidegit.StagePaths(repoPath, ".")
+		} else {
+			err = sidegit.UnstageAll(repoPath)
+		}
+		if err != nil {
+			return gitErrorMsg{repo: repoPath, err: err}
+		}
+		return stagedAllMsg{repo: repoPath, staged: stage}
+	}
+}
+
+// collapseOthersMsg collapses the directories around the selected one.
+type collapseOthersMsg struct{}
+
+// shellCmd suspends the TUI and runs $SHELL in dir until it exits; the
+// repo is refreshed then. In a bare repo's work tree, git commands in the
+// shell find the repo through GIT_DIR and GIT_WORK_TREE.
+func shellCmd(dir string) tea.Cmd {
+	shell := os.Getenv("SHELL")
+	if shell == "" {
+		shell = "sh"
+		if runtime.GOOS == "windows" {
+			shell = "cmd"
+		}
+	}
+	c := exec.Command(shell)
+	c.Dir = dir
+	c.Env = append(os.Environ(), sidegit.RepoEnv(dir)...)
+	return tea.ExecProcess(c, func(err error) tea.Msg {
+		return editorFinishedMsg{repo: dir, err: err}
+	})
+}
+
+// copyToClipboard hands s to the platform's clipboard tool, falling back
+// to an OSC 52 escape that most terminals (and tmux) pass on, over ssh too.
+func copyToClipboard(s string) error {
+	var tools [][]string
+	switch runtime.GOOS {
+	case "darwin":
+		tools = [][]string{{"pbcopy"}}
+	case "windows":
+		tools = [][]string{{"clip"}}
+	default:
+		tools = [][]string{{"wl-copy"}, {"xclip", "-selection", "clipboard"}, {"xsel", "--clipboard", "--input"}}
+	}
+	for _, t := range tools {
+		if _, err := exec.LookPath(t[0]); err != nil {
+			continue
+		}
+		c := exec.Command(t[0], t[1:]...)
+		c.Stdin = strings.NewReader(s)
+		if err := c.Run(); err == nil {
+			return nil
+		}
+	}
+	_, err := fmt.Fprintf(os.Stdout, "\x1b]52;c;%s\a", base64.StdEncoding.EncodeToString([]byte(s)))
+	return err
+}
@@ -0,0 +1,269 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/hermanschutte/sidegit/pkg/sidegit"
+)
+
+type jsonRepo struct {
+	Name     string     `json:"name"`
+	Path     string     `json:"path"`
+	Branch   string     `json:"branch"`
+	Detached string     `json:"detached,omitempty"`
+	Upstream string     `json:"upstream,omitempty"`
+	Ahead    int        `json:"ahead"`
+	Behind   int        `json:"behind"`
+	Files    []jsonFile `json:"files"`
+	Warnings []string   `json:"warnings,omitempty"`
+	Shallow  bool       `json:"shallow,omitempty"`
+	Partial  bool       `json:"partial,omitempty"`
+	Sparse   bool       `json:"sparse,omitempty"`
+
+	Unavailable bool `json:"unavailable,omitempty"`
+}
+
+type jsonFile struct {
+	Path   string `json:"path"`
+	Status string `json:"status"`
+	Staged bool   `json:"staged"`
+}
+
+// statusName returns the filter name of a status code, falling back to
+// the code itself.
+func statusName(code sidegit.StatusCode) string {
+	for _, s := range statusNames {
+		if s.code == code {
+			return s.name
+		}
+	}
+	return string(code)
+}
+
+// writeJSON prints repos as a JSON document for --json.
+func writeJSON(w io.Writer, repos []sidegit.Repo) error {
+	out := make([]jsonRepo, 0, len(repos))
+	for _, r := range repos {
+		jr := jsonRepo{
+			Name:     r.RelPath,
+			Path:     r.Path,
+			Branch:   r.Branch,
+			Detached: r.Detached,
+			Upstream: r.Upstream,
+			Ahead:    r.Ahead,
+			Behind:   r.Behind,
+			Files:    []jsonFile{},
+			Warnings: r.Warnings,
+			Shallow:  r.Shallow,
+			Partial:  r.Partial,
+			Sparse:   r.Sparse,
+
+			Unavailable: r.Unavailable,
+		}
+		for _, f := range r.Files {
+			jr.Files = append(jr.Files, jsonFile{Path: f.Path, Status: statusName(f.Status), Staged: f.IsStaged})
+		}
+		out = append(out, jr)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(struct {
+		Repos []jsonRepo `json:"repos"`
+	}{out})
+}
+
+// writeReport prints a compact colored summary for --once: one line per
+// repo, followed by its changed files.
+func writeReport(w io.Writer, repos []sidegit.Repo, theme Theme) error {
+	plain := lipgloss.NewStyle()
+	for _, r := range repos {
+		name := plain.Bold(true).Foreground(themeColor(theme.RepoName)).Render(r.RelPath)
+		branch := "[" + r.Branch + "]"
+		branchColor := theme.BranchName
+		if r.Detached != "" {
+			branch, branchColor = r.Detached, theme.Detached
+		}
+		if r.Unavailable {
+			branch, branchColor = "(unavailable)", theme.Warning
+		}
+		line := name + " " + plain.Foreground(themeColor(branchColor)).Render(branch)
+		line += renderAheadBehind(r.Ahead, r.Behind, plain, " ", theme)
+		summary := "clean"
+		if n := len(r.Files); n > 0 {
+			summary = fmt.Sprintf("%d changed", n)
+		}
+		line += " " + plain.Foreground(themeColor(theme.FileCount)).Render(summary)
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+		for _, f := range r.Files {
+			status := styleStatus(f.Status, f.IsStaged, false, theme, nil)
+			if _, err := fmt.Fprintf(w, "  %s %s\n", status, f.Path); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// writeMarkdown prints repos as a markdown report for --markdown and E:
+// a table of every repo, then the changed files of the dirty ones. It's
+// meant to be pasted into standup notes or a handoff.
+func writeMarkdown(w io.Writer, root string, repos []sidegit.Repo, now time.Time) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", root)
+	fmt.Fprintf(&b, "%s\n\n", now.Format("2006-01-02 15:04"))
+	b.WriteString("| Repo | Branch | Ahead | Behind | Changes |\n")
+	b.WriteString("|---|---|--:|--:|--:|\n")
+	for _, r := range repos {
+		changes := strconv.Itoa(len(r.Files))
+		if r.Unavailable {
+			changes = "?"
+		}
+		fmt.Fprintf(&b, "| %s | %s | %d | %d | %s |\n", markdownCell(r.RelPath), markdownCell(markdownBranch(r)), r.Ahead, r.Behind, changes)
+	}
+	for _, r := range repos {
+		if len(r.Files) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "\n## %s (%s)\n\n", r.RelPath, markdownBranch(r))
+		for _, f := range r.Files {
+			status := statusName(f.Status)
+			if f.IsStaged {
+				status += ", staged"
+			}
+			fmt.Fprintf(&b, "- %s: `%s`\n", status, f.Path)
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// markdownBranch names what r has checked out, and its upstream.
+func markdownBranch(r sidegit.Repo) string {
+	switch {
+	case r.Unavailable:
+		return "unavailable"
+	case r.Detached != "":
+		return r.Detached
+	case r.Upstream != "":
+		return r.Branch + " → " + r.Upstream
+	}
+	return r.Branch
+}
+
+// markdownCell escapes s for a markdown table cell.
+func markdownCell(s string) string {
+	return strings.ReplaceAll(s, "|", "\\|")
+}
+
+// reportOptions are the ways E exports the repos of the open tab.
+func (m model) reportOptions() []menuOption {
+	root, repos := m.scanRoot, m.repos
+	report := func() string {
+		var b bytes.Buffer
+		_ = writeMarkdown(&b, root, repos, time.Now())
+		return b.String()
+	}
+	return []menuOption{
+		{key: "y", label: "Copy to clipboard", action: func() tea.Cmd {
+			return func() tea.Msg {
+				if err := copyToClipboard(report()); err != nil {
+					return reportWrittenMsg{err: err}
+				}
+				return reportWrittenMsg{note: fmt.Sprintf("copied a report of %d repos", len(repos))}
+			}
+		}},
+		{key: "f", label: "Save to file…", action: func() tea.Cmd {
+			return promptCmd(openPromptMsg{
+				title:   "Save report to",
+				value:   "sidegit-report-" + time.Now().Format("2006-01-02") + ".md",
+				history: "report",
+				onSubmit: func(file string) tea.Cmd {
+					if !filepath.IsAbs(file) {
+						file = filepath.Join(root, file)
+					}
+					return func() tea.Msg {
+						if err := os.WriteFile(file, []byte(report()), 0o644); err != nil {
+							return reportWrittenMsg{err: err}
+						}
+						return reportWrittenMsg{note: "wrote " + file}
+					}
+				},
+			})
+		}},
+		{label: "Cancel"},
+	}
+}
+
+// reportWrittenMsg reports where a report went, or why it didn't.
+type reportWrittenMsg struct {
+	note string
+	err  error
+}
+
+// writeSegment prints the one-line summary for --tmux-segment and
+// --prompt-segment: how many repos are dirty and how many have unpushed
+// commits, like "3⚑ 2↑". It prints nothing when everything is clean and
+// pushed, so the segment disappears. With tmux the colors are #[fg=…]
+// markup, otherwise ANSI escapes for shell prompts.
+func writeSegment(w io.Writer, repos []sidegit.Repo, theme Theme, light, tmux bool) error {
+	dirty, ahead := 0, 0
+	for _, r := range repos {
+		if len(r.Files) > 0 {
+			dirty++
+		}
+		if r.Ahead > 0 {
+			ahead++
+		}
+	}
+	var parts []string
+	if dirty > 0 {
+		parts = append(parts, segmentColor(fmt.Sprintf("%d⚑", dirty), theme.StatusModified, light, tmux))
+	}
+	if ahead > 0 {
+		parts = append(parts, segmentColor(fmt.Sprintf("%d↑", ahead), theme.AheadColor, light, tmux))
+	}
+	if len(parts) == 0 {
+		return nil
+	}
+	_, err := fmt.Fprintln(w, strings.Join(parts, " "))
+	return err
+}
+
+// segmentColor wraps s in color, a theme color: an ANSI number, a hex
+// color or a "light|dark" pair.
+func segmentColor(s, color string, light, tmux bool) string {
+	if l, d, ok := strings.Cut(color, "|"); ok {
+		color = d
+		if light {
+			color = l
+		}
+	}
+	hex := strings.HasPrefix(color, "#") && len(color) == 7
+	n, err := strconv.Atoi(color)
+	switch {
+	case tmux && hex:
+		return "#[fg=" + color + "]" + s + "#[default]"
+	case tmux && err == nil:
+		return fmt.Sprintf("#[fg=colour%d]%s#[default]", n, s)
+	case hex:
+		var r, g, b int
+		if _, err := fmt.Sscanf(color, "#%02x%02x%02x", &r, &g, &b); err == nil {
+			return fmt.Sprintf("\x1b[38;2;%d;%d;%dm%s\x1b[0m", r, g, b, s)
+		}
+	case err == nil:
+		return fmt.Sprintf("\x1b[38;5;%dm%s\x1b[0m", n, s)
+	}
+	return s
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hermanschutte/sidegit/pkg/sidegit"
+)
+
+func TestWriteMarkdown(t *testing.T) {
+	now := time.Date(2026, 3, 4, 9, 30, 0, 0, time.UTC)
+	const header = "# ~/work\n\n2026-03-04 09:30\n\n| Repo | Branch | Ahead | Behind | Changes |\n|---|---|--:|--:|--:|\n"
+	tests := []struct {
+		name  string
+		repos []sidegit.Repo
+		want  string
+	}{
+		{
+			name: "no repos",
+			want: header,
+		},
+		{
+			name:  "clean with upstream",
+			repos: []sidegit.Repo{{RelPath: "api", Branch: "main", Upstream: "origin/main", Ahead: 2, Behind: 1}},
+			want:  header + "| api | main → origin/main | 2 | 1 | 0 |\n",
+		},
+		{
+			name: "dirty",
+			repos: []sidegit.Repo{{RelPath: "web", Branch: "feature", Files: []sidegit.FileStatus{
+				{Path: "a.go", Status: sidegit.StatusModified},
+				{Path: "b.go", Status: sidegit.StatusAdded, IsStaged: true},
+				{Path: "new dir/c.txt", Status: sidegit.StatusUntracked},
+				{Path: "x", Status: "T"},
+			}}},
+			want: header + "| web | feature | 0 | 0 | 4 |\n" +
+				"\n## web (feature)\n\n" +
+				"- modified: `a.go`\n" +
+				"- added, staged: `b.go`\n" +
+				"- untracked: `new dir/c.txt`\n" +
+				"- T: `x`\n",
+		},
+		{
+			name: "detached and unavailable",
+			repos: []sidegit.Repo{
+				{RelPath: "tools", Branch: "HEAD", Detached: "v1.2.0"},
+				{RelPath: "nfs", Unavailable: true},
+			},
+			want: header + "| tools | v1.2.0 | 0 | 0 | 0 |\n| nfs | unavailable | 0 | 0 | ? |\n",
+		},
+		{
+			name:  "pipes escaped in cells",
+			repos: []sidegit.Repo{{RelPath: "a|b", Branch: "fix|it"}},
+			want:  header + "| a\\|b | fix\\|it | 0 | 0 | 0 |\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b strings.Builder
+			if err := writeMarkdown(&b, "~/work", tt.repos, now); err != nil {
+				t.Fatal(err)
+			}
+			if b.String() != tt.want {
+				t.Errorf("got\n%s\nwant\n%s", b.String(), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/hermanschutte/sidegit/pkg/sidegit"
+)
+
+// Review marks check files off while reading through a change before
+// committing it. They are kept in the state file with a hash of the file's
+// content, so editing a file after its review clears the mark.
+
+// reviewHash hashes a changed file's content for its review mark. A
+// deleted file, or an untracked directory, hashes to "".
+func reviewHash(repoPath, filePath string) string {
+	data, err := os.ReadFile(filepath.Join(repoPath, filePath))
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// toggleReviewed marks the selected file reviewed and moves on to the next
+// file that isn't, opening its diff if a diff is open; on a file already
+// marked it clears the mark instead.
+func (m *model) toggleReviewed() tea.Cmd {
+	node := m.tree.SelectedNode()
+	if node == nil || node.Kind != NodeFile {
+		return nil
+	}
+	repo, file := node.Repo.Path, node.File.Path
+	if m.state.IsReviewed(repo, file) {
+		m.state.UnmarkReviewed(repo, file)
+		return nil
+	}
+	m.state.MarkReviewed(repo, file, reviewHash(repo, file))
+	if !m.tree.SelectNextFile(func(n TreeNode) bool { return !m.state.IsReviewed(n.Repo.Path, n.File.Path) }) {
+		m.statusMsg = tr("every file is reviewed")
+		return nil
+	}
+	m.trackVisit()
+	if m.diffOpen && m.diffFile != "" {
+		return m.openFileDiff(m.tree.SelectedNode())
+	}
+	return nil
+}
+
+// reviewProgress counts the changed files of a repo marked reviewed.
+func (m model) reviewProgress(r *sidegit.Repo) int {
+	n := 0
+	for _, f := range r.Files {
+		if m.state.IsReviewed(r.Path, f.Path) {
+			n++
+		}
+	}
+	return n
+}
+
+// reviewMark is a file's review mark with the hash it was made at.
+type reviewMark struct {
+	repo, file, hash string
+}
+
+// reviewsCheckedMsg carries the review marks whose files changed since.
+type reviewsCheckedMsg struct{ stale []reviewMark }
+
+// checkReviews clears the review marks of files in repos that are no
+// longer changed, then returns a command that hashes the rest to find the
+// ones edited since their review.
+func (m model) checkReviews(repos []sidegit.Repo) tea.Cmd {
+	var marks []reviewMark
+	for _, r := range repos {
+		rs, ok := m.state.Repos[r.Path]
+		if !ok || len(rs.Reviewed) == 0 || r.Unavailable {
+			continue
+		}
+		changed := map[string]bool{}
+		for _, f := range r.Files {
+			changed[f.Path] = true
+		}
+		for file, hash := range rs.Reviewed {
+			if !changed[file] {
+				delete(rs.Reviewed, file)
+				continue
+			}
+			marks = append(marks, reviewMark{r.Path, file, hash})
+		}
+	}
+	if len(marks) == 0 {
+		return nil
+	}
+	return func() tea.Msg {
+		var stale []reviewMark
+		for _, mk := range marks {
+			if reviewHash(mk.repo, mk.file) != mk.hash {
+				stale = append(stale, mk)
+			}
+		}
+		return reviewsCheckedMsg{stale: stale}
+	}
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/hermanschutte/sidegit/pkg/sidegit"
+)
+
+type snapshotTickMsg struct{}
+
+// snapshotsTakenMsg reports a round of snapshots.
+type snapshotsTakenMsg struct {
+	taken     int
+	err       error
+	scheduled bool // taken on snapshot_interval rather than asked for
+}
+
+func snapshotTickCmd(d time.Duration) tea.Cmd {
+	return tea.Tick(d, func(time.Time) tea.Msg { return snapshotTickMsg{} })
+}
+
+// takeSnapshotsCmd snapshots every repo with changes and prunes old
+// snapshots down to keep.
+func takeSnapshotsCmd(repos []sidegit.Repo, keep int, scheduled bool) tea.Cmd {
+	repos = append([]sidegit.Repo(nil), repos...)
+	return func() tea.Msg {
+		msg := snapshotsTakenMsg{scheduled: scheduled}
+		var errs []error
+		for _, r := range repos {
+			if len(r.Files) == 0 || r.Unavailable {
+				continue
+			}
+			took, err := sidegit.TakeSnapshot(r.Path)
+			if err == nil {
+				err = sidegit.PruneSnapshots(r.Path, keep)
+			}
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", r.RelPath, err))
+			}
+			if took {
+				msg.taken++
+			}
+		}
+		msg.err = errors.Join(errs...)
+		return msg
+	}
+}
+
+// snapshotView is the snapshots panel opened from a repo's menu: the
+// repo's snapshots, newest first, beside the changes of the selected one.
+type snapshotView struct {
+	repo   sidegit.Repo
+	snaps  []sidegit.Snapshot
+	cursor int
+	vp     viewport.Model
+}
+
+// snapshotsLoadedMsg opens (or refreshes) the snapshots panel;
+// snapshotDiffMsg fills it with a snapshot's changes.
+type snapshotsLoadedMsg struct {
+	repo  sidegit.Repo
+	snaps []sidegit.Snapshot
+}
+
+type snapshotDiffMsg struct {
+	hash string
+	text string
+}
+
+func loadSnapshotsCmd(repo sidegit.Repo) tea.Cmd {
+	return func() tea.Msg {
+		snaps, err := sidegit.ListSnapshots(repo.Path)
+		if err != nil {
+			return gitErrorMsg{repo: repo.Path, err: err}
+		}
+		return snapshotsLoadedMsg{repo: repo, snaps: snaps}
+	}
+}
+
+func snapshotDiffCmd(repoPath string, s sidegit.Snapshot) tea.Cmd {
+	return func() tea.Msg {
+		text, err := sidegit.SnapshotDiff(repoPath, s)
+		if err != nil {
+			text = err.Error()
+		}
+		return snapshotDiffMsg{hash: s.Hash, text: text}
+	}
+}
+
+// snapshotListWidth is the width of the panel's list of snapshots.
+const snapshotListWidth = 34
+
+// openSnapshots shows msg's snapshots, keeping the selection when the
+// panel is already open on the same repo.
+func (m *model) openSnapshots(msg snapshotsLoadedMsg) tea.Cmd {
+	cursor := 0
+	if m.snapOpen && m.snap.repo.Path == msg.repo.Path {
+		cursor = min(m.snap.cursor, max(0, len(msg.snaps)-1))
+	}
+	m.snap = snapshotView{repo: msg.repo, snaps: msg.snaps, cursor: cursor}
+	m.snap.vp = viewport.New(max(10, m.width-4-snapshotListWidth-1), m.commitViewHeight())
+	m.snapOpen = true
+	return m.showSnapshot()
+}
+
+// showSnapshot loads the changes of the selected snapshot.
+func (m *model) showSnapshot() tea.Cmd {
+	if len(m.snap.snaps) == 0 {
+		m.snap.vp.SetContent("No snapshots yet. n takes one now.")
+		return nil
+	}
+	m.snap.vp.SetContent("loading…")
+	m.snap.vp.GotoTop()
+	return snapshotDiffCmd(m.snap.repo.Path, m.snap.snaps[m.snap.cursor])
+}
+
+func (m model) handleSnapshotsKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	repo := m.snap.repo
+	var sel *sidegit.Snapshot
+	if m.snap.cursor < len(m.snap.snaps) {
+		sel = &m.snap.snaps[m.snap.cursor]
+	}
+	switch msg.String() {
+	case "esc", "q":
+		m.snapOpen = false
+	case "up", "k":
+		if m.snap.cursor > 0 {
+			m.snap.cursor--
+			return m, m.showSnapshot()
+		}
+	case "down", "j":
+		if m.snap.cursor < len(m.snap.snaps)-1 {
+			m.snap.cursor++
+			return m, m.showSnapshot()
+		}
+	case "n":
+		return m, takeSnapshotsCmd([]sidegit.Repo{repo}, m.config.SnapshotKeep, false)
+	case "a":
+		if sel != nil {
+			s := *sel
+			m.snapOpen = false
+			return m, gitNoteCmd(repo.Path, "applied snapshot to "+repo.RelPath, func() error { return sidegit.ApplySnapshot(repo.Path, s) })
+		}
+	case "x":
+		if sel != nil {
+			s := *sel
+			return m, func() tea.Msg {
+				if err := sidegit.DeleteSnapshot(repo.Path, s); err != nil {
+					return gitErrorMsg{repo: repo.Path, err: err}
+				}
+				return loadSnapshotsCmd(repo)()
+			}
+		}
+	case "g", "home":
+		m.snap.vp.GotoTop()
+	case "G", "end":
+		m.snap.vp.GotoBottom()
+	case "ctrl+d":
+		m.snap.vp.HalfPageDown()
+	case "ctrl+u":
+		m.snap.vp.HalfPageUp()
+	default:
+		var cmd tea.Cmd
+		m.snap.vp, cmd = m.snap.vp.Update(msg)
+		return m, cmd
+	}
+	return m, nil
+}
+
+func (m model) renderSnapshots() string {
+	boxWidth := m.width - 2
+	height := m.commitViewHeight()
+	cursor := lipgloss.NewStyle().Background(themeColor(m.config.Theme.CursorBg))
+	dim := lipgloss.NewStyle().Foreground(themeColor(m.config.Theme.FileCount))
+
+	start := 0
+	if m.snap.cursor >= height {
+		start = m.snap.cursor - height + 1
+	}
+	var rows []string
+	for i := start; i < len(m.snap.snaps) && i < start+height; i++ {
+		s := m.snap.snaps[i]
+		row := fmt.Sprintf("%s %s", s.When.Format("Mon 2 Jan 15:04"), dim.Render(formatAge(time.Since(s.When))))
+		row = lipgloss.NewStyle().Width(snapshotListWidth).MaxWidth(snapshotListWidth).Render(row)
+		if i == m.snap.cursor {
+			row = cursor.Render(row)
+		}
+		rows = append(rows, row)
+	}
+	list := lipgloss.NewStyle().Width(snapshotListWidth).Height(height).Render(strings.Join(rows, "\n"))
+	body := lipgloss.JoinHorizontal(lipgloss.Top, list, " ", m.snap.vp.View())
+
+	hints := "j/k select · a apply to the worktree · x delete · n take one now · esc close"
+	hint := lipgloss.NewStyle().Foreground(themeColor(m.config.Theme.StatusBar)).Render(truncateStr(hints, boxWidth-2))
+	title := fmt.Sprintf("Snapshots: %s (%d, newest first)", m.snap.repo.RelPath, len(m.snap.snaps))
+	box := renderBorderedPanel(title, body+"\n"+hint, boxWidth, height+3, m.config.Theme.BorderFocused, m.config.Theme.Title)
+	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, box,
+		lipgloss.WithWhitespaceChars(" "),
+		lipgloss.WithWhitespaceForeground(lipgloss.Color("0")))
+}
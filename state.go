@@ -0,0 +1,198 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// maxVisits bounds how many interaction timestamps are kept per repo.
+const maxVisits = 20
+
+// maxHistory bounds how many entries are kept per prompt history.
+const maxHistory = 50
+
+// State is UI state persisted between runs, separate from user config.
+type State struct {
+	Repos   map[string]*RepoState `yaml:"repos"`
+	History map[string][]string   `yaml:"history,omitempty"` // prompt input by kind, oldest first
+}
+
+type RepoState struct {
+	Visits   []time.Time       `yaml:"visits"`
+	Reviewed map[string]string `yaml:"reviewed,omitempty"` // file path → content hash when marked reviewed
+	Notes    map[string]string `yaml:"notes,omitempty"`    // file path → note, "" for the repo's own
+}
+
+func statePath() string {
+	dir := configDir()
+	if dir == "" {
+		return ""
+	}
+	return filepath.Join(dir, "state.yaml")
+}
+
+func LoadState() *State {
+	s := &State{Repos: map[string]*RepoState{}}
+	path := statePath()
+	if path == "" {
+		return s
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return s
+	}
+	_ = yaml.Unmarshal(data, s)
+	if s.Repos == nil {
+		s.Repos = map[string]*RepoState{}
+	}
+	return s
+}
+
+// Save writes the state through a temporary file, so another sidegit
+// reading it, or a crash halfway, never sees half of it.
+func (s *State) Save() error {
+	path := statePath()
+	if path == "" {
+		return nil
+	}
+	data, err := yaml.Marshal(s)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), "state-*.yaml")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(f.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+func (s *State) repo(repoPath string) *RepoState {
+	rs, ok := s.Repos[repoPath]
+	if !ok {
+		rs = &RepoState{}
+		s.Repos[repoPath] = rs
+	}
+	return rs
+}
+
+// RecordVisit notes an interaction with a repo for frecency ranking.
+func (s *State) RecordVisit(repoPath string, now time.Time) {
+	rs := s.repo(repoPath)
+	rs.Visits = append(rs.Visits, now)
+	if len(rs.Visits) > maxVisits {
+		rs.Visits = rs.Visits[len(rs.Visits)-maxVisits:]
+	}
+}
+
+// Frecency scores a repo by how often and how recently it was visited,
+// weighting each visit by its age in the same buckets Firefox uses.
+func (s *State) Frecency(repoPath string, now time.Time) int {
+	rs, ok := s.Repos[repoPath]
+	if !ok {
+		return 0
+	}
+	score := 0
+	for _, v := range rs.Visits {
+		age := now.Sub(v)
+		switch {
+		case age < 4*24*time.Hour:
+			score += 100
+		case age < 14*24*time.Hour:
+			score += 70
+		case age < 31*24*time.Hour:
+			score += 50
+		case age < 90*24*time.Hour:
+			score += 30
+		default:
+			score += 10
+		}
+	}
+	return score
+}
+
+// AddHistory remembers value as the latest input for the prompt history
+// key, dropping an earlier copy.
+func (s *State) AddHistory(key, value string) {
+	if s.History == nil {
+		s.History = map[string][]string{}
+	}
+	h := s.History[key]
+	for i, v := range h {
+		if v == value {
+			h = append(h[:i:i], h[i+1:]...)
+			break
+		}
+	}
+	h = append(h, value)
+	if len(h) > maxHistory {
+		h = h[len(h)-maxHistory:]
+	}
+	s.History[key] = h
+}
+
+// MarkReviewed marks a changed file reviewed, at the content hash it had
+// when it was read.
+func (s *State) MarkReviewed(repoPath, filePath, hash string) {
+	rs := s.repo(repoPath)
+	if rs.Reviewed == nil {
+		rs.Reviewed = map[string]string{}
+	}
+	rs.Reviewed[filePath] = hash
+}
+
+// UnmarkReviewed clears a file's review mark.
+func (s *State) UnmarkReviewed(repoPath, filePath string) {
+	if rs, ok := s.Repos[repoPath]; ok {
+		delete(rs.Reviewed, filePath)
+	}
+}
+
+// IsReviewed reports whether a file is marked reviewed.
+func (s *State) IsReviewed(repoPath, filePath string) bool {
+	rs, ok := s.Repos[repoPath]
+	if !ok {
+		return false
+	}
+	_, ok = rs.Reviewed[filePath]
+	return ok
+}
+
+// SetNote attaches a note to a file of a repo, or to the repo itself when
+// filePath is "". An empty note removes it.
+func (s *State) SetNote(repoPath, filePath, note string) {
+	rs := s.repo(repoPath)
+	if note == "" {
+		delete(rs.Notes, filePath)
+		return
+	}
+	if rs.Notes == nil {
+		rs.Notes = map[string]string{}
+	}
+	rs.Notes[filePath] = note
+}
+
+// Note returns the note on a file of a repo, or on the repo itself when
+// filePath is "".
+func (s *State) Note(repoPath, filePath string) string {
+	if rs, ok := s.Repos[repoPath]; ok {
+		return rs.Notes[filePath]
+	}
+	return ""
+}
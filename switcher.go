@@ -0,0 +1,166 @@
+package main
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/cursor"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/hermanschutte/sidegit/pkg/sidegit"
+)
+
+// repoMatch is a repo that matched the switcher query, with its score.
+type repoMatch struct {
+	repo  *sidegit.Repo
+	score int
+}
+
+func (m *model) openSwitcher() tea.Cmd {
+	ti := textinput.New()
+	ti.Prompt = "> "
+	ti.Placeholder = tr("repo name")
+	m.switcherInput = ti
+	m.switcherCursor = 0
+	m.switcherOpen = true
+	if m.config.ReducedMotion {
+		m.switcherInput.Cursor.SetMode(cursor.CursorStatic)
+	}
+	return m.switcherInput.Focus()
+}
+
+func (m *model) closeSwitcher() {
+	m.switcherOpen = false
+	m.switcherInput.Blur()
+	m.switcherCursor = 0
+}
+
+func (m model) handleSwitcherKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	matches := m.switcherMatches()
+	switch msg.String() {
+	case "esc", "ctrl+k":
+		m.closeSwitcher()
+		return m, nil
+	case "up", "ctrl+p":
+		if m.switcherCursor > 0 {
+			m.switcherCursor--
+		}
+		return m, nil
+	case "down", "ctrl+n":
+		if m.switcherCursor < len(matches)-1 {
+			m.switcherCursor++
+		}
+		return m, nil
+	case "enter":
+		if m.switcherCursor < len(matches) {
+			repo := matches[m.switcherCursor].repo
+			m.tree.SelectRepo(repo.Path)
+			m.trackVisit()
+		}
+		m.closeSwitcher()
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.switcherInput, cmd = m.switcherInput.Update(msg)
+	m.switcherCursor = 0
+	return m, cmd
+}
+
+// switcherMatches returns repos matching the query, best match first.
+func (m model) switcherMatches() []repoMatch {
+	query := m.switcherInput.Value()
+	var matches []repoMatch
+	for i := range m.repos {
+		if score, ok := fuzzyMatch(query, m.repos[i].RelPath); ok {
+			matches = append(matches, repoMatch{repo: &m.repos[i], score: score})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].score > matches[j].score
+	})
+	return matches
+}
+
+// fuzzyMatch reports whether every rune of query appears in target in order
+// (case-insensitive). Consecutive runs and matches at the start of a path
+// segment or word score higher; gaps between matched runes cost a point.
+func fuzzyMatch(query, target string) (int, bool) {
+	q := []rune(strings.ToLower(query))
+	t := []rune(strings.ToLower(target))
+	if len(q) == 0 {
+		return 0, true
+	}
+
+	score := 0
+	qi := 0
+	last := -1
+	for ti, r := range t {
+		if qi == len(q) {
+			break
+		}
+		if r != q[qi] {
+			continue
+		}
+		switch {
+		case last >= 0 && ti == last+1:
+			score += 5
+		case ti == 0 || strings.ContainsRune("/-_. ", t[ti-1]):
+			score += 3
+		default:
+			score++
+		}
+		if last >= 0 {
+			score -= ti - last - 1
+		}
+		last = ti
+		qi++
+	}
+	if qi < len(q) {
+		return 0, false
+	}
+	return score, true
+}
+
+func (m model) renderSwitcher() string {
+	cursorBg := themeColor(m.config.Theme.CursorBg)
+	borderColor := m.config.Theme.BorderFocused
+
+	boxWidth := m.width - 2
+	innerWidth := boxWidth - 2
+
+	matches := m.switcherMatches()
+	maxVisible := m.maxMenuVisible() - 1
+	start := 0
+	if m.switcherCursor >= maxVisible {
+		start = m.switcherCursor - maxVisible + 1
+	}
+
+	lines := []string{m.switcherInput.View()}
+	for i := start; i < len(matches) && i < start+maxVisible; i++ {
+		r := matches[i].repo
+		bg := lipgloss.NewStyle()
+		if i == m.switcherCursor {
+			bg = bg.Background(cursorBg)
+		}
+		name := bg.Bold(true).Foreground(themeColor(m.config.Theme.RepoName)).Render(truncatePath(r.RelPath, max(1, innerWidth/2)))
+		branch := bg.Foreground(themeColor(m.config.Theme.BranchName)).Render(" [" + r.Branch + "]")
+		line := bg.Render("  "+cursorPrefix(i == m.switcherCursor)) + name + branch
+		vis := lipgloss.Width(line)
+		if vis < innerWidth {
+			line += bg.Render(strings.Repeat(" ", innerWidth-vis))
+		}
+		lines = append(lines, line)
+	}
+	if len(matches) == 0 {
+		lines = append(lines, lipgloss.NewStyle().Foreground(themeColor(m.config.Theme.NoRepos)).Render("  "+tr("no matching repos")))
+	}
+
+	content := strings.Join(lines, "\n")
+	box := renderBorderedPanel(tr("Go to repo"), content, boxWidth, len(lines)+2, borderColor, m.config.Theme.Title)
+
+	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, box,
+		lipgloss.WithWhitespaceChars(" "),
+		lipgloss.WithWhitespaceForeground(lipgloss.Color("0")))
+}
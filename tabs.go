@@ -0,0 +1,301 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/hermanschutte/sidegit/pkg/sidegit"
+)
+
+// workspace is the state of one tab: a scan root with its own engine,
+// config, tree and open diff. The active tab's state lives in the model's
+// own fields; the others wait here until switched to.
+type workspace struct {
+	root    string
+	service Engine
+	config  Config // the global config with root's .sidegit.yaml over it
+	repos   []sidegit.Repo
+	tree    TreeModel
+	focused panel
+
+	diffOpen     bool
+	diffContent  string
+	diffFiles    []sidegit.FileDiff
+	diffLines    []diffLine
+	diffRows     []int
+	diffUnfolded map[int]bool
+	diffFile     string
+	diffRepo     string
+	diffPages    int
+	diffCut      bool
+	diffSize     int64
+	diffViewport viewport.Model
+
+	visitedRepo string
+	selectRepo  string
+	selectFile  string
+}
+
+// startEngine returns an engine for root: the daemon serving it if there
+// is one, otherwise a service of our own.
+func startEngine(cfg Config, root string) Engine {
+	if !cfg.SafeMode {
+		if c, err := DialDaemon(root); err == nil {
+			return c
+		}
+	}
+	return startService(cfg, root)
+}
+
+// tabConfig returns the config for a tab on root: base's config files and
+// overrides, with root's workspace config layered over them. A config that
+// doesn't load leaves base.
+func tabConfig(base Config, root string) Config {
+	cfg, err := ReloadConfig(root, base.Overrides)
+	if err != nil && !isConfigProblems(err) {
+		return base
+	}
+	cfg.SafeMode = base.SafeMode
+	cfg.Inline = base.Inline // the screen is picked at start
+	return cfg
+}
+
+// startService starts a service of our own for root.
+func startService(cfg Config, root string) Engine {
+	opts := cfg.serviceOptions(root)
+	opts.Logger = debugLog
+	s := sidegit.NewService(root, opts)
+	s.Start()
+	return s
+}
+
+// saveTab stores the active tab's state.
+func (m *model) saveTab() {
+	m.tabs[m.activeTab] = workspace{
+		root:         m.scanRoot,
+		service:      m.service,
+		config:       m.config,
+		repos:        m.repos,
+		tree:         m.tree,
+		focused:      m.focused,
+		diffOpen:     m.diffOpen,
+		diffContent:  m.diffContent,
+		diffFiles:    m.diffFiles,
+		diffLines:    m.diffLines,
+		diffRows:     m.diffRows,
+		diffUnfolded: m.diffUnfolded,
+		diffFile:     m.diffFile,
+		diffRepo:     m.diffRepo,
+		diffPages:    m.diffPages,
+		diffCut:      m.diffCut,
+		diffSize:     m.diffSize,
+		diffViewport: m.diffViewport,
+		visitedRepo:  m.visitedRepo,
+		selectRepo:   m.selectRepo,
+		selectFile:   m.selectFile,
+	}
+}
+
+// loadTab makes tab i the active one. Its repos may have been rescanned
+// while it was in the background, so the tree is rebuilt.
+func (m *model) loadTab(i int) {
+	w := m.tabs[i]
+	m.activeTab = i
+	m.scanRoot = w.root
+	m.service = w.service
+	m.useConfig(w.config)
+	m.repos = w.repos
+	m.tree = w.tree
+	m.focused = w.focused
+	m.diffOpen = w.diffOpen
+	m.diffContent = w.diffContent
+	m.diffFiles = w.diffFiles
+	m.diffLines = w.diffLines
+	m.diffRows = w.diffRows
+	m.diffUnfolded = w.diffUnfolded
+	m.diffFile = w.diffFile
+	m.diffRepo = w.diffRepo
+	m.diffPages = w.diffPages
+	m.diffCut = w.diffCut
+	m.diffSize = w.diffSize
+	m.diffViewport = w.diffViewport
+	m.visitedRepo = w.visitedRepo
+	m.selectRepo = w.selectRepo
+	m.selectFile = w.selectFile
+
+	m.applyAccents()
+	m.applyPRs()
+	m.sortRepos()
+	m.rebuildTree()
+	// The window may have been resized in the meantime
+	m.diffViewport.Width = m.diffWidth()
+	m.diffViewport.Height = m.diffHeight()
+}
+
+func (m *model) switchTab(i int) {
+	if i < 0 || i >= len(m.tabs) || i == m.activeTab {
+		return
+	}
+	m.saveTab()
+	m.loadTab(i)
+}
+
+// cycleTab switches to the next tab, or the previous one if back is set.
+func (m *model) cycleTab(back bool) {
+	if len(m.tabs) < 2 {
+		return
+	}
+	step := 1
+	if back {
+		step = len(m.tabs) - 1
+	}
+	m.switchTab((m.activeTab + step) % len(m.tabs))
+}
+
+// addTab opens root in a new tab and switches to it.
+func (m *model) addTab(root string, engine Engine, cfg Config) tea.Cmd {
+	m.saveTab()
+	m.tabs = append(m.tabs, workspace{root: root, service: engine, config: cfg})
+	m.loadTab(len(m.tabs) - 1)
+	m.diffViewport = viewport.New(m.diffWidth(), m.diffHeight())
+	engine.Refresh()
+	return tea.Batch(waitForReposCmd(engine), waitForConfigCmd(engine))
+}
+
+// closeTab closes the active tab, unless it's the last one. Stopping its
+// engine closes its updates, which ends the commands waiting on them.
+func (m *model) closeTab() {
+	if len(m.tabs) < 2 {
+		m.statusMsg = "can't close the last tab"
+		return
+	}
+	m.service.Stop()
+	i := m.activeTab
+	m.tabs = append(m.tabs[:i], m.tabs[i+1:]...)
+	m.loadTab(min(i, len(m.tabs)-1))
+}
+
+// openTabCmd prompts for a workspace (or a single repo) to open in a new
+// tab, starting from the selected repo.
+func (m model) openTabCmd() tea.Cmd {
+	value := m.scanRoot
+	if node := m.tree.SelectedNode(); node != nil && node.Repo != nil {
+		value = node.Repo.Path
+	}
+	base := m.config
+	return promptCmd(openPromptMsg{
+		title:   "Open in a new tab (path)",
+		value:   value,
+		history: "path",
+		validate: func(v string) error {
+			_, err := scanRoot(expandHome(v))
+			return err
+		},
+		onSubmit: func(v string) tea.Cmd {
+			root, _ := scanRoot(expandHome(v))
+			return func() tea.Msg {
+				cfg := tabConfig(base, root)
+				return tabOpenedMsg{root: root, engine: startEngine(cfg, root), config: cfg}
+			}
+		},
+	})
+}
+
+// tabOpenedMsg carries the engine for a new tab, started off the UI loop.
+type tabOpenedMsg struct {
+	root   string
+	engine Engine
+	config Config
+}
+
+// engineLostMsg reports that a tab's engine, a daemon connection, ended.
+type engineLostMsg struct{ from Engine }
+
+// replaceEngine swaps the engine of the tab that ran on the lost one for a
+// service of our own, so the tab keeps updating after its daemon is gone.
+func (m *model) replaceEngine(lost Engine) tea.Cmd {
+	i := m.tabOf(lost)
+	if i < 0 {
+		return nil
+	}
+	root, cfg := m.tabs[i].root, m.tabs[i].config
+	if i == m.activeTab {
+		root, cfg = m.scanRoot, m.config
+	}
+	reason := "connection lost"
+	if c, ok := lost.(*Client); ok && c.Err() != nil {
+		reason = c.Err().Error()
+	}
+	lost.Stop()
+	engine := startService(cfg, root)
+	if i == m.activeTab {
+		m.service = engine
+	}
+	m.tabs[i].service = engine
+	engine.Refresh()
+	return tea.Batch(
+		m.notifyError(fmt.Sprintf("daemon for %s: %s; scanning here instead", root, reason)),
+		waitForReposCmd(engine), waitForConfigCmd(engine))
+}
+
+// tabOf returns the index of the tab whose engine is e, or -1.
+func (m model) tabOf(e Engine) int {
+	if e == m.service {
+		return m.activeTab
+	}
+	for i, w := range m.tabs {
+		if w.service == e {
+			return i
+		}
+	}
+	return -1
+}
+
+// stopTabs stops every tab's engine.
+func (m model) stopTabs() {
+	m.saveTab()
+	for _, w := range m.tabs {
+		w.service.Stop()
+	}
+}
+
+func (m model) tabBarHeight() int {
+	if len(m.tabs) < 2 {
+		return 0
+	}
+	return 1
+}
+
+func (m model) renderTabBar() string {
+	var parts []string
+	for i, w := range m.tabs {
+		label := fmt.Sprintf(" %s%d %s ", cursorPrefix(i == m.activeTab), i+1, filepath.Base(w.root))
+		style := lipgloss.NewStyle().Foreground(themeColor(m.config.Theme.StatusBar))
+		if i == m.activeTab {
+			style = style.Bold(true).Foreground(themeColor(m.config.Theme.Title)).Background(themeColor(m.config.Theme.CursorBg))
+		}
+		parts = append(parts, style.Render(label))
+	}
+	sep := "│"
+	if accessible {
+		sep = "|"
+	}
+	bar := strings.Join(parts, lipgloss.NewStyle().Foreground(themeColor(m.config.Theme.BorderNormal)).Render(sep))
+	return lipgloss.NewStyle().MaxWidth(m.width - 2).Render(bar)
+}
+
+// expandHome expands a leading ~ in a path typed into a prompt or the
+// config.
+func expandHome(p string) string {
+	if p == "~" || strings.HasPrefix(p, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, p[1:])
+		}
+	}
+	return p
+}
@@ -0,0 +1,375 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/hermanschutte/sidegit/pkg/sidegit"
+)
+
+// Tasks are the commands from the commands setting, like "test: go test
+// ./...", run in a repo with ! or from its menu, and the git or shell
+// commands typed after :. Their output streams into a panel, and the repo
+// row shows how the last one went.
+
+// maxTaskOutput is how many bytes of a run's output are kept; older
+// output is dropped from the start.
+const maxTaskOutput = 256 << 10
+
+// taskRuns holds the last run in each repo, by path.
+type taskRuns map[string]*taskRun
+
+// last returns the last run in a repo, nil for none.
+func (ts taskRuns) last(repoPath string) *taskRun {
+	return ts[repoPath]
+}
+
+// taskRun is the last command run in a repo. Its job holds the output,
+// and why the command couldn't run or was stopped.
+type taskRun struct {
+	*job
+	repo    sidegit.Repo
+	name    string
+	command string
+	code    int // exit code, once done
+	events  chan tea.Msg
+}
+
+// failed reports whether the run ended in an error or a nonzero exit.
+func (t *taskRun) failed() bool {
+	return !t.running() && (t.code != 0 || t.err != nil)
+}
+
+// taskOutputMsg is more output of run id; taskDoneMsg says it exited.
+type taskOutputMsg struct {
+	id   int
+	text string
+}
+
+type taskDoneMsg struct {
+	id   int
+	code int
+	err  error
+}
+
+// taskStartMsg runs command in repo, shown as name.
+type taskStartMsg struct {
+	repo          sidegit.Repo
+	name, command string
+}
+
+// taskNames returns the names of the configured commands, sorted.
+func (m model) taskNames() []string {
+	var names []string
+	for name := range m.config.Commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// taskMenuOptions builds the menu of commands to run in repo: one per
+// configured command, numbered, and the output of the last run.
+func (m model) taskMenuOptions(repo sidegit.Repo) []menuOption {
+	var opts []menuOption
+	for i, name := range m.taskNames() {
+		key := ""
+		if i < 9 {
+			key = fmt.Sprint(i + 1)
+		}
+		command := m.config.Commands[name]
+		opts = append(opts, menuOption{key: key, label: name + ": " + command, action: func() tea.Cmd {
+			return func() tea.Msg { return taskStartMsg{repo: repo, name: name, command: command} }
+		}})
+	}
+	if _, ok := m.tasks[repo.Path]; ok {
+		path := repo.Path
+		opts = append(opts, menuOption{key: "o", label: "Show the output of the last run", action: func() tea.Cmd {
+			return func() tea.Msg { return taskShowMsg{repo: path} }
+		}})
+	}
+	return append(opts, menuOption{label: "Cancel"})
+}
+
+// taskShowMsg opens the output panel on a repo's last run.
+type taskShowMsg struct{ repo string }
+
+// openTaskMenu opens the command menu for the selected row's repo.
+func (m *model) openTaskMenu() {
+	node := m.tree.SelectedNode()
+	if node == nil || node.Repo == nil {
+		return
+	}
+	if len(m.config.Commands) == 0 {
+		m.statusMsg = tr("no commands set up; add them under commands in the config")
+		return
+	}
+	m.openMenu("Run in "+node.Repo.RelPath, m.taskMenuOptions(*node.Repo))
+}
+
+// execPromptCmd asks for a git command to run in the selected row's repo,
+// like "rebase --abort", or a shell command after a "!".
+func (m model) execPromptCmd() tea.Cmd {
+	node := m.tree.SelectedNode()
+	if node == nil || node.Repo == nil {
+		return nil
+	}
+	repo := *node.Repo
+	return promptCmd(openPromptMsg{
+		title:       "Run in " + repo.RelPath,
+		placeholder: "git command, e.g. rebase --abort; !command for the shell",
+		history:     "exec",
+		validate: func(input string) error {
+			if strings.TrimSpace(input) == "!" {
+				return errors.New("type a shell command after the !")
+			}
+			return nil
+		},
+		onSubmit: func(input string) tea.Cmd {
+			name, command := execCommand(input)
+			return func() tea.Msg { return taskStartMsg{repo: repo, name: name, command: command} }
+		},
+	})
+}
+
+// execCommand turns the input of the : prompt into a shell command, and a
+// name for the repo row: "git" and the subcommand, or the shell command's
+// first word.
+func execCommand(input string) (name, command string) {
+	input = strings.TrimSpace(input)
+	if shell, ok := strings.CutPrefix(input, "!"); ok {
+		command = strings.TrimSpace(shell)
+		name, _, _ = strings.Cut(command, " ")
+		return name, command
+	}
+	command = "git " + strings.TrimSpace(strings.TrimPrefix(input, "git "))
+	words := strings.Fields(command)
+	return strings.Join(words[:min(2, len(words))], " "), command
+}
+
+// startTask runs command in repo and opens the output panel on it. A
+// repo runs one command at a time.
+func (m *model) startTask(repo sidegit.Repo, name, command string) tea.Cmd {
+	if t, ok := m.tasks[repo.Path]; ok && t.running() {
+		m.showTask(repo.Path)
+		return m.notifyError(fmt.Sprintf("%s is still running in %s", t.name, repo.RelPath))
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	j := m.addJob(name+" in "+repo.RelPath, repo.Path, cancel)
+	t := &taskRun{job: j, repo: repo, name: name, command: command, events: make(chan tea.Msg)}
+	m.tasks[repo.Path] = t
+	m.showTask(repo.Path)
+	go runTask(ctx, t.id, repo.Path, command, t.events)
+	return waitForTaskCmd(t.events)
+}
+
+// runTask runs command with the shell in the repo at dir and sends its
+// combined output to events as it comes, then a taskDoneMsg, then closes
+// events.
+func runTask(ctx context.Context, id int, dir, command string, events chan<- tea.Msg) {
+	defer close(events)
+	shell, flag := "sh", "-c"
+	if runtime.GOOS == "windows" {
+		shell, flag = "cmd", "/c"
+	}
+	cmd := exec.CommandContext(ctx, shell, flag, command)
+	cmd.Dir = dir
+	// Nothing can answer a prompt: the output goes to the panel, not
+	// the terminal
+	cmd.Env = append(append(os.Environ(), sidegit.RepoEnv(dir)...), sidegit.NoPromptEnv(dir)...)
+	out := taskWriter{id: id, events: events}
+	cmd.Stdout, cmd.Stderr = out, out
+	killTree(cmd)
+	// Something the command started in the background may hold the
+	// output open after it exits; stop waiting for it
+	cmd.WaitDelay = time.Second
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	switch {
+	case ctx.Err() != nil:
+		events <- taskDoneMsg{id: id, code: -1, err: ctx.Err()}
+	case errors.As(err, &exitErr):
+		events <- taskDoneMsg{id: id, code: exitErr.ExitCode()}
+	case err != nil:
+		events <- taskDoneMsg{id: id, code: -1, err: err}
+	default:
+		events <- taskDoneMsg{id: id}
+	}
+}
+
+// taskWriter sends what a command writes to the UI as taskOutputMsgs.
+type taskWriter struct {
+	id     int
+	events chan<- tea.Msg
+}
+
+func (w taskWriter) Write(p []byte) (int, error) {
+	w.events <- taskOutputMsg{id: w.id, text: string(p)}
+	return len(p), nil
+}
+
+// waitForTaskCmd waits for the next output or the exit of a run.
+func waitForTaskCmd(events <-chan tea.Msg) tea.Cmd {
+	return func() tea.Msg {
+		return <-events
+	}
+}
+
+// taskByID finds a run still in m.tasks, nil if a newer run replaced it.
+func (m model) taskByID(id int) *taskRun {
+	for _, t := range m.tasks {
+		if t.id == id {
+			return t
+		}
+	}
+	return nil
+}
+
+// taskOutput adds output to run id, scrolling the panel along if it's
+// showing the end of it.
+func (m *model) taskOutput(msg taskOutputMsg) tea.Cmd {
+	t := m.taskByID(msg.id)
+	if t == nil {
+		return nil
+	}
+	t.output += msg.text
+	if len(t.output) > maxTaskOutput {
+		cut := len(t.output) - maxTaskOutput
+		if i := strings.IndexByte(t.output[cut:], '\n'); i >= 0 {
+			cut += i + 1 // at a line break
+		}
+		t.output = t.output[cut:]
+	}
+	if m.taskOpen && m.taskRepo == t.repo.Path {
+		m.setTaskContent()
+	}
+	return waitForTaskCmd(t.events)
+}
+
+// taskDone records how run id ended and says so in the status bar.
+func (m *model) taskDone(msg taskDoneMsg) tea.Cmd {
+	t := m.taskByID(msg.id)
+	if t == nil {
+		return nil
+	}
+	t.code = msg.code
+	t.finish(msg.err)
+	if m.taskOpen && m.taskRepo == t.repo.Path {
+		m.setTaskContent()
+	}
+	m.refresh(t.repo.Path)
+	alert := m.jobAlert(t.job, t.err != nil || t.code != 0)
+	switch {
+	case t.canceled():
+		return m.notify(fmt.Sprintf("stopped %s in %s", t.name, t.repo.RelPath))
+	case t.err != nil:
+		return tea.Batch(alert, m.notifyError(fmt.Sprintf("%s in %s: %v", t.name, t.repo.RelPath, t.err)))
+	case t.code != 0:
+		return tea.Batch(alert, m.notifyError(fmt.Sprintf("%s failed in %s (exit %d)", t.name, t.repo.RelPath, t.code)))
+	}
+	return tea.Batch(alert, m.notify(fmt.Sprintf("%s passed in %s", t.name, t.repo.RelPath)))
+}
+
+// showTask opens the output panel on repoPath's last run, at its end.
+func (m *model) showTask(repoPath string) {
+	if _, ok := m.tasks[repoPath]; !ok {
+		return
+	}
+	m.taskOpen = true
+	m.taskRepo = repoPath
+	m.taskView = viewport.New(m.width-4, m.taskViewHeight())
+	m.setTaskContent()
+	m.taskView.GotoBottom()
+}
+
+func (m model) taskViewHeight() int {
+	// Outer margin, the border and the key hints
+	return max(3, m.height-2-2-1)
+}
+
+// setTaskContent shows the output of the panel's run, wrapped to the
+// panel, staying at the end if the panel was there.
+func (m *model) setTaskContent() {
+	t := m.tasks[m.taskRepo]
+	follow := m.taskView.AtBottom()
+	m.taskView.SetContent(lipgloss.NewStyle().Width(m.taskView.Width).Render(taskText(t.output)))
+	if follow {
+		m.taskView.GotoBottom()
+	}
+}
+
+// taskText cleans output for the panel. Progress bars redraw a line with
+// carriage returns; only what the line ended up as is kept.
+func taskText(output string) string {
+	lines := strings.Split(strings.ReplaceAll(output, "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		line = strings.TrimRight(line, "\r")
+		lines[i] = line[strings.LastIndex(line, "\r")+1:]
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (m model) handleTaskKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	t := m.tasks[m.taskRepo]
+	switch msg.String() {
+	case "esc", "q", "!":
+		// Closing doesn't stop the command; its row shows when it's done
+		m.taskOpen = false
+	case "x":
+		if t.running() {
+			t.cancel()
+		}
+	case "r":
+		if !t.running() {
+			return m, m.startTask(t.repo, t.name, t.command)
+		}
+	default:
+		var cmd tea.Cmd
+		m.taskView, cmd = m.taskView.Update(msg)
+		return m, cmd
+	}
+	return m, nil
+}
+
+// taskStatus says how a run is going, e.g. "running" or "exit 1".
+func taskStatus(t *taskRun) string {
+	switch {
+	case t.running():
+		return tr("running")
+	case t.canceled():
+		return tr("stopped")
+	case t.err != nil:
+		return t.err.Error()
+	}
+	return trf("exit %d", t.code)
+}
+
+func (m model) renderTask() string {
+	t := m.tasks[m.taskRepo]
+	boxWidth := m.width - 2
+	title := fmt.Sprintf("%s in %s: %s", t.name, t.repo.RelPath, taskStatus(t))
+	hints := tr("↑↓ scroll · x stop · esc close (it keeps running)")
+	if !t.running() {
+		hints = tr("↑↓ scroll · r run again · esc close")
+	}
+	hint := lipgloss.NewStyle().Foreground(themeColor(m.config.Theme.StatusBar)).Render(truncateStr(t.command+" · "+hints, boxWidth-2))
+	content := m.taskView.View() + "\n" + hint
+	border := m.config.Theme.BorderFocused
+	if t.failed() {
+		border = m.config.Theme.StatusConflict
+	}
+	box := renderBorderedPanel(title, content, boxWidth, m.taskViewHeight()+3, border, m.config.Theme.Title)
+	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, box,
+		lipgloss.WithWhitespaceChars(" "),
+		lipgloss.WithWhitespaceForeground(lipgloss.Color("0")))
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// themeEditorWidth is the width of the theme editor panel beside the tree.
+const themeEditorWidth = 46
+
+// themeEditor edits the colors of the running theme. Every change shows in
+// the tree beside it straight away; s writes the changed colors to the
+// config file and esc puts the theme back the way it was.
+type themeEditor struct {
+	orig   Theme
+	cursor int
+}
+
+type openThemeEditorMsg struct{}
+
+// themeColorMsg sets color i (see Theme.colors) to value.
+type themeColorMsg struct {
+	i     int
+	value string
+}
+
+// themeSavedMsg reports the colors written to the config file.
+type themeSavedMsg struct {
+	n   int
+	err error
+}
+
+func (m *model) openThemeEditor() {
+	m.themeEdit = themeEditor{orig: m.config.Theme}
+	m.themeEditOpen = true
+}
+
+// setThemeColor changes color i of the running theme and redraws the tree
+// with it.
+func (m *model) setThemeColor(i int, value string) {
+	*m.config.Theme.colors()[i] = value
+	m.rebuildTree()
+}
+
+// changedColors returns the colors that differ from when the editor
+// opened, by config key.
+func (e themeEditor) changedColors(t Theme) map[string]string {
+	changed := map[string]string{}
+	names, orig := colorNames(), e.orig.colors()
+	for i, c := range t.colors() {
+		if *c != *orig[i] {
+			changed[names[i]] = *c
+		}
+	}
+	return changed
+}
+
+func (m model) handleThemeEditorKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	e := &m.themeEdit
+	colors := m.config.Theme.colors()
+	switch msg.String() {
+	case "esc", "q":
+		m.config.Theme = e.orig
+		m.rebuildTree()
+		m.themeEditOpen = false
+	case "up", "k":
+		if e.cursor > 0 {
+			e.cursor--
+		}
+	case "down", "j":
+		if e.cursor < len(colors)-1 {
+			e.cursor++
+		}
+	case "left", "h", "right", "l":
+		delta := 1
+		if msg.String() == "left" || msg.String() == "h" {
+			delta = -1
+		}
+		if c, ok := stepColor(*colors[e.cursor], delta); ok {
+			m.setThemeColor(e.cursor, c)
+		} else {
+			m.statusMsg = "only ANSI numbers step; press enter to type a value"
+		}
+	case "r":
+		m.setThemeColor(e.cursor, *e.orig.colors()[e.cursor])
+	case "enter":
+		i, name := e.cursor, colorNames()[e.cursor]
+		return m, promptCmd(openPromptMsg{
+			title:       "theme." + name,
+			value:       *colors[i],
+			placeholder: `0-255, #rrggbb or "light|dark"`,
+			validate: func(s string) error {
+				if s == "" || !validColor(s) {
+					return fmt.Errorf("not a color: an ANSI number, a hex code, or a light|dark pair of them")
+				}
+				return nil
+			},
+			onSubmit: func(s string) tea.Cmd {
+				return func() tea.Msg { return themeColorMsg{i: i, value: s} }
+			},
+		})
+	case "s":
+		changed := e.changedColors(m.config.Theme)
+		if len(changed) == 0 {
+			m.themeEditOpen = false
+			return m, m.notify("no colors changed")
+		}
+		path := m.config.Overrides.configFile()
+		m.themeEditOpen = false
+		return m, func() tea.Msg {
+			return themeSavedMsg{n: len(changed), err: setThemeColors(path, changed)}
+		}
+	}
+	return m, nil
+}
+
+// stepColor moves an ANSI color number by delta, wrapping around 0-255. In
+// a "light|dark" pair it moves the half for the terminal's background.
+func stepColor(s string, delta int) (string, bool) {
+	if light, dark, ok := strings.Cut(s, "|"); ok {
+		if lipgloss.HasDarkBackground() {
+			dark, ok = stepColor(dark, delta)
+		} else {
+			light, ok = stepColor(light, delta)
+		}
+		return light + "|" + dark, ok
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 || n > 255 {
+		return s, false
+	}
+	return strconv.Itoa((n + delta + 256) % 256), true
+}
+
+func (m model) renderThemeEditor(width, height int) string {
+	innerWidth := width - 2
+	names := colorNames()
+	colors := m.config.Theme.colors()
+	orig := m.themeEdit.orig.colors()
+	hintStyle := lipgloss.NewStyle().Foreground(themeColor(m.config.Theme.StatusBar))
+
+	var rows []string
+	for i, c := range colors {
+		bg := lipgloss.NewStyle()
+		if i == m.themeEdit.cursor {
+			bg = bg.Background(themeColor(m.config.Theme.CursorBg))
+		}
+		mark := " "
+		if *c != *orig[i] {
+			mark = "*"
+		}
+		swatch := lipgloss.NewStyle().Background(themeColor(*c)).Render("   ")
+		line := bg.Render(fmt.Sprintf(" %s %-17s ", mark, names[i])) + swatch + bg.Render(" "+*c)
+		if vis := lipgloss.Width(line); vis < innerWidth {
+			line += bg.Render(strings.Repeat(" ", innerWidth-vis))
+		}
+		rows = append(rows, line)
+	}
+
+	// Keep the cursor in view above the hint lines
+	hint := strings.Split(lipgloss.NewStyle().Width(innerWidth).Render(
+		trf("↑↓ pick · ←→ step · ↵ type · r reset · s save to %s · esc cancel", filepath.Base(m.config.Overrides.configFile()))), "\n")
+	visible := max(1, height-2-len(hint)-1)
+	start := 0
+	if m.themeEdit.cursor >= visible {
+		start = m.themeEdit.cursor - visible + 1
+	}
+	rows = rows[start:min(len(rows), start+visible)]
+	for len(rows) < visible {
+		rows = append(rows, "")
+	}
+	rows = append(rows, "")
+	for _, h := range hint {
+		rows = append(rows, hintStyle.Render(h))
+	}
+
+	return renderBorderedPanel(tr("Theme"), strings.Join(rows, "\n"), width, height, m.config.Theme.BorderFocused, m.config.Theme.Title)
+}
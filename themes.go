@@ -0,0 +1,208 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/charmbracelet/lipgloss"
+	"gopkg.in/yaml.v3"
+)
+
+// builtinThemes are the themes selectable by name, with --theme,
+// `theme: name` in the config, or the T picker. Hex colors are downgraded
+// to the nearest 256 or 16 color by lipgloss on terminals without true
+// color support.
+var builtinThemes = map[string]func() Theme{
+	"default":    DefaultTheme,
+	"dark":       darkTheme,
+	"light":      lightTheme,
+	"mono":       monoTheme,
+	"catppuccin": palette{cursor: "#313244", border: "#45475a", muted: "#6c7086", text: "#a6adc8", primary: "#89b4fa", secondary: "#cba6f7", green: "#a6e3a1", red: "#f38ba8", yellow: "#f9e2af", orange: "#fab387", accent: "#94e2d5"}.theme,
+	"gruvbox":    palette{cursor: "#3c3836", border: "#504945", muted: "#928374", text: "#d5c4a1", primary: "#83a598", secondary: "#d3869b", green: "#b8bb26", red: "#fb4934", yellow: "#fabd2f", orange: "#fe8019", accent: "#8ec07c"}.theme,
+	"dracula":    palette{cursor: "#44475a", border: "#6272a4", muted: "#6272a4", text: "#f8f8f2", primary: "#bd93f9", secondary: "#ff79c6", green: "#50fa7b", red: "#ff5555", yellow: "#f1fa8c", orange: "#ffb86c", accent: "#8be9fd"}.theme,
+	"solarized":  palette{cursor: "#073642", border: "#586e75", muted: "#586e75", text: "#93a1a1", primary: "#268bd2", secondary: "#d33682", green: "#859900", red: "#dc322f", yellow: "#b58900", orange: "#cb4b16", accent: "#2aa198"}.theme,
+	"nord":       palette{cursor: "#3b4252", border: "#4c566a", muted: "#616e88", text: "#d8dee9", primary: "#81a1c1", secondary: "#b48ead", green: "#a3be8c", red: "#bf616a", yellow: "#ebcb8b", orange: "#d08770", accent: "#88c0d0"}.theme,
+}
+
+// palette is the handful of colors a preset is built from.
+type palette struct {
+	cursor, border, muted, text string
+	primary, secondary, accent  string
+	green, red, yellow, orange  string
+}
+
+func (p palette) theme() Theme {
+	return Theme{
+		CursorBg:        p.cursor,
+		BorderFocused:   p.primary,
+		BorderNormal:    p.border,
+		Title:           p.accent,
+		StatusBar:       p.muted,
+		NoRepos:         p.muted,
+		RepoName:        p.primary,
+		BranchName:      p.secondary,
+		Detached:        p.orange,
+		FileCount:       p.text,
+		FolderIcon:      p.text,
+		DirName:         p.text,
+		StatusStaged:    p.green,
+		StatusAdded:     p.green,
+		StatusDeleted:   p.red,
+		StatusModified:  p.yellow,
+		StatusUntracked: p.muted,
+		StatusConflict:  p.red,
+		DefaultIcon:     p.text,
+		AheadColor:      p.green,
+		BehindColor:     p.red,
+		TreeLines:       p.border,
+		Warning:         p.yellow,
+		LineNumber:      p.muted,
+	}
+}
+
+// lightTheme swaps the bright accents for darker ones that stay readable
+// on a light terminal background.
+func lightTheme() Theme {
+	t := darkTheme()
+	t.CursorBg = "254"
+	t.BorderFocused = "4"
+	t.BorderNormal = "250"
+	t.Title = "6"
+	t.StatusBar = "244"
+	t.NoRepos = "244"
+	t.RepoName = "4"
+	t.BranchName = "5"
+	t.Detached = "166"
+	t.FileCount = "240"
+	t.FolderIcon = "240"
+	t.DirName = "240"
+	t.StatusStaged = "2"
+	t.StatusAdded = "2"
+	t.StatusDeleted = "1"
+	t.StatusModified = "3"
+	t.StatusUntracked = "244"
+	t.StatusConflict = "1"
+	t.DefaultIcon = "240"
+	t.AheadColor = "2"
+	t.BehindColor = "1"
+	t.TreeLines = "250"
+	t.Warning = "3"
+	t.LineNumber = "250"
+	return t
+}
+
+// monoTheme uses greys only, for screenshots and low-color terminals.
+func monoTheme() Theme {
+	t := DefaultTheme()
+	for _, c := range []*string{
+		&t.RepoName, &t.BranchName, &t.Detached, &t.StatusStaged,
+		&t.StatusAdded, &t.StatusDeleted, &t.StatusModified, &t.StatusConflict,
+		&t.AheadColor, &t.BehindColor, &t.Warning, &t.Title, &t.BorderFocused,
+	} {
+		*c = "0|15"
+	}
+	return t
+}
+
+var (
+	terminalDarkOnce sync.Once
+	terminalDark     bool
+)
+
+// applyBackground honors the background setting. "auto" falls back to what
+// the terminal reported at startup.
+func applyBackground(background string) {
+	terminalDarkOnce.Do(func() { terminalDark = lipgloss.HasDarkBackground() })
+	switch background {
+	case "light":
+		lipgloss.SetHasDarkBackground(false)
+	case "dark":
+		lipgloss.SetHasDarkBackground(true)
+	default:
+		lipgloss.SetHasDarkBackground(terminalDark)
+	}
+}
+
+// LookupTheme returns the named theme: a built-in one, or
+// ~/.config/sidegit/themes/<name>.yaml.
+func LookupTheme(name string) (Theme, error) {
+	if fn, ok := builtinThemes[name]; ok {
+		return fn(), nil
+	}
+	data, err := os.ReadFile(filepath.Join(configDir(), "themes", name+".yaml"))
+	if err != nil {
+		return Theme{}, fmt.Errorf("unknown theme %q (built-in: %s)", name, builtinThemeNames())
+	}
+	t := DefaultTheme()
+	if err := yaml.Unmarshal(data, &t); err != nil {
+		return Theme{}, fmt.Errorf("theme %q: %v", name, err)
+	}
+	applyThemeDefaults(&t)
+	return t, nil
+}
+
+// sortedThemeNames lists the built-in themes alphabetically.
+func sortedThemeNames() []string {
+	names := make([]string, 0, len(builtinThemes))
+	for name := range builtinThemes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func builtinThemeNames() string {
+	return strings.Join(sortedThemeNames(), ", ")
+}
+
+// UnmarshalYAML accepts either a preset name (`theme: nord`) or a map of
+// colors. A map may name a `preset` to start from; colors it sets win. An
+// unknown preset is a *yaml.TypeError, so the rest of the config still
+// decodes.
+func (t *Theme) UnmarshalYAML(n *yaml.Node) error {
+	type plain Theme
+	if n.Kind == yaml.ScalarNode {
+		preset, err := LookupTheme(n.Value)
+		if err != nil {
+			return presetError(n, err)
+		}
+		*t = preset
+		return nil
+	}
+	var base struct {
+		Preset string `yaml:"preset"`
+	}
+	if err := n.Decode(&base); err != nil {
+		return err
+	}
+	var presetErr error
+	if base.Preset != "" {
+		preset, err := LookupTheme(base.Preset)
+		if err != nil {
+			presetErr = presetError(n, err) // the colors set here still apply
+		} else {
+			*t = preset
+		}
+	}
+	if err := n.Decode((*plain)(t)); err != nil {
+		return err
+	}
+	return presetErr
+}
+
+func presetError(n *yaml.Node, err error) error {
+	return &yaml.TypeError{Errors: []string{fmt.Sprintf("line %d: %v", n.Line, err)}}
+}
+
+// themeColor turns a theme value into a color. A "light|dark" pair adapts
+// to the terminal background; anything else is used as is.
+func themeColor(s string) lipgloss.TerminalColor {
+	if light, dark, ok := strings.Cut(s, "|"); ok {
+		return lipgloss.AdaptiveColor{Light: light, Dark: dark}
+	}
+	return lipgloss.Color(s)
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// tourTarget is the part of the UI a tour step points at.
+type tourTarget int
+
+const (
+	tourTargetNone tourTarget = iota
+	tourTargetTree
+	tourTargetDiff
+	tourTargetStatusBar
+)
+
+// tourStep is one page of the first-run onboarding tour.
+type tourStep struct {
+	title  string
+	text   string
+	target tourTarget
+}
+
+var tourSteps = []tourStep{
+	{
+		title: "Welcome to sidegit",
+		text:  "sidegit shows the uncommitted changes of every git repo below the current directory in a single tree.",
+	},
+	{
+		title:  "↑ The tree",
+		text:   "Repos are listed with their branch, change count and ahead/behind. Move with ↑/k and ↓/j, collapse or expand with c/e.",
+		target: tourTargetTree,
+	},
+	{
+		title:  "↑ The diff panel",
+		text:   "↵ on a file opens its diff here. ⇥ switches focus between tree and diff, esc closes it and p toggles the layout.",
+		target: tourTargetDiff,
+	},
+	{
+		title:  "↓ Key actions",
+		text:   "o opens a file in $EDITOR, d discards changes, b switches branch, s pulls or pushes and r refreshes. Press ? any time for the full list.",
+		target: tourTargetStatusBar,
+	},
+}
+
+func (m *model) nextTourStep() {
+	if m.tourStep < len(tourSteps)-1 {
+		m.tourStep++
+		return
+	}
+	m.closeTour()
+}
+
+func (m *model) prevTourStep() {
+	if m.tourStep > 0 {
+		m.tourStep--
+	}
+}
+
+func (m *model) closeTour() {
+	m.tourOpen = false
+	m.tourStep = 0
+}
+
+// tourHighlights reports whether the open tour step points at target.
+func (m model) tourHighlights(target tourTarget) bool {
+	return m.tourOpen && tourSteps[m.tourStep].target == target
+}
+
+// renderTour draws the current tour step as a box anchored below the panels.
+func (m model) renderTour(width int) string {
+	step := tourSteps[m.tourStep]
+	innerWidth := width - 2
+
+	text := lipgloss.NewStyle().Width(innerWidth).Render(step.text)
+	hint := fmt.Sprintf("%d/%d  ↵ next · ← back · esc skip · q quit", m.tourStep+1, len(tourSteps))
+	hint = lipgloss.NewStyle().Width(innerWidth).Foreground(themeColor(m.config.Theme.StatusBar)).Render(hint)
+
+	lines := strings.Split(text, "\n")
+	lines = append(lines, strings.Split(hint, "\n")...)
+
+	return renderBorderedPanel(step.title, strings.Join(lines, "\n"), width, len(lines)+2, m.config.Theme.Title, m.config.Theme.Title)
+}
@@ -2,11 +2,15 @@ package main
 
 import (
 	"fmt"
+	"hash/fnv"
+	"path"
 	"path/filepath"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/lipgloss"
+	"github.com/hermanschutte/sidegit/pkg/sidegit"
 )
 
 type NodeKind int
@@ -15,15 +19,22 @@ const (
 	NodeRepo NodeKind = iota
 	NodeDir
 	NodeFile
+	NodeGroup // a header for the repos of a configured group; Repo is nil
 )
 
 type TreeNode struct {
 	Kind        NodeKind
-	Repo        *Repo
-	File        *FileStatus
+	Repo        *sidegit.Repo
+	File        *sidegit.FileStatus
 	DirPath     string // for NodeDir: the directory path
+	Group       string // for NodeGroup: the group name
+	groupRepos  int    // for NodeGroup: repos in the group
+	groupFiles  int    // for NodeGroup: changed files across them
+	dirFull     string // for NodeDir: path relative to the repo root
 	RepoIndex   int
-	Depth       int  // indentation depth (0=repo, 1=dir/root file, 2=file under dir)
+	Depth       int    // indentation depth (0=repo, 1=dir/root file, 2=file under dir)
+	nest        int    // how many repos the node's repo is nested in
+	repoName    string // for a nested NodeRepo: its path below the enclosing repo
 	Collapsed   bool
 	ParentDir   int  // index of parent dir node (-1 if none)
 	IsLastChild bool // true if this is the last child of its parent
@@ -34,25 +45,65 @@ type TreeModel struct {
 	visible []int
 	cursor  int
 	theme   Theme
+
+	ShowAges bool // how long ago each file changed, in a column on the right
+	// Reviewed reports whether a file is marked reviewed, shown with a
+	// checkmark on the right; nil for none
+	Reviewed func(repoPath, filePath string) bool
+	// Note returns the note on a file, or on a repo for filePath "",
+	// marked with a pencil on the right; nil for none
+	Note func(repoPath, filePath string) string
+	// Task returns the last command run in a repo, shown on its row with
+	// how it went; nil for none
+	Task func(repoPath string) *taskRun
+	// Stale reports whether a repo has gone quiet, drawn dimmed; nil for
+	// none
+	Stale func(repo sidegit.Repo) bool
 }
 
-func NewTreeModel(repos []Repo, theme Theme) TreeModel {
+// NewTreeModel builds the tree of repos. With recent, the files and
+// directories of each repo are ordered by when they last changed, newest
+// first, instead of by name. With nested, repos inside other repos are
+// indented below them and collapse with them.
+func NewTreeModel(repos []sidegit.Repo, groups []RepoGroup, theme Theme, recent, nested bool) TreeModel {
 	var nodes []TreeNode
-	for i := range repos {
+	var parents map[int]int
+	if nested {
+		parents = enclosingRepos(repos)
+	}
+	order, starts := groupRepos(repos, groups, parents)
+	groupIdx := -1
+	repoNodes := map[int]int{}  // repo index -> node index
+	repoGroups := map[int]int{} // repo index -> node index of its group header
+	for _, i := range order {
+		if g, ok := starts[i]; ok {
+			groupIdx = len(nodes)
+			nodes = append(nodes, g)
+		}
 		repoIdx := len(nodes)
-		nodes = append(nodes, TreeNode{
+		repoNodes[i], repoGroups[i] = repoIdx, groupIdx
+		repoNode := TreeNode{
 			Kind:      NodeRepo,
 			Repo:      &repos[i],
 			RepoIndex: i,
 			Depth:     0,
-			ParentDir: -1,
-		})
+			ParentDir: groupIdx,
+		}
+		if p, ok := parents[i]; ok {
+			if pi, ok := repoNodes[p]; ok && repoGroups[p] == groupIdx {
+				repoNode.ParentDir = pi
+				repoNode.nest = nodes[pi].nest + 1
+				repoNode.repoName = strings.TrimPrefix(repos[i].RelPath, repos[p].RelPath+"/")
+			}
+		}
+		nest := repoNode.nest
+		nodes = append(nodes, repoNode)
 
 		// Group files by directory
-		dirFiles := map[string][]*FileStatus{} // dir -> files
+		dirFiles := map[string][]*sidegit.FileStatus{} // dir -> files
 		for j := range repos[i].Files {
 			f := &repos[i].Files[j]
-			dir := filepath.Dir(f.Path)
+			dir := path.Dir(f.Path) // git paths always use "/"
 			if dir == "." {
 				dir = ""
 			}
@@ -75,6 +126,9 @@ func NewTreeModel(repos []Repo, theme Theme) TreeModel {
 			allDirs = append(allDirs, d)
 		}
 		sort.Strings(allDirs)
+		if recent {
+			allDirs = recentFirst(dirFiles, allDirs)
+		}
 
 		// Build directory nodes hierarchically
 		dirNodeIdx := map[string]int{} // dir path -> node index
@@ -96,9 +150,11 @@ func NewTreeModel(repos []Repo, theme Theme) TreeModel {
 			nodes = append(nodes, TreeNode{
 				Kind:      NodeDir,
 				DirPath:   parts[len(parts)-1], // show just the last segment
+				dirFull:   dir,
 				Repo:      &repos[i],
 				RepoIndex: i,
 				Depth:     depth,
+				nest:      nest,
 				ParentDir: parentIdx,
 			})
 			// Add files that belong directly to this directory
@@ -110,6 +166,7 @@ func NewTreeModel(repos []Repo, theme Theme) TreeModel {
 						Repo:      &repos[i],
 						RepoIndex: i,
 						Depth:     depth + 1,
+						nest:      nest,
 						ParentDir: dirIdx,
 					})
 				}
@@ -128,6 +185,7 @@ func NewTreeModel(repos []Repo, theme Theme) TreeModel {
 					Repo:      &repos[i],
 					RepoIndex: i,
 					Depth:     1,
+					nest:      nest,
 					ParentDir: repoIdx,
 				})
 			}
@@ -137,7 +195,7 @@ func NewTreeModel(repos []Repo, theme Theme) TreeModel {
 	// Mark last children: group by parent, last child in each group gets IsLastChild
 	lastChildByParent := map[int]int{} // parentIdx -> last child node index
 	for i, n := range nodes {
-		if n.Kind == NodeRepo {
+		if n.Kind == NodeRepo || n.Kind == NodeGroup {
 			continue
 		}
 		lastChildByParent[n.ParentDir] = i
@@ -151,12 +209,192 @@ func NewTreeModel(repos []Repo, theme Theme) TreeModel {
 	return tm
 }
 
+// recentFirst sorts each directory's files newest first, and returns dirs
+// (sorted by name) reordered so that siblings are newest first too. The
+// result still lists every directory before its subdirectories.
+func recentFirst(dirFiles map[string][]*sidegit.FileStatus, dirs []string) []string {
+	newest := map[string]time.Time{}
+	for dir, files := range dirFiles {
+		sort.SliceStable(files, func(a, b int) bool { return files[a].ModTime.After(files[b].ModTime) })
+		if len(files) == 0 {
+			continue
+		}
+		// Sorted, so the first file is the newest; it counts for every
+		// ancestor too
+		for d := dir; d != "" && d != "."; d = path.Dir(d) {
+			if files[0].ModTime.After(newest[d]) {
+				newest[d] = files[0].ModTime
+			}
+		}
+	}
+	children := map[string][]string{}
+	for _, d := range dirs {
+		parent := path.Dir(d)
+		if parent == "." {
+			parent = ""
+		}
+		children[parent] = append(children[parent], d)
+	}
+	var order []string
+	var walk func(dir string)
+	walk = func(dir string) {
+		kids := children[dir]
+		sort.SliceStable(kids, func(a, b int) bool { return newest[kids[a]].After(newest[kids[b]]) })
+		for _, k := range kids {
+			order = append(order, k)
+			walk(k)
+		}
+	}
+	walk("")
+	return order
+}
+
+// groupRepos orders repos by group, in config order, with repos that
+// match no group last under "other". It returns the order and the header
+// node to insert before the first repo of each group. Without groups the
+// order is unchanged and there are no headers. Repos with an entry in
+// parents come right after the repo they are nested in.
+func groupRepos(repos []sidegit.Repo, groups []RepoGroup, parents map[int]int) ([]int, map[int]TreeNode) {
+	members := make([][]int, len(groups)+1) // the extra one is "other"
+	for i, r := range repos {
+		g := len(groups)
+		for j, group := range groups {
+			if sidegit.MatchGlob(group.Repos, r.RelPath) {
+				g = j
+				break
+			}
+		}
+		members[g] = append(members[g], i)
+	}
+	var order []int
+	starts := map[int]TreeNode{}
+	for g, idx := range members {
+		if len(idx) == 0 {
+			continue
+		}
+		idx = nestRepos(idx, parents)
+		if len(groups) > 0 {
+			name := "other"
+			if g < len(groups) {
+				name = groups[g].Name
+			}
+			header := TreeNode{Kind: NodeGroup, Group: name, ParentDir: -1, groupRepos: len(idx)}
+			for _, i := range idx {
+				header.groupFiles += len(repos[i].Files)
+			}
+			starts[idx[0]] = header
+		}
+		order = append(order, idx...)
+	}
+	return order, starts
+}
+
+// enclosingRepos maps the index of each repo that lies inside another
+// repo in repos to the index of the innermost one.
+func enclosingRepos(repos []sidegit.Repo) map[int]int {
+	parents := map[int]int{}
+	for i, r := range repos {
+		best := -1
+		for j, p := range repos {
+			if i != j && strings.HasPrefix(r.Path, p.Path+string(filepath.Separator)) && (best < 0 || len(p.Path) > len(repos[best].Path)) {
+				best = j
+			}
+		}
+		if best >= 0 {
+			parents[i] = best
+		}
+	}
+	return parents
+}
+
+// nestRepos moves every repo in order whose parent is in order too to
+// just after the parent, keeping the order otherwise.
+func nestRepos(order []int, parents map[int]int) []int {
+	if len(parents) == 0 {
+		return order
+	}
+	in := map[int]bool{}
+	for _, i := range order {
+		in[i] = true
+	}
+	children := map[int][]int{}
+	var top []int
+	for _, i := range order {
+		if p, ok := parents[i]; ok && in[p] {
+			children[p] = append(children[p], i)
+		} else {
+			top = append(top, i)
+		}
+	}
+	out := make([]int, 0, len(order))
+	var add func(i int)
+	add = func(i int) {
+		out = append(out, i)
+		for _, c := range children[i] {
+			add(c)
+		}
+	}
+	for _, i := range top {
+		add(i)
+	}
+	return out
+}
+
+// nodeKey identifies a node across rebuilds of the tree.
+func nodeKey(n TreeNode) string {
+	switch n.Kind {
+	case NodeGroup:
+		return "\x00g\x00" + n.Group
+	case NodeDir:
+		return n.Repo.Path + "\x00d\x00" + n.dirFull
+	case NodeFile:
+		return n.Repo.Path + "\x00f\x00" + n.File.Path
+	default:
+		return n.Repo.Path
+	}
+}
+
+// Restore carries the collapsed state and cursor position of prev over to
+// a freshly built tree, so refreshes don't reset navigation.
+func (tm *TreeModel) Restore(prev TreeModel) {
+	collapsed := map[string]bool{}
+	for _, n := range prev.nodes {
+		if n.Collapsed {
+			collapsed[nodeKey(n)] = true
+		}
+	}
+	for i := range tm.nodes {
+		if collapsed[nodeKey(tm.nodes[i])] {
+			tm.nodes[i].Collapsed = true
+		}
+	}
+	tm.rebuildVisible()
+
+	sel := prev.SelectedNode()
+	if sel == nil {
+		return
+	}
+	key := nodeKey(*sel)
+	for vi, idx := range tm.visible {
+		if nodeKey(tm.nodes[idx]) == key {
+			tm.cursor = vi
+			return
+		}
+	}
+	// The selected node is gone (e.g. a discarded file): stay at the same row
+	tm.cursor = min(prev.cursor, max(0, len(tm.visible)-1))
+}
+
 func (tm *TreeModel) rebuildVisible() {
 	tm.visible = nil
 	for i, n := range tm.nodes {
 		switch n.Kind {
-		case NodeRepo:
+		case NodeGroup:
 			tm.visible = append(tm.visible, i)
+		case NodeRepo:
+			if tm.isAncestorExpanded(n) {
+				tm.visible = append(tm.visible, i)
+			}
 		case NodeDir:
 			// Visible if all ancestors are expanded
 			if tm.isAncestorExpanded(n) {
@@ -202,12 +440,152 @@ func (tm *TreeModel) ToggleCollapse() {
 	if node == nil {
 		return
 	}
-	if node.Kind == NodeRepo || node.Kind == NodeDir {
+	if node.Kind != NodeFile {
 		node.Collapsed = !node.Collapsed
 		tm.rebuildVisible()
 	}
 }
 
+// SelectRepo expands the repo at repoPath and moves the cursor onto it.
+func (tm *TreeModel) SelectRepo(repoPath string) {
+	for i := range tm.nodes {
+		n := &tm.nodes[i]
+		if n.Kind != NodeRepo || n.Repo.Path != repoPath {
+			continue
+		}
+		n.Collapsed = false
+		if n.ParentDir >= 0 {
+			tm.nodes[n.ParentDir].Collapsed = false // its group
+		}
+		tm.rebuildVisible()
+		for vi, idx := range tm.visible {
+			if idx == i {
+				tm.cursor = vi
+				return
+			}
+		}
+	}
+}
+
+// SelectFile expands the ancestors of a file and moves the cursor onto it.
+// It reports false when the file isn't in the tree (yet).
+func (tm *TreeModel) SelectFile(repoPath, filePath string) bool {
+	for i := range tm.nodes {
+		n := &tm.nodes[i]
+		if n.Kind != NodeFile || n.Repo.Path != repoPath || n.File.Path != filePath {
+			continue
+		}
+		for p := n.ParentDir; p >= 0; p = tm.nodes[p].ParentDir {
+			tm.nodes[p].Collapsed = false
+		}
+		for j := range tm.nodes {
+			if tm.nodes[j].Kind == NodeRepo && tm.nodes[j].Repo.Path == repoPath {
+				tm.nodes[j].Collapsed = false
+			}
+		}
+		tm.rebuildVisible()
+		for vi, idx := range tm.visible {
+			if idx == i {
+				tm.cursor = vi
+				return true
+			}
+		}
+		return false
+	}
+	return false
+}
+
+// SelectNextFile moves the cursor to the next visible file that want
+// accepts, wrapping around to the top, and reports whether there was one.
+func (tm *TreeModel) SelectNextFile(want func(TreeNode) bool) bool {
+	for step := 1; step < len(tm.visible); step++ {
+		i := (tm.cursor + step) % len(tm.visible)
+		if n := tm.nodes[tm.visible[i]]; n.Kind == NodeFile && want(n) {
+			tm.cursor = i
+			return true
+		}
+	}
+	return false
+}
+
+// isReviewed reports whether node is a file marked reviewed.
+func (tm *TreeModel) isReviewed(node TreeNode) bool {
+	return node.Kind == NodeFile && tm.Reviewed != nil && tm.Reviewed(node.Repo.Path, node.File.Path)
+}
+
+// note returns the note on a repo or file node, "" for none.
+func (tm *TreeModel) note(node TreeNode) string {
+	if tm.Note == nil {
+		return ""
+	}
+	switch node.Kind {
+	case NodeRepo:
+		return tm.Note(node.Repo.Path, "")
+	case NodeFile:
+		return tm.Note(node.Repo.Path, node.File.Path)
+	}
+	return ""
+}
+
+// task returns the last command run in a repo node's repo, nil for none
+// or on other nodes.
+func (tm *TreeModel) task(node TreeNode) *taskRun {
+	if node.Kind != NodeRepo || tm.Task == nil {
+		return nil
+	}
+	return tm.Task(node.Repo.Path)
+}
+
+// SelectedDirFiles returns the files beneath the selected directory, at
+// any depth, or nil when the cursor isn't on a directory.
+func (tm *TreeModel) SelectedDirFiles() []sidegit.FileStatus {
+	node := tm.SelectedNode()
+	if node == nil || node.Kind != NodeDir {
+		return nil
+	}
+	dir := tm.visible[tm.cursor]
+	var files []sidegit.FileStatus
+	for i, n := range tm.nodes {
+		if n.Kind == NodeFile && tm.isUnder(i, dir) {
+			files = append(files, *n.File)
+		}
+	}
+	return files
+}
+
+// CollapseOthers collapses every directory of the selected one's repo
+// except the selected directory and its ancestors, which are expanded.
+func (tm *TreeModel) CollapseOthers() {
+	node := tm.SelectedNode()
+	if node == nil || node.Kind != NodeDir {
+		return
+	}
+	sel := tm.visible[tm.cursor]
+	for i := range tm.nodes {
+		n := &tm.nodes[i]
+		if n.Kind == NodeDir && n.RepoIndex == node.RepoIndex {
+			n.Collapsed = i != sel && !tm.isUnder(sel, i)
+		}
+	}
+	tm.nodes[sel].Collapsed = false
+	tm.rebuildVisible()
+	for vi, idx := range tm.visible {
+		if idx == sel {
+			tm.cursor = vi
+		}
+	}
+}
+
+// isUnder reports whether node i is a descendant of node ancestor.
+func (tm *TreeModel) isUnder(i, ancestor int) bool {
+	for p := tm.nodes[i].ParentDir; p >= 0; p = tm.nodes[p].ParentDir {
+		if p == ancestor {
+			return true
+		}
+	}
+	return false
+}
+
 func (tm *TreeModel) SelectedNode() *TreeNode {
 	if len(tm.visible) == 0 || tm.cursor < 0 || tm.cursor >= len(tm.visible) {
 		return nil
@@ -225,24 +603,74 @@ func (tm *TreeModel) Render(width, height int) string {
 			Width(width).
 			Height(height).
 			Align(lipgloss.Center, lipgloss.Center).
-			Foreground(lipgloss.Color(tm.theme.NoRepos)).
-			Render("No git repositories found.\nRun sidegit in a directory containing git repos.")
+			Foreground(themeColor(tm.theme.NoRepos)).
+			Render(tr("No git repositories found.\nRun sidegit in a directory containing git repos."))
+	}
+	if accessible {
+		return tm.renderLinear(width, height)
 	}
 
+	ageStyle := lipgloss.NewStyle().Foreground(themeColor(tm.theme.FileCount))
 	startIdx := 0
 	if tm.cursor >= height {
 		startIdx = tm.cursor - height + 1
 	}
 
 	var lines []string
-	cursorBg := lipgloss.Color(tm.theme.CursorBg)
-	treeLine := lipgloss.Color(tm.theme.TreeLines)
+	cursorBg := themeColor(tm.theme.CursorBg)
+	treeLine := themeColor(tm.theme.TreeLines)
 	for i := startIdx; i < len(tm.visible) && len(lines) < height; i++ {
 		node := tm.nodes[tm.visible[i]]
 		selected := i == tm.cursor
-		prefix := tm.buildTreePrefix(node, selected, cursorBg, treeLine)
-		line := renderNode(node, selected, width, tm.theme, cursorBg, prefix)
-		line = padRight(line, width, selected, cursorBg)
+		if node.Repo != nil && tm.Stale != nil && tm.Stale(*node.Repo) {
+			// Drawn in the dim color rather than the repo's own
+			r := *node.Repo
+			r.Accent, r.BranchColor = tm.theme.FileCount, tm.theme.FileCount
+			node.Repo = &r
+		}
+		lineColor := treeLine
+		if node.Repo != nil && node.Repo.Accent != "" {
+			// Tint the connectors so files are visibly tied to their repo
+			lineColor = themeColor(node.Repo.Accent)
+		}
+		prefix := tm.buildTreePrefix(node, selected, cursorBg, lineColor)
+		rowWidth, age, mark := width, "", ""
+		if tm.ShowAges && node.Kind == NodeFile && !node.File.ModTime.IsZero() && width > 20 {
+			age = fmt.Sprintf(" %4s", formatAge(time.Since(node.File.ModTime)))
+			rowWidth -= len(age)
+		}
+		if width > 10 {
+			markStyle := lipgloss.NewStyle()
+			if selected {
+				markStyle = markStyle.Background(cursorBg)
+			}
+			if t := tm.task(node); t != nil {
+				glyph, color := "●", tm.theme.Warning
+				switch {
+				case t.failed():
+					glyph, color = "✗", tm.theme.StatusConflict
+				case !t.running():
+					glyph, color = "✓", tm.theme.StatusAdded
+				}
+				mark += markStyle.Foreground(themeColor(color)).Render(" " + glyph + " " + truncateStr(t.name, 12))
+			}
+			if tm.note(node) != "" {
+				mark += markStyle.Foreground(themeColor(tm.theme.StatusModified)).Render(" ✎")
+			}
+			if tm.isReviewed(node) {
+				mark += markStyle.Foreground(themeColor(tm.theme.StatusAdded)).Render(" ✓")
+			}
+			rowWidth -= lipgloss.Width(mark)
+		}
+		line := renderNode(node, selected, rowWidth, tm.theme, cursorBg, prefix)
+		line = padRight(line, rowWidth, selected, cursorBg) + mark
+		if age != "" {
+			if selected {
+				line += ageStyle.Background(cursorBg).Render(age)
+			} else {
+				line += ageStyle.Render(age)
+			}
+		}
 		lines = append(lines, line)
 	}
 
@@ -253,8 +681,8 @@ func (tm *TreeModel) Render(width, height int) string {
 	return strings.Join(lines, "\n")
 }
 
-func (tm *TreeModel) buildTreePrefix(node TreeNode, selected bool, cursorBg, treeLine lipgloss.Color) string {
-	if node.Kind == NodeRepo || node.Depth == 0 {
+func (tm *TreeModel) buildTreePrefix(node TreeNode, selected bool, cursorBg, treeLine lipgloss.TerminalColor) string {
+	if node.Kind == NodeGroup {
 		return ""
 	}
 
@@ -264,6 +692,15 @@ func (tm *TreeModel) buildTreePrefix(node TreeNode, selected bool, cursorBg, tre
 	}
 	lineStyle := bg.Foreground(treeLine)
 
+	// Nested repos and their files are indented under the outer repo
+	indent := ""
+	if node.nest > 0 {
+		indent = bg.Render(strings.Repeat("  ", node.nest))
+	}
+	if node.Kind == NodeRepo || node.Depth == 0 {
+		return indent
+	}
+
 	// Build ancestor chain from depth 1 to node.Depth
 	// For each depth level, we need to know if the ancestor at that level is the last child
 	ancestors := make([]TreeNode, node.Depth+1)
@@ -276,7 +713,7 @@ func (tm *TreeModel) buildTreePrefix(node TreeNode, selected bool, cursorBg, tre
 		ancestors[d-1] = cur
 	}
 
-	var prefix string
+	prefix := indent
 	for d := 1; d < node.Depth; d++ {
 		if ancestors[d].IsLastChild {
 			prefix += bg.Render("  ")
@@ -295,7 +732,7 @@ func (tm *TreeModel) buildTreePrefix(node TreeNode, selected bool, cursorBg, tre
 	return prefix
 }
 
-func padRight(s string, width int, selected bool, cursorBg lipgloss.Color) string {
+func padRight(s string, width int, selected bool, cursorBg lipgloss.TerminalColor) string {
 	visible := lipgloss.Width(s)
 	pad := width - visible
 	if pad <= 0 {
@@ -308,41 +745,91 @@ func padRight(s string, width int, selected bool, cursorBg lipgloss.Color) strin
 	return s + spaces
 }
 
+// accentPalette is used to auto-assign repo accents; colors are chosen to
+// be distinguishable from each other on dark backgrounds.
+var accentPalette = []string{"39", "42", "208", "170", "214", "81", "203", "141", "118", "227"}
+
+// autoAccent picks a stable accent for a repo by hashing its name.
+func autoAccent(name string) string {
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	return accentPalette[h.Sum32()%uint32(len(accentPalette))]
+}
+
+// formatAge renders a duration compactly, e.g. "5m", "3h", "2d".
+func formatAge(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return "now"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	default:
+		return fmt.Sprintf("%dd", int(d.Hours()/24))
+	}
+}
+
 // truncateStr shortens a string from the right with "…" suffix.
 func truncateStr(s string, maxWidth int) string {
 	if maxWidth <= 0 {
 		return ""
 	}
-	if len(s) <= maxWidth {
+	if lipgloss.Width(s) <= maxWidth {
 		return s
 	}
 	if maxWidth <= 1 {
 		return "…"
 	}
-	return s[:maxWidth-1] + "…"
+	// By cell, so wide characters and translations aren't cut in half
+	w := 0
+	for i, r := range s {
+		if w += lipgloss.Width(string(r)); w > maxWidth-1 {
+			return s[:i] + "…"
+		}
+	}
+	return s
+}
+
+// truncateLeft shortens a string from the left with a "…" prefix,
+// keeping its end.
+func truncateLeft(s string, maxWidth int) string {
+	if maxWidth <= 0 {
+		return ""
+	}
+	if lipgloss.Width(s) <= maxWidth {
+		return s
+	}
+	runes := []rune(s)
+	w := 1 // the "…"
+	i := len(runes)
+	for i > 0 && w+lipgloss.Width(string(runes[i-1])) <= maxWidth {
+		i--
+		w += lipgloss.Width(string(runes[i]))
+	}
+	return "…" + string(runes[i:])
 }
 
-// truncateBranch shortens "[branchname]" keeping the brackets visible.
+// truncateBranch shortens "[branchname]" (or "(detached @ …)") keeping the
+// brackets visible.
 func truncateBranch(branch string, maxWidth int) string {
-	if len(branch) <= maxWidth {
+	if lipgloss.Width(branch) <= maxWidth {
 		return branch
 	}
 	if maxWidth <= 2 {
 		return "" // can't show anything useful
 	}
-	if maxWidth == 3 {
-		return "[…]"
-	}
+	// The brackets are ASCII, a byte each
+	open, close := branch[:1], branch[len(branch)-1:]
 	// "[" + truncated + "…]"
-	innerMax := maxWidth - 3 // 1 for "[", 1 for "…", 1 for "]"
-	return "[" + branch[1:1+innerMax] + "…]"
+	return open + truncateStr(branch[1:len(branch)-1], maxWidth-2) + close
 }
 
 // fitNameAndBranch splits available space between repo name and branch,
 // truncating each proportionally so both remain partially visible.
 func fitNameAndBranch(name, branch string, avail int) (string, string) {
-	nameLen := len(name)
-	branchLen := len(branch)
+	nameLen := lipgloss.Width(name)
+	branchLen := lipgloss.Width(branch)
 
 	// Both fit without truncation
 	if nameLen+branchLen <= avail {
@@ -385,25 +872,25 @@ func fitNameAndBranch(name, branch string, avail int) (string, string) {
 
 // truncatePath shortens a path from the left to fit maxWidth, e.g. "…/Projects/gitbar"
 func truncatePath(path string, maxWidth int) string {
-	if len(path) <= maxWidth {
+	if lipgloss.Width(path) <= maxWidth {
 		return path
 	}
 	if maxWidth <= 3 {
 		return "..."
 	}
 
-	parts := strings.Split(path, string(filepath.Separator))
+	parts := strings.Split(path, "/")
 	// Always keep the last segment (folder name)
 	result := parts[len(parts)-1]
-	if len(result)+2 > maxWidth {
+	if lipgloss.Width(result)+2 > maxWidth {
 		// Even the last segment is too long
-		return "…" + result[len(result)-(maxWidth-1):]
+		return truncateLeft(result, maxWidth)
 	}
 
 	// Add segments from the right until we'd exceed maxWidth
 	for i := len(parts) - 2; i >= 0; i-- {
-		candidate := parts[i] + string(filepath.Separator) + result
-		if len(candidate)+1 > maxWidth { // +1 for the "…" prefix
+		candidate := parts[i] + "/" + result
+		if lipgloss.Width(candidate)+1 > maxWidth { // +1 for the "…" prefix
 			break
 		}
 		result = candidate
@@ -412,10 +899,10 @@ func truncatePath(path string, maxWidth int) string {
 	if result == path {
 		return path
 	}
-	return "…" + string(filepath.Separator) + result
+	return "…/" + result
 }
 
-func renderNode(node TreeNode, selected bool, width int, theme Theme, cursorBg lipgloss.Color, prefix string) string {
+func renderNode(node TreeNode, selected bool, width int, theme Theme, cursorBg lipgloss.TerminalColor, prefix string) string {
 	var bg lipgloss.Style
 	if selected {
 		bg = lipgloss.NewStyle().Background(cursorBg)
@@ -426,14 +913,48 @@ func renderNode(node TreeNode, selected bool, width int, theme Theme, cursorBg l
 	sp := bg.Render(" ")
 
 	switch node.Kind {
+	case NodeGroup:
+		arrow := "▾"
+		if node.Collapsed {
+			arrow = "▸"
+		}
+		icon := bg.Foreground(themeColor(theme.Title)).Render("\uf0e8")
+		name := bg.Bold(true).Foreground(themeColor(theme.Title)).Render(node.Group)
+		count := fmt.Sprintf("(%d repos, %d files)", node.groupRepos, node.groupFiles)
+		if 4+lipgloss.Width(node.Group)+1+len(count) > width {
+			return bg.Render(arrow) + sp + icon + sp + name
+		}
+		return bg.Render(arrow) + sp + icon + sp + name + sp + bg.Foreground(themeColor(theme.FileCount)).Render(count)
+
 	case NodeRepo:
+		if node.Repo.Accent != "" {
+			theme.RepoName = node.Repo.Accent
+		}
+		repoIcon := bg.Foreground(themeColor(theme.FolderIcon)).Render("\uf07b")
+		if len(node.Repo.Warnings) > 0 {
+			repoIcon = bg.Foreground(themeColor(theme.Warning)).Render("\uf071")
+		}
 		arrow := "▾"
 		if node.Collapsed {
 			arrow = "▸"
 		}
 		branchFull := fmt.Sprintf("[%s]", node.Repo.Branch)
+		if node.Repo.BranchColor != "" {
+			theme.BranchName = node.Repo.BranchColor
+		}
+		if node.Repo.Detached != "" {
+			branchFull = node.Repo.Detached
+			theme.BranchName = theme.Detached
+		}
+		if node.Repo.Unavailable {
+			branchFull = "(unavailable)"
+			theme.BranchName = theme.Warning
+		}
 		countStr := fmt.Sprintf("(%d)", len(node.Repo.Files))
 		nameFull := node.Repo.RelPath
+		if node.repoName != "" {
+			nameFull = node.repoName
+		}
 
 		// Build ahead/behind suffix
 		abStr := ""
@@ -445,38 +966,77 @@ func renderNode(node TreeNode, selected bool, width int, theme Theme, cursorBg l
 		}
 
 		// Available space after "▸ 📁 " (arrow + space + icon + space = 4 chars)
-		avail := width - 4
+		avail := width - 4 - lipgloss.Width(prefix)
 
 		// Try to fit all: name + " " + branch + " " + count + abStr
-		fullLen := len(nameFull) + 1 + len(branchFull) + 1 + len(countStr) + len(abStr)
+		fullLen := lipgloss.Width(nameFull) + 1 + lipgloss.Width(branchFull) + 1 + len(countStr) + lipgloss.Width(abStr)
 		if fullLen <= avail {
-			icon := bg.Foreground(lipgloss.Color(theme.FolderIcon)).Render("\uf07b")
-			name := bg.Bold(true).Foreground(lipgloss.Color(theme.RepoName)).Render(nameFull)
-			branch := bg.Bold(false).Foreground(lipgloss.Color(theme.BranchName)).Render(branchFull)
-			fileCount := bg.Foreground(lipgloss.Color(theme.FileCount)).Render(countStr)
-			arrowStyled := bg.Render(arrow)
+			icon := repoIcon
+			name := bg.Bold(true).Foreground(themeColor(theme.RepoName)).Render(nameFull)
+			branch := bg.Bold(false).Foreground(themeColor(theme.BranchName)).Render(branchFull)
+			fileCount := bg.Foreground(themeColor(theme.FileCount)).Render(countStr)
+			arrowStyled := prefix + bg.Render(arrow)
 			result := arrowStyled + sp + icon + sp + name + sp + branch + sp + fileCount
 			result += renderAheadBehind(node.Repo.Ahead, node.Repo.Behind, bg, sp, theme)
+			extra := fullLen
+			if node.Repo.AutoCommit && extra+7 <= avail {
+				result += sp + bg.Foreground(themeColor(theme.StatusStaged)).Render("\uf021 auto")
+				extra += 7
+			}
+			if layout := cloneBadge(*node.Repo); layout != "" && extra+1+lipgloss.Width(layout) <= avail {
+				result += sp + bg.Foreground(themeColor(theme.Warning)).Render(layout)
+				extra += 1 + lipgloss.Width(layout)
+			}
+			if pr := node.Repo.PR; pr != nil {
+				prStr, prColor := fmt.Sprintf("\uf407 #%d", pr.Number), theme.FileCount
+				switch pr.Checks {
+				case "pass":
+					prStr, prColor = prStr+" ✓", theme.StatusAdded
+				case "fail":
+					prStr, prColor = prStr+" ✗", theme.StatusDeleted
+				case "pending":
+					prStr, prColor = prStr+" ●", theme.StatusModified
+				}
+				if extra+1+lipgloss.Width(prStr) <= avail {
+					result += sp + bg.Foreground(themeColor(prColor)).Render(prStr)
+					extra += 1 + lipgloss.Width(prStr)
+				}
+			}
+			// Show the upstream only when there is room to spare
+			upstream := "→ " + node.Repo.Upstream
+			if node.Repo.Upstream == "" {
+				upstream = "(no upstream)"
+			}
+			if extra+1+lipgloss.Width(upstream) <= avail {
+				result += sp + bg.Foreground(themeColor(theme.FileCount)).Render(upstream)
+				extra += 1 + lipgloss.Width(upstream)
+			}
+			if !node.Repo.Fetched.IsZero() {
+				fetched := "⟳" + formatAge(time.Since(node.Repo.Fetched))
+				if extra+1+lipgloss.Width(fetched) <= avail {
+					result += sp + bg.Foreground(themeColor(theme.FileCount)).Render(fetched)
+				}
+			}
 			return result
 		}
 
 		// Try with count: name + branch share (avail - countLen - abLen - 2 spaces)
-		availNB := avail - len(countStr) - len(abStr) - 2
+		availNB := avail - len(countStr) - lipgloss.Width(abStr) - 2
 		showCount := true
 		if availNB < 7 { // not enough for meaningful name+branch with count
-			availNB = avail - len(abStr) - 1 // drop count, 1 space between name and branch
+			availNB = avail - lipgloss.Width(abStr) - 1 // drop count, 1 space between name and branch
 			showCount = false
 		}
 
 		nameStr, branchStr := fitNameAndBranch(nameFull, branchFull, availNB)
 		if nameStr != "" && branchStr != "" {
-			icon := bg.Foreground(lipgloss.Color(theme.FolderIcon)).Render("\uf07b")
-			name := bg.Bold(true).Foreground(lipgloss.Color(theme.RepoName)).Render(nameStr)
-			branch := bg.Bold(false).Foreground(lipgloss.Color(theme.BranchName)).Render(branchStr)
-			arrowStyled := bg.Render(arrow)
+			icon := repoIcon
+			name := bg.Bold(true).Foreground(themeColor(theme.RepoName)).Render(nameStr)
+			branch := bg.Bold(false).Foreground(themeColor(theme.BranchName)).Render(branchStr)
+			arrowStyled := prefix + bg.Render(arrow)
 			var result string
 			if showCount {
-				fileCount := bg.Foreground(lipgloss.Color(theme.FileCount)).Render(countStr)
+				fileCount := bg.Foreground(themeColor(theme.FileCount)).Render(countStr)
 				result = arrowStyled + sp + icon + sp + name + sp + branch + sp + fileCount
 			} else {
 				result = arrowStyled + sp + icon + sp + name + sp + branch
@@ -487,9 +1047,9 @@ func renderNode(node TreeNode, selected bool, width int, theme Theme, cursorBg l
 
 		// Last resort: just name
 		nameStr = truncatePath(nameFull, max(1, avail))
-		icon := bg.Foreground(lipgloss.Color(theme.FolderIcon)).Render("\uf07b")
-		name := bg.Bold(true).Foreground(lipgloss.Color(theme.RepoName)).Render(nameStr)
-		arrowStyled := bg.Render(arrow)
+		icon := repoIcon
+		name := bg.Bold(true).Foreground(themeColor(theme.RepoName)).Render(nameStr)
+		arrowStyled := prefix + bg.Render(arrow)
 		return arrowStyled + sp + icon + sp + name
 
 	case NodeDir:
@@ -498,17 +1058,17 @@ func renderNode(node TreeNode, selected bool, width int, theme Theme, cursorBg l
 			arrow = "▸"
 		}
 		// prefix + arrow + sp + icon + sp + name
-		fixedWidth := node.Depth*2 + 1 + 1 + 1 + 1
+		fixedWidth := (node.nest+node.Depth)*2 + 1 + 1 + 1 + 1
 		dirName := truncateStr(node.DirPath, width-fixedWidth)
-		icon := bg.Foreground(lipgloss.Color(theme.FolderIcon)).Render("\uf07b")
-		name := bg.Bold(true).Foreground(lipgloss.Color(theme.DirName)).Render(dirName)
+		icon := bg.Foreground(themeColor(theme.FolderIcon)).Render("\uf07b")
+		name := bg.Bold(true).Foreground(themeColor(theme.DirName)).Render(dirName)
 		arrowStyled := bg.Render(arrow)
 		return prefix + arrowStyled + sp + icon + sp + name
 
 	case NodeFile:
 		// prefix + status + sp + icon + sp + name
-		fixedWidth := node.Depth*2 + 1 + 1 + 1 + 1
-		fileName := truncateStr(filepath.Base(node.File.Path), width-fixedWidth)
+		fixedWidth := (node.nest+node.Depth)*2 + 1 + 1 + 1 + 1
+		fileName := truncateStr(path.Base(node.File.Path), width-fixedWidth)
 		styledStatus := styleStatus(node.File.Status, node.File.IsStaged, selected, theme, cursorBg)
 		icon := fileIconStyled(node.File.Path, selected, theme, cursorBg)
 		fileStyled := bg.Render(fileName)
@@ -520,32 +1080,34 @@ func renderNode(node TreeNode, selected bool, width int, theme Theme, cursorBg l
 func renderAheadBehind(ahead, behind int, bg lipgloss.Style, sp string, theme Theme) string {
 	var result string
 	if ahead > 0 {
-		result += sp + bg.Foreground(lipgloss.Color(theme.AheadColor)).Render(fmt.Sprintf("↑%d", ahead))
+		result += sp + bg.Foreground(themeColor(theme.AheadColor)).Render(fmt.Sprintf("↑%d", ahead))
 	}
 	if behind > 0 {
-		result += sp + bg.Foreground(lipgloss.Color(theme.BehindColor)).Render(fmt.Sprintf("↓%d", behind))
+		result += sp + bg.Foreground(themeColor(theme.BehindColor)).Render(fmt.Sprintf("↓%d", behind))
 	}
 	return result
 }
 
-func styleStatus(code StatusCode, staged bool, selected bool, theme Theme, cursorBg lipgloss.Color) string {
+func styleStatus(code sidegit.StatusCode, staged bool, selected bool, theme Theme, cursorBg lipgloss.TerminalColor) string {
 	s := string(code)
 	base := lipgloss.NewStyle()
 	if selected {
 		base = base.Background(cursorBg)
 	}
 	if staged {
-		return base.Foreground(lipgloss.Color(theme.StatusStaged)).Bold(true).Render(s)
+		return base.Foreground(themeColor(theme.StatusStaged)).Bold(true).Render(s)
 	}
 	switch code {
-	case StatusAdded:
-		return base.Foreground(lipgloss.Color(theme.StatusAdded)).Render(s)
-	case StatusDeleted:
-		return base.Foreground(lipgloss.Color(theme.StatusDeleted)).Render(s)
-	case StatusModified:
-		return base.Foreground(lipgloss.Color(theme.StatusModified)).Render(s)
-	case StatusUntracked:
-		return base.Foreground(lipgloss.Color(theme.StatusUntracked)).Render(s)
+	case sidegit.StatusAdded:
+		return base.Foreground(themeColor(theme.StatusAdded)).Render(s)
+	case sidegit.StatusDeleted:
+		return base.Foreground(themeColor(theme.StatusDeleted)).Render(s)
+	case sidegit.StatusModified:
+		return base.Foreground(themeColor(theme.StatusModified)).Render(s)
+	case sidegit.StatusUntracked:
+		return base.Foreground(themeColor(theme.StatusUntracked)).Render(s)
+	case sidegit.StatusConflict:
+		return base.Foreground(themeColor(theme.StatusConflict)).Bold(true).Render(s)
 	default:
 		return base.Render(s)
 	}
@@ -621,7 +1183,7 @@ var iconColors = map[string]string{
 	".env": "#FAF743", ".gitignore": "#F54D27",
 }
 
-func fileIconStyled(path string, selected bool, theme Theme, cursorBg lipgloss.Color) string {
+func fileIconStyled(path string, selected bool, theme Theme, cursorBg lipgloss.TerminalColor) string {
 	name := filepath.Base(path)
 
 	if icon, ok := nerdIconNames[name]; ok {
@@ -633,14 +1195,14 @@ func fileIconStyled(path string, selected bool, theme Theme, cursorBg lipgloss.C
 		return colorIcon(icon, name, selected, theme, cursorBg)
 	}
 
-	base := lipgloss.NewStyle().Foreground(lipgloss.Color(theme.DefaultIcon))
+	base := lipgloss.NewStyle().Foreground(themeColor(theme.DefaultIcon))
 	if selected {
 		base = base.Background(cursorBg)
 	}
 	return base.Render("\uf15b")
 }
 
-func colorIcon(icon, name string, selected bool, theme Theme, cursorBg lipgloss.Color) string {
+func colorIcon(icon, name string, selected bool, theme Theme, cursorBg lipgloss.TerminalColor) string {
 	ext := strings.ToLower(filepath.Ext(name))
 	base := lipgloss.NewStyle()
 	if selected {
@@ -649,5 +1211,5 @@ func colorIcon(icon, name string, selected bool, theme Theme, cursorBg lipgloss.
 	if color, ok := iconColors[ext]; ok {
 		return base.Foreground(lipgloss.Color(color)).Render(icon)
 	}
-	return base.Foreground(lipgloss.Color(theme.DefaultIcon)).Render(icon)
+	return base.Foreground(themeColor(theme.DefaultIcon)).Render(icon)
 }
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/hermanschutte/sidegit/pkg/sidegit"
+)
+
+// WebURLs are the URL templates for a git host's web UI. Templates may use
+// {host}, {repo} (owner/name), {branch} and {path}.
+type WebURLs struct {
+	Preset  string `yaml:"preset"`  // github, gitlab or bitbucket; fills in unset templates
+	Tree    string `yaml:"tree"`    // the repo at a branch
+	File    string `yaml:"file"`    // a file at a branch
+	Compare string `yaml:"compare"` // the page that opens a pull/merge request
+}
+
+var webPresets = map[string]WebURLs{
+	"github": {
+		Tree:    "https://{host}/{repo}/tree/{branch}",
+		File:    "https://{host}/{repo}/blob/{branch}/{path}",
+		Compare: "https://{host}/{repo}/compare/{branch}?expand=1",
+	},
+	"gitlab": {
+		Tree:    "https://{host}/{repo}/-/tree/{branch}",
+		File:    "https://{host}/{repo}/-/blob/{branch}/{path}",
+		Compare: "https://{host}/{repo}/-/merge_requests/new?merge_request[source_branch]={branch}",
+	},
+	"bitbucket": {
+		Tree:    "https://{host}/{repo}/src/{branch}/",
+		File:    "https://{host}/{repo}/src/{branch}/{path}",
+		Compare: "https://{host}/{repo}/pull-requests/new?source={branch}",
+	},
+}
+
+// webHosts are the hosted services known without any config.
+var webHosts = map[string]string{
+	"github.com":    "github",
+	"gitlab.com":    "gitlab",
+	"bitbucket.org": "bitbucket",
+}
+
+// webTemplates returns the templates for host: web_urls from the config
+// first, then the hosted services.
+func webTemplates(cfg map[string]WebURLs, host string) (WebURLs, bool) {
+	t, ok := cfg[host]
+	if !ok {
+		preset, known := webHosts[host]
+		if !known {
+			return WebURLs{}, false
+		}
+		t.Preset = preset
+	}
+	if p, ok := webPresets[t.Preset]; ok {
+		if t.Tree == "" {
+			t.Tree = p.Tree
+		}
+		if t.File == "" {
+			t.File = p.File
+		}
+		if t.Compare == "" {
+			t.Compare = p.Compare
+		}
+	}
+	return t, true
+}
+
+// parseRemoteURL splits a remote URL (https://, ssh:// or scp-like
+// git@host:owner/name) into its host and repo path.
+func parseRemoteURL(remote string) (host, repo string, ok bool) {
+	if u, err := url.Parse(remote); err == nil && u.Host != "" {
+		host, repo = u.Hostname(), u.Path
+	} else if at, rest, found := strings.Cut(remote, ":"); found && !strings.Contains(at, "/") {
+		host, repo = at, rest
+		if _, h, found := strings.Cut(host, "@"); found {
+			host = h
+		}
+	} else {
+		return "", "", false
+	}
+	repo = strings.TrimSuffix(strings.Trim(repo, "/"), ".git")
+	return host, repo, host != "" && repo != ""
+}
+
+// webRemote picks the remote to browse: the upstream's, else origin, else
+// the first one.
+func webRemote(repo sidegit.Repo) (sidegit.Remote, error) {
+	remotes, err := sidegit.ListRemotes(repo.Path)
+	if err != nil {
+		return sidegit.Remote{}, err
+	}
+	want, _, _ := strings.Cut(repo.Upstream, "/")
+	if want == "" {
+		want = "origin"
+	}
+	for _, r := range remotes {
+		if r.Name == want {
+			return r, nil
+		}
+	}
+	if len(remotes) == 0 {
+		return sidegit.Remote{}, fmt.Errorf("%s has no remotes", repo.RelPath)
+	}
+	return remotes[0], nil
+}
+
+// webURL fills in the template picked by kind ("tree", "file" or
+// "compare") for repo and, for files, filePath.
+func webURL(cfg map[string]WebURLs, repo sidegit.Repo, kind, filePath string) (string, error) {
+	remote, err := webRemote(repo)
+	if err != nil {
+		return "", err
+	}
+	host, name, ok := parseRemoteURL(remote.URL)
+	if !ok {
+		return "", fmt.Errorf("can't tell the web address of %s", remote.URL)
+	}
+	t, ok := webTemplates(cfg, host)
+	if !ok {
+		return "", fmt.Errorf("no web URLs for %s; add it under web_urls in the config", host)
+	}
+	tmpl := map[string]string{"tree": t.Tree, "file": t.File, "compare": t.Compare}[kind]
+	if tmpl == "" {
+		return "", fmt.Errorf("no %s URL for %s", kind, host)
+	}
+
+	branch := repo.Branch
+	if _, b, found := strings.Cut(repo.Upstream, "/"); found {
+		branch = b // the name the branch has on the remote
+	}
+	if branch == "" {
+		if kind == "compare" {
+			return "", fmt.Errorf("HEAD is detached, there's no branch to open a pull request from")
+		}
+		out, err := exec.Command("git", sidegit.RepoArgs(repo.Path, "rev-parse", "HEAD")...).Output()
+		if err != nil {
+			return "", fmt.Errorf("git rev-parse HEAD: %v", err)
+		}
+		branch = strings.TrimSpace(string(out))
+	}
+	return strings.NewReplacer(
+		"{host}", host,
+		"{repo}", name,
+		"{branch}", escapePath(branch),
+		"{path}", escapePath(strings.TrimSuffix(filePath, "/")),
+	).Replace(tmpl), nil
+}
+
+// escapePath escapes each segment of a slash-separated path.
+func escapePath(p string) string {
+	segs := strings.Split(p, "/")
+	for i, s := range segs {
+		segs[i] = url.PathEscape(s)
+	}
+	return strings.Join(segs, "/")
+}
+
+// openWebCmd opens the web page for repo (or a file in it) in the browser.
+func openWebCmd(cfg map[string]WebURLs, repo sidegit.Repo, kind, filePath string) tea.Cmd {
+	return func() tea.Msg {
+		u, err := webURL(cfg, repo, kind, filePath)
+		if err == nil {
+			err = openURL(u)
+		}
+		if err != nil {
+			return gitErrorMsg{repo: repo.Path, err: err}
+		}
+		return nil
+	}
+}
+
+// openURL hands u to the platform's opener.
+func openURL(u string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", u)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", u)
+	default:
+		cmd = exec.Command("xdg-open", u)
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("opening %s: %v", u, err)
+	}
+	go cmd.Wait() // reap it; the browser outlives us
+	return nil
+}